@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/archives"
+	"github.com/encilab/dragon-ball/src/config"
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func runCommand(
+	cfg *config.Config,
+	characterRepository domains.CharacterStore,
+	args []string,
+) error {
+	switch args[0] {
+	case "export":
+		return runExportCommand(characterRepository, args[1:])
+	case "import":
+		return runImportCommand(cfg, characterRepository, args[1:])
+	case "purge":
+		return runPurgeCommand(cfg, characterRepository, args[1:])
+	case "sync":
+		return runSyncCommand(cfg, characterRepository, args[1:])
+	case "api-keys":
+		apiKeyRepository, ok := characterRepository.(domains.APIKeyRepository)
+		if !ok {
+			return fmt.Errorf("the %s storage keeps no API keys", cfg.Storage.Driver)
+		}
+		return runAPIKeysCommand(apiKeyRepository, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, available commands: api-keys, config, export, import, purge, sync", args[0])
+	}
+}
+
+func runExportCommand(
+	characterArchiveRepository domains.CharacterArchiveRepository,
+	args []string,
+) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "archive file to write, defaults to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	characters, err := characterArchiveRepository.ExportCharactersInDatabase(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return archives.WriteCharacters(os.Stdout, characters, time.Now())
+	}
+
+	// A failed Close may mean the archive never fully reached the disk.
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := archives.WriteCharacters(file, characters, time.Now()); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func runImportCommand(
+	cfg *config.Config,
+	characterArchiveRepository domains.CharacterArchiveRepository,
+	args []string,
+) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	modeFlag := flags.String("mode", string(domains.ImportModeUpsert), "import mode: upsert or replace")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	mode, err := domains.ParseImportMode(*modeFlag)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if flags.NArg() > 1 {
+		return errors.New("import accepts a single archive file")
+	}
+	if flags.NArg() == 1 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	_, characters, err := archives.ReadCharacters(r, int64(cfg.Import.MaxDecompressedBytes))
+	if err != nil {
+		return err
+	}
+
+	report, err := characterArchiveRepository.ImportCharactersInDatabase(
+		domains.WithAuditor(context.Background(), cliActor(), domains.AuditSourceImport),
+		characters,
+		mode,
+		*dryRun,
+	)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func runPurgeCommand(
+	cfg *config.Config,
+	characterRepository domains.CharacterStore,
+	args []string,
+) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	purgeDeletedCharactersJob := newPurgeDeletedCharactersJob(cfg, characterRepository)
+
+	purged, err := purgeDeletedCharactersJob.RunOnce(
+		domains.WithAuditor(context.Background(), cliActor(), domains.AuditSourcePurge),
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d deleted characters\n", purged)
+	return nil
+}
+
+func runSyncCommand(
+	cfg *config.Config,
+	characterRepository domains.CharacterStore,
+	args []string,
+) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	resetOverrides := flags.Bool("reset-overrides", false, "drop local edits and take every field from the external API")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	syncCharactersJob := newSyncCharactersJob(cfg, characterRepository)
+
+	report, err := syncCharactersJob.RunOnce(
+		domains.WithAuditor(context.Background(), cliActor(), domains.AuditSourceSync),
+		*resetOverrides,
+	)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func runAPIKeysCommand(
+	apiKeyRepository domains.APIKeyRepository,
+	args []string,
+) error {
+	if len(args) == 0 {
+		return errors.New("api-keys needs a subcommand: create, list or revoke")
+	}
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("api-keys create", flag.ContinueOnError)
+		name := flags.String("name", "", "name of the key, who or what uses it")
+		roleFlag := flags.String("role", string(domains.RoleReader), "role of the key: reader, editor or admin")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *name == "" {
+			return domains.ErrAPIKeyNameIsRequired
+		}
+		role, err := domains.ParseRole(*roleFlag)
+		if err != nil {
+			return err
+		}
+
+		secret, err := domains.NewAPIKeySecret()
+		if err != nil {
+			return err
+		}
+
+		key, err := apiKeyRepository.CreateAPIKeyInDatabase(context.Background(), *name, role, domains.HashAPIKey(secret))
+		if err != nil {
+			return err
+		}
+
+		// The secret is not stored, this is the only time it can be read.
+		fmt.Printf("created API key %d %q with role %s\n%s\n", key.ID, key.Name, key.Role, secret)
+		return nil
+	case "list":
+		keys, err := apiKeyRepository.ListAPIKeysInDatabase(context.Background())
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(keys)
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("api-keys revoke accepts a single key id")
+		}
+		id, err := strconv.ParseUint(args[1], 10, 0)
+		if err != nil {
+			return fmt.Errorf("key id must be a positive integer, got %q", args[1])
+		}
+
+		key, err := apiKeyRepository.RevokeAPIKeyInDatabase(context.Background(), uint(id))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("revoked API key %d %q\n", key.ID, key.Name)
+		return nil
+	default:
+		return fmt.Errorf("unknown api-keys subcommand %q, available subcommands: create, list, revoke", args[0])
+	}
+}
+
+func cliActor() string {
+	current, err := user.Current()
+	if err != nil {
+		return "cli"
+	}
+
+	return "cli:" + current.Username
+}
@@ -1,37 +1,59 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/encilab/dragon-ball/src/caches"
+	"github.com/encilab/dragon-ball/src/config"
+	"github.com/encilab/dragon-ball/src/domains"
 	"github.com/encilab/dragon-ball/src/handlers"
+	"github.com/encilab/dragon-ball/src/health"
+	"github.com/encilab/dragon-ball/src/jobs"
+	"github.com/encilab/dragon-ball/src/metrics"
+	"github.com/encilab/dragon-ball/src/middlewares"
 	"github.com/encilab/dragon-ball/src/repositories"
-	"github.com/gin-contrib/cors"
+	"github.com/encilab/dragon-ball/src/tokens"
+	"github.com/encilab/dragon-ball/src/tracing"
 	"github.com/gin-gonic/gin"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 var folderEnv = "./conf"
-var webPort = "8080"
+
+// jwksTimeout bounds fetching the JWKS, which the requests with a JWT wait for.
+const jwksTimeout = 5 * time.Second
 
 func addRoutes(
 	app *gin.Engine,
-	sqlClient *sql.DB,
+	cfg *config.Config,
+	characterRepository *repositories.CachedCharacterRepository,
+	apiKeyRepository domains.APIKeyRepository,
+	tokenVerifier domains.TokenVerifier,
+	draining *atomic.Bool,
+	logLevel *slog.LevelVar,
+	m *metrics.Metrics,
+	healthRegistry *health.Registry,
 ) (*gin.Engine, error) {
-	sqlClientTimeout, err := time.ParseDuration(os.Getenv("PSQL_TIMEOUT"))
-	if err != nil {
-		return app, err
-	}
-
-	characterRepository := repositories.NewCharacterRepository(
-		sqlClient,
-		sqlClientTimeout,
+	app.GET(
+		"/metrics",
+		gin.WrapH(m.Handler()),
 	)
 
 	apiGroup := app.Group("/api")
@@ -41,118 +63,638 @@ func addRoutes(
 	)
 	apiGroup.GET(
 		"/readyz",
-		handlers.ReadyzHandler(sqlClient),
+		handlers.ReadyzHandler(healthRegistry, draining),
 	)
 
-	apiCharacters := apiGroup.Group("/characters")
-	apiCharacters.POST(
-		"/",
+	characterCachePolicy := middlewares.CachePolicy{
+		CacheControl: cfg.HTTPCache.CharacterCacheControl,
+		Vary:         cfg.HTTPCache.CharacterVary,
+	}
+	searchCachePolicy := middlewares.CachePolicy{
+		CacheControl: cfg.HTTPCache.SearchCacheControl,
+		Vary:         cfg.HTTPCache.SearchVary,
+	}
+	// A shared cache in front of the web app must not answer an anonymous
+	// request with what it kept for an authenticated one.
+	if cfg.Auth.Enabled && !cfg.Auth.PublicReads {
+		characterCachePolicy = characterCachePolicy.Private()
+		searchCachePolicy = searchCachePolicy.Private()
+	}
+
+	// Each role may do what the ones below it may. Without AUTH_ENABLED
+	// anyone may do anything; with AUTH_PUBLIC_READS anyone may read.
+	requireRole := func(role domains.Role) gin.HandlerFunc {
+		if !cfg.Auth.Enabled || (role == domains.RoleReader && cfg.Auth.PublicReads) {
+			return func(ctx *gin.Context) { ctx.Next() }
+		}
+		return middlewares.RequireRole(role)
+	}
+	apiAuthenticated := apiGroup.Group("", middlewares.Authenticate(apiKeyRepository, tokenVerifier))
+	apiReaders := apiAuthenticated.Group("", requireRole(domains.RoleReader))
+	apiEditors := apiAuthenticated.Group("", requireRole(domains.RoleEditor))
+	apiV1Admin := apiAuthenticated.Group("/v1/admin", requireRole(domains.RoleAdmin))
+
+	apiReaders.POST(
+		"/characters/",
 		handlers.GetCharactersHandler(characterRepository),
 	)
-	apiCharacters.GET(
-		"/search",
+	apiReaders.GET(
+		"/characters/search",
+		middlewares.CacheHeaders(searchCachePolicy),
 		handlers.SearchCharactersHandler(characterRepository),
 	)
-	apiCharacters.DELETE(
-		"/delete/:name",
-		handlers.DeleteCharacterHandler(characterRepository),
+	apiReaders.GET(
+		"/v1/characters/:id",
+		middlewares.CacheHeaders(characterCachePolicy),
+		handlers.GetCharacterHandler(characterRepository, characterRepository),
+	)
+	apiReaders.GET(
+		"/v1/characters/:id/history",
+		handlers.GetCharacterHistoryHandler(characterRepository),
+	)
+	apiReaders.GET(
+		"/v1/characters/:id/diff",
+		handlers.DiffCharacterVersionsHandler(characterRepository),
+	)
+
+	apiEditors.DELETE(
+		"/characters/delete/:name",
+		handlers.DeleteCharacterHandler(characterRepository, cfg.Characters.DeleteIdempotent),
+	)
+	apiEditors.GET(
+		"/v1/characters/trash",
+		handlers.ListDeletedCharactersHandler(characterRepository),
+	)
+	apiEditors.POST(
+		"/v1/characters/:id/restore",
+		handlers.RestoreCharacterHandler(characterRepository),
+	)
+	apiEditors.PATCH(
+		"/v1/characters/:id",
+		handlers.PatchCharacterHandler(characterRepository),
+	)
+
+	apiV1Admin.GET(
+		"/export",
+		handlers.ExportCharactersHandler(characterRepository),
+	)
+	apiV1Admin.POST(
+		"/import",
+		handlers.ImportCharactersHandler(characterRepository, int64(cfg.Import.MaxBytes), int64(cfg.Import.MaxDecompressedBytes)),
+	)
+	apiV1Admin.GET(
+		"/cache",
+		handlers.CacheStatsHandler(characterRepository),
+	)
+	apiV1Admin.GET(
+		"/log-level",
+		handlers.GetLogLevelHandler(logLevel),
+	)
+	apiV1Admin.PUT(
+		"/log-level",
+		handlers.SetLogLevelHandler(logLevel),
+	)
+	apiV1Admin.GET(
+		"/api-keys",
+		handlers.ListAPIKeysHandler(apiKeyRepository),
+	)
+	apiV1Admin.POST(
+		"/api-keys",
+		handlers.CreateAPIKeyHandler(apiKeyRepository),
+	)
+	apiV1Admin.DELETE(
+		"/api-keys/:id",
+		handlers.RevokeAPIKeyHandler(apiKeyRepository),
 	)
 
 	return app, nil
 }
 
-func newWebApp() (*gin.Engine, error) {
+// newCharacterStore opens the storage STORAGE_DRIVER names: postgres, sqlite
+// in the file SQLITE_PATH, or memory. closeStore releases it.
+func newCharacterStore(cfg *config.Config) (characterStore domains.CharacterStore, closeStore func() error, err error) {
+	switch cfg.Storage.Driver {
+	case "postgres":
+		pool, err := newPostgresPool(cfg.Postgres, psqlConnString(cfg.Postgres))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(cfg.Postgres.ReplicaHosts) == 0 {
+			return repositories.NewCharacterRepository(
+				pool,
+				cfg.Postgres.Timeout,
+			), func() error { pool.Close(); return nil }, nil
+		}
+
+		readReplicas, replicaPools, err := newReadReplicas(cfg.Postgres)
+		if err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go readReplicas.Run(domains.WithLogAttrs(ctx, "worker", "ReadReplicas"))
+
+		closeStore := func() error {
+			cancel()
+			for _, replicaPool := range replicaPools {
+				replicaPool.Close()
+			}
+			pool.Close()
+			return nil
+		}
+
+		return repositories.NewReplicatedCharacterRepository(
+			pool,
+			readReplicas,
+			cfg.Postgres.Timeout,
+		), closeStore, nil
+	case "sqlite":
+		sqlClient, err := repositories.NewSQLiteClient(cfg.Storage.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return repositories.NewSQLiteCharacterRepository(
+			sqlClient,
+			cfg.Postgres.Timeout,
+		), sqlClient.Close, nil
+	case "memory":
+		return repositories.NewMemoryCharacterRepository(
+			cfg.Postgres.Timeout,
+		), func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STORAGE_DRIVER %q", cfg.Storage.Driver)
+	}
+}
+
+func newCachedCharacterRepository(
+	cfg *config.Config,
+	characterStore domains.CharacterStore,
+	cache domains.Cache,
+) *repositories.CachedCharacterRepository {
+	return repositories.NewCachedCharacterRepository(
+		characterStore,
+		cache,
+		cfg.Cache.TTL,
+		cfg.Cache.NegativeTTL,
+		cfg.Postgres.ReadStickiness,
+	)
+}
+
+func newCharacterChangeListener(
+	cfg *config.Config,
+	cachedCharacterRepository *repositories.CachedCharacterRepository,
+) *repositories.CharacterChangeListener {
+	return repositories.NewCharacterChangeListener(
+		psqlConnString(cfg.Postgres),
+		cachedCharacterRepository.HandleCharacterChange,
+		cachedCharacterRepository.Flush,
+		cfg.Cache.ListenMinBackoff,
+		cfg.Cache.ListenMaxBackoff,
+	)
+}
+
+func newCache(cfg *config.Config) domains.Cache {
+	if cfg.Cache.Driver == "redis" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+
+		return caches.NewRedis(client, "dragon-ball:")
+	}
+
+	return caches.NewMemory(cfg.Cache.Size)
+}
+
+func newPurgeDeletedCharactersJob(
+	cfg *config.Config,
+	characterRepository domains.CharacterStore,
+) *jobs.PurgeDeletedCharactersJob {
+	return jobs.NewPurgeDeletedCharactersJob(
+		characterRepository,
+		cfg.Trash.Retention,
+		cfg.Trash.PurgeInterval,
+	)
+}
+
+func newSyncCharactersJob(
+	cfg *config.Config,
+	characterRepository domains.CharacterStore,
+) *jobs.SyncCharactersJob {
+	return jobs.NewSyncCharactersJob(
+		characterRepository,
+		characterRepository,
+		cfg.Sync.Interval,
+	)
+}
+
+// newLogger writes LOG_FORMAT lines to stderr from LOG_LEVEL up. The level
+// can be changed while running through the returned LevelVar.
+func newLogger(cfg config.Log) (*slog.LevelVar, *slog.Logger) {
+	logLevel := new(slog.LevelVar)
+	level, err := domains.ParseLogLevel(cfg.Level)
+	if err == nil {
+		logLevel.Set(level)
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	if cfg.Format == "text" {
+		return logLevel, slog.New(slog.NewTextHandler(os.Stderr, options))
+	}
+
+	return logLevel, slog.New(slog.NewJSONHandler(os.Stderr, options))
+}
+
+func newWebApp(
+	logger *slog.Logger,
+	m *metrics.Metrics,
+	serviceName string,
+	trustedProxies []string,
+	corsConfig config.CORS,
+) (*gin.Engine, error) {
+	corsMiddleware, err := middlewares.CORS(middlewares.CORSPolicy{
+		AllowOrigins:     corsConfig.AllowOrigins,
+		AllowMethods:     corsConfig.AllowMethods,
+		AllowHeaders:     corsConfig.AllowHeaders,
+		ExposeHeaders:    corsConfig.ExposeHeaders,
+		AllowCredentials: corsConfig.AllowCredentials,
+		MaxAge:           corsConfig.MaxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	app := gin.New()
+	app.ContextWithFallback = true
+	// Without trusted proxies the client address is the peer of the
+	// connection, so X-Forwarded-For cannot forge the actor of the audit.
+	if err := app.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
 
 	app.Use(
-		gin.Recovery(),
-		cors.New(cors.Config{
-			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowOrigins:     []string{"*"},
-			AllowCredentials: true,
-		}),
-		gin.Logger(),
+		middlewares.Metrics(m),
+		otelgin.Middleware(serviceName),
+		middlewares.RequestID(),
+		middlewares.Logger(logger),
+		middlewares.Auditor(),
+		corsMiddleware,
+		middlewares.Problems(middlewares.NewDomainProblemRegistry()),
+		middlewares.Recovery(),
 	)
 
 	return app, nil
 }
 
-func validateEnvironmentVariables() error {
-	eScope := os.Getenv("SCOPE")
-	if eScope != "local" && eScope != "test" && eScope != "qa" && eScope != "prod" {
-		return errors.New("there was a problem reading environment variables SCOPE=[local,test,qa,prod]")
+func psqlConnString(cfg config.Postgres) string {
+	return psqlConnStringFor(cfg, cfg.Host, cfg.Port)
+}
+
+func psqlConnStringFor(
+	cfg config.Postgres,
+	host string,
+	port int,
+) string {
+	return fmt.Sprintf(
+		"host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
+		host,
+		port,
+		cfg.Name,
+		cfg.User,
+		cfg.Pass,
+		"disable",
+	)
+}
+
+// newReadReplicas opens a pool on every replica in PSQL_REPLICA_HOSTS, host or
+// host:port, with the database and credentials of the primary.
+func newReadReplicas(cfg config.Postgres) (*repositories.ReadReplicas, []*pgxpool.Pool, error) {
+	var pools []*pgxpool.Pool
+	for _, replica := range cfg.ReplicaHosts {
+		host, port := replica, cfg.Port
+		if h, p, found := strings.Cut(replica, ":"); found {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				closePools(pools)
+				return nil, nil, fmt.Errorf("port of replica %q: %w", replica, err)
+			}
+			host, port = h, n
+		}
+
+		pool, err := newPostgresPool(cfg, psqlConnStringFor(cfg, host, port))
+		if err != nil {
+			closePools(pools)
+			return nil, nil, err
+		}
+		pools = append(pools, pool)
+	}
+
+	return repositories.NewReadReplicas(
+		pools,
+		cfg.ReadStickiness,
+		cfg.ReplicaMaxLag,
+		cfg.ReplicaCheckInterval,
+	), pools, nil
+}
+
+func closePools(pools []*pgxpool.Pool) {
+	for _, pool := range pools {
+		pool.Close()
+	}
+}
+
+// newHealthRegistry checks the storage, its schema and the external API; the
+// cache is checked once main opens it.
+func newHealthRegistry(
+	cfg *config.Config,
+	characterStore domains.CharacterStore,
+) *health.Registry {
+	healthRegistry := health.NewRegistry(cfg.Health.CacheTTL)
+	healthRegistry.Register(health.StorageCheck(cfg.Storage.Driver, characterStore, cfg.Health.CheckTimeout))
+	if schemaVersioner, ok := characterStore.(domains.SchemaVersioner); ok {
+		healthRegistry.Register(health.SchemaCheck(schemaVersioner, repositories.SchemaVersion, cfg.Health.CheckTimeout))
+	}
+	if externalAPIPinger, ok := characterStore.(domains.ExternalAPIPinger); ok {
+		healthRegistry.Register(health.ExternalAPICheck(externalAPIPinger, cfg.Health.CheckTimeout))
+	}
+
+	return healthRegistry
+}
+
+// newTokenVerifier verifies the JWTs with the JWKS of JWT_JWKS_FILE, read
+// once, or of JWT_JWKS_URL, fetched when first needed. It answers nil, and
+// JWTs are refused, when neither is set.
+func newTokenVerifier(cfg config.JWT) (domains.TokenVerifier, error) {
+	var keys tokens.KeySet
+	switch {
+	case cfg.JWKSFile != "":
+		keySet, err := tokens.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = keySet
+	case cfg.JWKSURL != "":
+		client := &http.Client{
+			Timeout:   jwksTimeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}
+		keys = tokens.NewRemoteKeySet(client, cfg.JWKSURL, cfg.JWKSRefresh)
+	default:
+		return nil, nil
 	}
-	err := godotenv.Load(folderEnv + "/.env." + eScope)
+
+	return tokens.NewVerifier(keys, tokens.Options{
+		Issuer:        cfg.Issuer,
+		Audience:      cfg.Audience,
+		RequireExpiry: cfg.RequireExpiry,
+		Leeway:        cfg.Leeway,
+		Scopes: map[domains.Role]string{
+			domains.RoleReader: cfg.ReaderScope,
+			domains.RoleEditor: cfg.EditorScope,
+			domains.RoleAdmin:  cfg.AdminScope,
+		},
+	}), nil
+}
+
+// newTracerProvider installs the tracer provider exporting to
+// OTEL_TRACES_EXPORTER as the global one, which the SQL statements and the
+// calls to the external API are traced with.
+func newTracerProvider(cfg config.Tracing) (*sdktrace.TracerProvider, error) {
+	exporter, err := tracing.NewExporter(context.Background(), cfg.Exporter, cfg.Endpoint)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	psqlHost := os.Getenv("PSQL_HOST")
-	psqlPort := os.Getenv("PSQL_PORT")
-	psqlName := os.Getenv("PSQL_NAME")
-	psqlUser := os.Getenv("PSQL_USER")
-	psqlPass := os.Getenv("PSQL_PASS")
-	psqlTimeout := os.Getenv("PSQL_TIMEOUT")
-	if psqlHost == "" || psqlPort == "" || psqlName == "" ||
-		psqlUser == "" || psqlPass == "" || psqlTimeout == "" {
-		return errors.New("there was a problem reading environment variables PSQL")
+	tracerProvider := tracing.NewTracerProvider(exporter, cfg.ServiceName, cfg.SampleRatio)
+	tracing.Install(tracerProvider)
+
+	return tracerProvider, nil
+}
+
+// newPostgresPool sizes the pool with PSQL_MAX_CONNS, PSQL_MIN_CONNS,
+// PSQL_MAX_CONN_LIFETIME and PSQL_MAX_CONN_IDLE_TIME. Prepared statements are
+// cached per connection, PSQL_STATEMENT_CACHE_CAPACITY of them; 0 turns the
+// cache off for poolers such as PgBouncer in transaction mode. Every
+// statement is traced with the global tracer provider.
+func newPostgresPool(
+	cfg config.Postgres,
+	connString string,
+) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.ConnConfig.Tracer = repositories.NewPostgresTracer(otel.Tracer(tracing.Name))
+	poolConfig.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	if cfg.StatementCacheCapacity == 0 {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
 	}
 
-	return nil
+	return pgxpool.NewWithConfig(context.Background(), poolConfig)
 }
 
 func main() {
-	err := validateEnvironmentVariables()
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv, folderEnv)
 	if err != nil {
-		log.Println("error when execute validateEnvironmentVariables, err: " + err.Error())
-		return
+		slog.Error("error when execute config.Load", "err", err)
+		os.Exit(1)
 	}
 
-	app, err := newWebApp()
-	if err != nil {
-		log.Println("error when execute newWebApp, err: " + err.Error())
+	logLevel, logger := newLogger(cfg.Log)
+	slog.SetDefault(logger)
+	if logLevel.Level() > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	if len(args) > 0 && args[0] == "config" {
+		if err := cfg.Print(os.Stdout); err != nil {
+			slog.Error("error when execute cfg.Print", "err", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	// init sqlClient
-	sqlClient, err := sql.Open(
-		"postgres",
-		fmt.Sprintf(
-			"host=%s port=%s dbname=%s user=%s password=%s sslmode=%s",
-			os.Getenv("PSQL_HOST"),
-			os.Getenv("PSQL_PORT"),
-			os.Getenv("PSQL_NAME"),
-			os.Getenv("PSQL_USER"),
-			os.Getenv("PSQL_PASS"),
-			"disable",
-		),
-	)
+	// init characterStore
+	characterStore, closeStore, err := newCharacterStore(cfg)
 	if err != nil {
-		log.Println("error when execute sql.Open, err: " + err.Error())
+		slog.Error("error when execute newCharacterStore", "err", err)
 		return
 	}
 	defer func() {
-		err := sqlClient.Close()
+		err := closeStore()
 		if err != nil {
-			log.Println("error when execute sqlClient.Close, err: " + err.Error())
+			slog.Error("error when execute closeStore", "err", err)
 			return
 		}
 	}()
 
+	if len(args) > 0 {
+		if err := runCommand(cfg, characterStore, args); err != nil {
+			slog.Error("error when execute runCommand", "err", err)
+			closeStore()
+			os.Exit(1)
+		}
+		return
+	}
+
+	m := metrics.New()
+	if store, ok := characterStore.(interface{ SQLClients() map[string]*sql.DB }); ok {
+		for name, sqlClient := range store.SQLClients() {
+			if err := m.RegisterDB(name, sqlClient); err != nil {
+				slog.Error("error when execute metrics.RegisterDB", "err", err)
+				return
+			}
+		}
+	}
+
+	tracerProvider, err := newTracerProvider(cfg.Tracing)
+	if err != nil {
+		slog.Error("error when execute newTracerProvider", "err", err)
+		return
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			slog.Error("error when execute tracerProvider.Shutdown", "err", err)
+		}
+	}()
+	healthRegistry := newHealthRegistry(cfg, characterStore)
+	apiKeyRepository, ok := characterStore.(domains.APIKeyRepository)
+	if !ok {
+		slog.Error("error when execute newCharacterStore, the storage keeps no API keys", "driver", cfg.Storage.Driver)
+		return
+	}
+	tokenVerifier, err := newTokenVerifier(cfg.JWT)
+	if err != nil {
+		slog.Error("error when execute newTokenVerifier", "err", err)
+		return
+	}
+	if !cfg.Auth.Enabled {
+		slog.Warn("AUTH_ENABLED is false, anyone reaching the web app may change characters")
+	}
+	characterStore = repositories.NewInstrumentedCharacterStore(characterStore, m, tracerProvider.Tracer(tracing.Name))
+
+	cache := newCache(cfg)
+	healthRegistry.Register(health.CacheCheck(cache, cfg.Health.CheckTimeout))
+	cachedCharacterRepository := newCachedCharacterRepository(cfg, characterStore, cache)
+	if err := m.RegisterCache(cachedCharacterRepository); err != nil {
+		slog.Error("error when execute metrics.RegisterCache", "err", err)
+		return
+	}
+
+	app, err := newWebApp(logger, m, cfg.Tracing.ServiceName, cfg.Web.TrustedProxies, cfg.CORS)
+	if err != nil {
+		slog.Error("error when execute newWebApp", "err", err)
+		return
+	}
+
+	// The workers are stopped in the order they start: the jobs writing to
+	// the store first, then the listener keeping the cache fresh.
+	var background workers
+	var draining atomic.Bool
+
+	purgeDeletedCharactersJob := newPurgeDeletedCharactersJob(cfg, cachedCharacterRepository)
+	background.start(
+		domains.WithAuditor(context.Background(), "system", domains.AuditSourcePurge),
+		"PurgeDeletedCharactersJob",
+		purgeDeletedCharactersJob.Run,
+	)
+
+	syncCharactersJob := newSyncCharactersJob(cfg, cachedCharacterRepository)
+	if syncCharactersJob.Enabled() {
+		background.start(
+			domains.WithAuditor(context.Background(), "system", domains.AuditSourceSync),
+			"SyncCharactersJob",
+			syncCharactersJob.Run,
+		)
+	}
+
+	// Only Postgres is shared between replicas; the embedded backends have
+	// no one else writing to them.
+	if cfg.Storage.Driver == "postgres" {
+		characterChangeListener := newCharacterChangeListener(cfg, cachedCharacterRepository)
+		background.start(context.Background(), "CharacterChangeListener", characterChangeListener.Run)
+	}
+
 	app, err = addRoutes(
 		app,
-		sqlClient,
+		cfg,
+		cachedCharacterRepository,
+		apiKeyRepository,
+		tokenVerifier,
+		&draining,
+		logLevel,
+		m,
+		healthRegistry,
 	)
 	if err != nil {
-		log.Println("error when execute addRoutes, err: " + err.Error())
+		slog.Error("error when execute addRoutes", "err", err)
+		background.stop(context.Background())
 		return
 	}
 
-	if err := app.Run(
-		fmt.Sprintf(":%s", webPort),
-	); err != nil {
-		log.Println("error when execute app.Run, err: " + err.Error())
-		return
+	server := newWebServer(cfg.Web, app)
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		slog.Error("error when execute server.ListenAndServe", "err", err)
+		background.stop(context.Background())
+	case <-signals.Done():
+		// A second signal kills the process without waiting.
+		stopSignals()
+		shutdown(cfg.Web, server, &background, &draining)
+	}
+}
+
+func newWebServer(
+	cfg config.Web,
+	handler http.Handler,
+) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// shutdown fails readyz for WEB_DRAIN_DELAY so load balancers take the
+// instance out, then stops accepting connections, waits for the in-flight
+// requests and stops the workers, all within WEB_SHUTDOWN_TIMEOUT. The store
+// is closed after it returns.
+func shutdown(
+	cfg config.Web,
+	server *http.Server,
+	background *workers,
+	draining *atomic.Bool,
+) {
+	slog.Info("shutting down, draining in-flight requests")
+	draining.Store(true)
+	time.Sleep(cfg.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("error when execute server.Shutdown", "err", err)
+		server.Close()
 	}
+	background.stop(ctx)
 }
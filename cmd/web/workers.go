@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// workers are the background loops of the web app. Each one runs until its
+// own context is cancelled, so they can be stopped one after the other.
+type workers struct {
+	started []*worker
+}
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (w *workers) start(
+	ctx context.Context,
+	name string,
+	run func(ctx context.Context),
+) {
+	ctx, cancel := context.WithCancel(domains.WithLogAttrs(ctx, "worker", name))
+	started := &worker{
+		name:   name,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	w.started = append(w.started, started)
+
+	go func() {
+		defer close(started.done)
+		run(ctx)
+	}()
+}
+
+// stop cancels the workers in the order they were started, waiting for each
+// one to return before the next, until ctx is done.
+func (w *workers) stop(ctx context.Context) {
+	for _, started := range w.started {
+		started.cancel()
+
+		select {
+		case <-started.done:
+		case <-ctx.Done():
+			slog.Error("error when execute workers.stop, the worker did not stop in time", "worker", started.name, "err", ctx.Err())
+		}
+	}
+}
@@ -1,4 +1,3 @@
-
 CREATE TABLE IF NOT EXISTS character_dragonball (
 	id INT NOT NULL,
 	name VARCHAR(64) NOT NULL,
@@ -8,4 +7,99 @@ CREATE TABLE IF NOT EXISTS character_dragonball (
 	PRIMARY KEY (id)
 );
 
-CREATE INDEX idx_character_name ON character_dragonball (name);
+CREATE INDEX IF NOT EXISTS idx_character_name ON character_dragonball (name);
+
+ALTER TABLE character_dragonball ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+ALTER TABLE character_dragonball ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE character_dragonball ADD COLUMN IF NOT EXISTS overridden_fields JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE character_dragonball ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- updated_at backs Last-Modified: every update of a character touches it.
+CREATE OR REPLACE FUNCTION character_dragonball_touch() RETURNS trigger AS $$
+BEGIN
+	NEW.updated_at = NOW();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_character_dragonball_touch ON character_dragonball;
+CREATE TRIGGER trg_character_dragonball_touch
+	BEFORE UPDATE ON character_dragonball
+	FOR EACH ROW EXECUTE FUNCTION character_dragonball_touch();
+
+CREATE INDEX IF NOT EXISTS idx_character_deleted_at ON character_dragonball (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS character_audit (
+	id BIGSERIAL NOT NULL,
+	character_id INT NOT NULL,
+	action VARCHAR(16) NOT NULL,
+	actor VARCHAR(128) NOT NULL,
+	source VARCHAR(16) NOT NULL,
+	before JSONB NULL,
+	after JSONB NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_character_audit_character_id ON character_audit (character_id, id);
+
+-- character_audit is append-only: reject any change to an existing entry.
+CREATE OR REPLACE FUNCTION character_audit_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'character_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_character_audit_append_only ON character_audit;
+CREATE TRIGGER trg_character_audit_append_only
+	BEFORE UPDATE OR DELETE ON character_audit
+	FOR EACH ROW EXECUTE FUNCTION character_audit_append_only();
+
+CREATE TABLE IF NOT EXISTS character_version (
+	character_id INT NOT NULL,
+	version INT NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	ki VARCHAR(256) NOT NULL,
+	race VARCHAR(64) NOT NULL,
+	image VARCHAR(256) NOT NULL,
+	deleted_at TIMESTAMPTZ NULL,
+	valid_from TIMESTAMPTZ NOT NULL,
+	valid_to TIMESTAMPTZ NULL,
+	PRIMARY KEY (character_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_character_version_open ON character_version (character_id) WHERE valid_to IS NULL;
+
+-- Rows written before versioning existed start with a first version.
+INSERT INTO character_version (character_id, version, name, ki, race, image, deleted_at, valid_from)
+SELECT c.id, 1, c.name, c.ki, c.race, c.image, c.deleted_at, NOW()
+FROM character_dragonball c
+WHERE NOT EXISTS (SELECT 1 FROM character_version v WHERE v.character_id = c.id);
+
+-- api_key holds the keys of the API: the SHA-256 of each secret, never the
+-- secret. Revoked keys are kept so their names stay in the audit trail.
+CREATE TABLE IF NOT EXISTS api_key (
+	id BIGSERIAL NOT NULL,
+	name VARCHAR(128) NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	revoked_at TIMESTAMPTZ NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_name ON api_key (name);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_key_hash ON api_key (key_hash);
+
+-- schema_version is checked by /api/readyz against the version the web app
+-- expects, repositories.SchemaVersion: bump both with every change above.
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INT NOT NULL
+);
+
+DELETE FROM schema_version;
+INSERT INTO schema_version (version) VALUES (2);
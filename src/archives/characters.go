@@ -0,0 +1,132 @@
+package archives
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+const Format = "dragon-ball/characters"
+const Version = 1
+
+// Manifest is the first line of an archive. Checksum is the hex encoded
+// sha256 of every record line that follows it, newlines included.
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Count     int       `json:"count"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func WriteCharacters(
+	w io.Writer,
+	characters []domains.Character,
+	createdAt time.Time,
+) error {
+	var records bytes.Buffer
+	encoder := json.NewEncoder(&records)
+	for _, character := range characters {
+		if err := encoder.Encode(character); err != nil {
+			return err
+		}
+	}
+
+	checksum := sha256.Sum256(records.Bytes())
+	manifest := Manifest{
+		Format:    Format,
+		Version:   Version,
+		Count:     len(characters),
+		Checksum:  hex.EncodeToString(checksum[:]),
+		CreatedAt: createdAt.UTC(),
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	if err := json.NewEncoder(gzipWriter).Encode(manifest); err != nil {
+		return err
+	}
+	if _, err := records.WriteTo(gzipWriter); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// ReadCharacters reads at most maxSize bytes of the decompressed archive and
+// answers domains.ErrArchiveTooLarge past them. Errors of r are wrapped, so
+// callers can tell them apart.
+func ReadCharacters(r io.Reader, maxSize int64) (Manifest, []domains.Character, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("%w: %w", domains.ErrArchiveInvalid, err)
+	}
+	defer gzipReader.Close()
+
+	// One byte past maxSize tells an archive that is too large from one of
+	// exactly maxSize; any error after reading it comes from the cut.
+	limited := &io.LimitedReader{R: gzipReader, N: maxSize + 1}
+	fail := func(err error) (Manifest, []domains.Character, error) {
+		if limited.N <= 0 {
+			return Manifest{}, nil, domains.ErrArchiveTooLarge
+		}
+		return Manifest{}, nil, err
+	}
+
+	scanner := bufio.NewScanner(limited)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fail(fmt.Errorf("%w: %w", domains.ErrArchiveInvalid, err))
+		}
+		return fail(fmt.Errorf("%w: missing manifest", domains.ErrArchiveInvalid))
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(scanner.Bytes(), &manifest); err != nil {
+		return fail(fmt.Errorf("%w: %v", domains.ErrArchiveInvalid, err))
+	}
+	if manifest.Format != Format {
+		return fail(fmt.Errorf("%w: unknown format %q", domains.ErrArchiveInvalid, manifest.Format))
+	}
+	if manifest.Version != Version {
+		return fail(domains.ErrArchiveVersionNotSupported)
+	}
+
+	hash := sha256.New()
+	characters := []domains.Character{}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		hash.Write(line)
+		hash.Write([]byte("\n"))
+
+		var character domains.Character
+		if err := json.Unmarshal(line, &character); err != nil {
+			return fail(fmt.Errorf("%w: record %d: %v", domains.ErrArchiveInvalid, len(characters)+1, err))
+		}
+		characters = append(characters, character)
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(fmt.Errorf("%w: %w", domains.ErrArchiveInvalid, err))
+	}
+	if limited.N <= 0 {
+		return Manifest{}, nil, domains.ErrArchiveTooLarge
+	}
+
+	if len(characters) != manifest.Count {
+		return Manifest{}, nil, fmt.Errorf("%w: expected %d records, got %d", domains.ErrArchiveInvalid, manifest.Count, len(characters))
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != manifest.Checksum {
+		return Manifest{}, nil, domains.ErrArchiveChecksumMismatch
+	}
+
+	return manifest, characters, nil
+}
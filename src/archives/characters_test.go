@@ -0,0 +1,108 @@
+package archives
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteCharacters(t *testing.T) {
+	characters := []domains.Character{
+		{
+			ID:    1,
+			Name:  "goku",
+			Ki:    "60.000.000",
+			Race:  "Saiyan",
+			Image: "https://dragonball-api.com/characters/goku_normal.webp",
+		},
+		{
+			ID:    2,
+			Name:  "vegeta",
+			Ki:    "54.000.000",
+			Race:  "Saiyan",
+			Image: "https://dragonball-api.com/characters/vegeta_normal.webp",
+		},
+	}
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("given characters, it writes an archive that reads back", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCharacters(&buf, characters, createdAt))
+
+		manifest, result, err := ReadCharacters(&buf, 1<<20)
+		require.NoError(t, err)
+
+		assert.Equal(t, Format, manifest.Format)
+		assert.Equal(t, Version, manifest.Version)
+		assert.Equal(t, 2, manifest.Count)
+		assert.Equal(t, createdAt, manifest.CreatedAt)
+		assert.Equal(t, characters, result)
+	})
+
+	t.Run("given no characters, it writes an empty archive", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCharacters(&buf, []domains.Character{}, createdAt))
+
+		manifest, result, err := ReadCharacters(&buf, 1<<20)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, manifest.Count)
+		assert.Empty(t, result)
+	})
+}
+
+func Test_ReadCharacters(t *testing.T) {
+	gzipLines := func(t *testing.T, lines string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&buf)
+		_, err := gzipWriter.Write([]byte(lines))
+		require.NoError(t, err)
+		require.NoError(t, gzipWriter.Close())
+		return &buf
+	}
+
+	t.Run("given a body that is not gzip, it returns ErrArchiveInvalid", func(t *testing.T) {
+		_, _, err := ReadCharacters(bytes.NewBufferString("not an archive"), 1<<20)
+		assert.ErrorIs(t, err, domains.ErrArchiveInvalid)
+	})
+
+	t.Run("given an unknown version, it returns ErrArchiveVersionNotSupported", func(t *testing.T) {
+		buf := gzipLines(t, `{"format":"dragon-ball/characters","version":99,"count":0,"checksum":""}`+"\n")
+
+		_, _, err := ReadCharacters(buf, 1<<20)
+		assert.ErrorIs(t, err, domains.ErrArchiveVersionNotSupported)
+	})
+
+	t.Run("given a tampered record, it returns ErrArchiveChecksumMismatch", func(t *testing.T) {
+		buf := gzipLines(t,
+			`{"format":"dragon-ball/characters","version":1,"count":1,"checksum":"0000"}`+"\n"+
+				`{"id":1,"name":"goku","ki":"1","race":"Saiyan","image":""}`+"\n",
+		)
+
+		_, _, err := ReadCharacters(buf, 1<<20)
+		assert.ErrorIs(t, err, domains.ErrArchiveChecksumMismatch)
+	})
+
+	t.Run("given an archive larger than the limit once decompressed, it returns ErrArchiveTooLarge", func(t *testing.T) {
+		lines := `{"format":"dragon-ball/characters","version":1,"count":0,"checksum":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}` + "\n"
+
+		_, result, err := ReadCharacters(gzipLines(t, lines), int64(len(lines)))
+		require.NoError(t, err)
+		assert.Empty(t, result)
+
+		_, _, err = ReadCharacters(gzipLines(t, lines), int64(len(lines)-1))
+		assert.ErrorIs(t, err, domains.ErrArchiveTooLarge)
+	})
+
+	t.Run("given a wrong record count, it returns ErrArchiveInvalid", func(t *testing.T) {
+		buf := gzipLines(t, `{"format":"dragon-ball/characters","version":1,"count":3,"checksum":""}`+"\n")
+
+		_, _, err := ReadCharacters(buf, 1<<20)
+		assert.ErrorIs(t, err, domains.ErrArchiveInvalid)
+	})
+}
@@ -0,0 +1,103 @@
+package caches
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_Caches runs the same behaviour against every Cache, with an
+// in-process Redis standing in for the real one.
+func Test_Caches(t *testing.T) {
+	newMemory := func(t *testing.T) (domains.Cache, func(time.Duration)) {
+		now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		cache := NewMemory(10)
+		cache.lru.now = func() time.Time { return now }
+
+		return cache, func(d time.Duration) { now = now.Add(d) }
+	}
+	newRedis := func(t *testing.T) (domains.Cache, func(time.Duration)) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		return NewRedis(client, "dragon-ball:"), server.FastForward
+	}
+
+	for name, newCache := range map[string]func(t *testing.T) (domains.Cache, func(time.Duration)){
+		"memory": newMemory,
+		"redis":  newRedis,
+	} {
+		t.Run(name+": given a stored key, it returns its value", func(t *testing.T) {
+			cache, _ := newCache(t)
+			require.NoError(t, cache.Set(context.Background(), "goku", []byte("saiyan"), time.Minute))
+
+			value, ok, err := cache.Get(context.Background(), "goku")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte("saiyan"), value)
+		})
+
+		t.Run(name+": given an unknown key, it misses without error", func(t *testing.T) {
+			cache, _ := newCache(t)
+
+			_, ok, err := cache.Get(context.Background(), "goku")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+
+		t.Run(name+": given an expired key, it misses", func(t *testing.T) {
+			cache, advance := newCache(t)
+			require.NoError(t, cache.Set(context.Background(), "goku", []byte("saiyan"), time.Second))
+
+			advance(2 * time.Second)
+
+			_, ok, err := cache.Get(context.Background(), "goku")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+
+		t.Run(name+": given deleted keys, they miss", func(t *testing.T) {
+			cache, _ := newCache(t)
+			require.NoError(t, cache.Set(context.Background(), "goku", []byte("saiyan"), time.Minute))
+			require.NoError(t, cache.Set(context.Background(), "vegeta", []byte("saiyan"), time.Minute))
+
+			require.NoError(t, cache.Delete(context.Background(), "goku", "vegeta", "krillin"))
+
+			for _, key := range []string{"goku", "vegeta"} {
+				_, ok, err := cache.Get(context.Background(), key)
+				require.NoError(t, err)
+				assert.False(t, ok, key)
+			}
+		})
+	}
+
+	t.Run("redis: given a prefix, it namespaces the keys", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+
+		cache := NewRedis(client, "dragon-ball:")
+		require.NoError(t, cache.Set(context.Background(), "goku", []byte("saiyan"), time.Minute))
+
+		value, err := server.Get("dragon-ball:goku")
+		require.NoError(t, err)
+		assert.Equal(t, "saiyan", value)
+	})
+
+	t.Run("redis: given a server that is down, it returns an error", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		defer client.Close()
+		server.Close()
+
+		_, _, err := NewRedis(client, "").Get(context.Background(), "goku")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package caches
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LRU(t *testing.T) {
+	t.Run("given a full cache, it evicts the least recently used key", func(t *testing.T) {
+		cache := NewLRU[string, int](2)
+		cache.Set("goku", 1, time.Minute)
+		cache.Set("vegeta", 2, time.Minute)
+
+		_, ok := cache.Get("goku")
+		assert.True(t, ok)
+
+		cache.Set("krillin", 3, time.Minute)
+
+		_, ok = cache.Get("vegeta")
+		assert.False(t, ok)
+		value, ok := cache.Get("goku")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		assert.Equal(t, 2, cache.Len())
+		assert.Equal(t, uint64(1), cache.Evictions())
+	})
+
+	t.Run("given an expired key, it misses and drops it", func(t *testing.T) {
+		now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		cache := NewLRU[string, int](2)
+		cache.now = func() time.Time { return now }
+
+		cache.Set("goku", 1, time.Second)
+		now = now.Add(time.Second)
+
+		_, ok := cache.Get("goku")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("given a deleted key, it misses", func(t *testing.T) {
+		cache := NewLRU[string, int](2)
+		cache.Set("goku", 1, time.Minute)
+		cache.Delete("goku")
+
+		_, ok := cache.Get("goku")
+		assert.False(t, ok)
+	})
+
+	t.Run("given a cleared cache, it misses every key", func(t *testing.T) {
+		cache := NewLRU[string, int](2)
+		cache.Set("goku", 1, time.Minute)
+		cache.Set("vegeta", 2, time.Minute)
+		cache.Clear()
+
+		_, ok := cache.Get("goku")
+		assert.False(t, ok)
+		_, ok = cache.Get("vegeta")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+		assert.Equal(t, uint64(0), cache.Evictions())
+
+		cache.Set("krillin", 3, time.Minute)
+		value, ok := cache.Get("krillin")
+		assert.True(t, ok)
+		assert.Equal(t, 3, value)
+	})
+
+	t.Run("given a zero size, it stores nothing", func(t *testing.T) {
+		cache := NewLRU[string, int](0)
+		cache.Set("goku", 1, time.Minute)
+
+		_, ok := cache.Get("goku")
+		assert.False(t, ok)
+	})
+
+	t.Run("given concurrent readers and writers, it stays within its size", func(t *testing.T) {
+		cache := NewLRU[string, int](16)
+
+		var wg sync.WaitGroup
+		for worker := 0; worker < 8; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					key := fmt.Sprintf("character-%d", (worker*1000+i)%64)
+					cache.Set(key, i, time.Minute)
+					cache.Get(key)
+					if i%10 == 0 {
+						cache.Delete(key)
+					}
+				}
+			}(worker)
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, cache.Len(), 16)
+	})
+}
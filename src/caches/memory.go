@@ -0,0 +1,58 @@
+package caches
+
+import (
+	"context"
+	"time"
+)
+
+// Memory is a Cache local to the process, backed by an LRU.
+type Memory struct {
+	lru *LRU[string, []byte]
+}
+
+func NewMemory(size int) *Memory {
+
+	return &Memory{
+		lru: NewLRU[string, []byte](size),
+	}
+}
+
+func (c *Memory) Get(
+	_ context.Context,
+	key string,
+) ([]byte, bool, error) {
+	value, ok := c.lru.Get(key)
+	return value, ok, nil
+}
+
+func (c *Memory) Set(
+	_ context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+	c.lru.Set(key, value, ttl)
+	return nil
+}
+
+func (c *Memory) Delete(
+	_ context.Context,
+	keys ...string,
+) error {
+	for _, key := range keys {
+		c.lru.Delete(key)
+	}
+	return nil
+}
+
+func (c *Memory) Clear() {
+	c.lru.Clear()
+}
+
+func (c *Memory) Len() int {
+	return c.lru.Len()
+}
+
+func (c *Memory) Evictions() uint64 {
+	return c.lru.Evictions()
+}
@@ -0,0 +1,71 @@
+package caches
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Cache shared by every replica, spoken to over the Redis
+// protocol. Keys are namespaced with prefix.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedis(
+	client redis.UniversalClient,
+	prefix string,
+) *Redis {
+
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (c *Redis) Get(
+	ctx context.Context,
+	key string,
+) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (c *Redis) Set(
+	ctx context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(
+	ctx context.Context,
+	keys ...string,
+) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+
+	return c.client.Del(ctx, prefixed...).Err()
+}
@@ -0,0 +1,381 @@
+// Package config reads the settings of the web app and its commands. Every
+// setting has a key, the name of its environment variable, and is layered
+// from, lowest first: its default, the config file, the .env files of the
+// scope, the environment and the command line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrConfigInvalid = errors.New("configuration is invalid")
+
+type Config struct {
+	Scope      string     `key:"SCOPE" file:"scope" usage:"environment to run as: local, test, qa or prod"`
+	Log        Log        `file:"log"`
+	Tracing    Tracing    `file:"tracing"`
+	Web        Web        `file:"web"`
+	CORS       CORS       `file:"cors"`
+	Health     Health     `file:"health"`
+	Auth       Auth       `file:"auth"`
+	JWT        JWT        `file:"jwt"`
+	Storage    Storage    `file:"storage"`
+	Postgres   Postgres   `file:"psql"`
+	Cache      Cache      `file:"cache"`
+	Redis      Redis      `file:"redis"`
+	HTTPCache  HTTPCache  `file:"http_cache"`
+	Characters Characters `file:"characters"`
+	Import     Import     `file:"import"`
+	Trash      Trash      `file:"trash"`
+	Sync       Sync       `file:"sync"`
+
+	sources map[string]string
+}
+
+type Log struct {
+	Level  string `key:"LOG_LEVEL" file:"level" default:"info" usage:"lowest level logged: debug, info, warn or error"`
+	Format string `key:"LOG_FORMAT" file:"format" default:"json" usage:"log line format: json or text"`
+}
+
+type Tracing struct {
+	Exporter    string  `key:"OTEL_TRACES_EXPORTER" file:"exporter" default:"none" usage:"where spans are sent: otlp, stdout or none"`
+	Endpoint    string  `key:"OTEL_EXPORTER_OTLP_ENDPOINT" file:"endpoint" usage:"URL of the OTLP/HTTP collector, http://localhost:4318 when empty"`
+	ServiceName string  `key:"OTEL_SERVICE_NAME" file:"service_name" default:"dragon-ball" usage:"service.name of the spans"`
+	SampleRatio float64 `key:"OTEL_TRACES_SAMPLER_ARG" file:"sample_ratio" default:"1" usage:"share of the new traces sampled, from 0 to 1"`
+}
+
+type Web struct {
+	Port              int           `key:"WEB_PORT" file:"port" default:"8080" usage:"port the web app listens on"`
+	ReadHeaderTimeout time.Duration `key:"WEB_READ_HEADER_TIMEOUT" file:"read_header_timeout" default:"5s" usage:"time to read the headers of a request"`
+	ReadTimeout       time.Duration `key:"WEB_READ_TIMEOUT" file:"read_timeout" default:"15s" usage:"time to read a whole request, body included"`
+	WriteTimeout      time.Duration `key:"WEB_WRITE_TIMEOUT" file:"write_timeout" default:"60s" usage:"time to handle a request and write its response"`
+	IdleTimeout       time.Duration `key:"WEB_IDLE_TIMEOUT" file:"idle_timeout" default:"120s" usage:"how long an idle keep-alive connection stays open"`
+	DrainDelay        time.Duration `key:"WEB_DRAIN_DELAY" file:"drain_delay" default:"5s" usage:"how long readyz fails before the listener closes on shutdown"`
+	ShutdownTimeout   time.Duration `key:"WEB_SHUTDOWN_TIMEOUT" file:"shutdown_timeout" default:"20s" usage:"deadline for in-flight requests and workers to finish on shutdown"`
+	TrustedProxies    []string      `key:"WEB_TRUSTED_PROXIES" file:"trusted_proxies" usage:"comma separated addresses or CIDRs of the proxies whose X-Forwarded-For is trusted, none when empty"`
+}
+
+type CORS struct {
+	AllowOrigins     []string      `key:"CORS_ALLOW_ORIGINS" file:"allow_origins" usage:"comma separated origins browsers may call the API from, such as https://*.example.com, * for any, none when empty"`
+	AllowMethods     []string      `key:"CORS_ALLOW_METHODS" file:"allow_methods" default:"GET,POST,PUT,PATCH,DELETE" usage:"comma separated methods the origins may send"`
+	AllowHeaders     []string      `key:"CORS_ALLOW_HEADERS" file:"allow_headers" default:"Origin,Content-Type,Accept,Authorization,X-API-Key,X-Request-ID,If-Match,If-None-Match,If-Modified-Since" usage:"comma separated headers the origins may send"`
+	ExposeHeaders    []string      `key:"CORS_EXPOSE_HEADERS" file:"expose_headers" default:"ETag,Last-Modified,X-Request-ID" usage:"comma separated response headers the origins may read"`
+	AllowCredentials bool          `key:"CORS_ALLOW_CREDENTIALS" file:"allow_credentials" default:"false" usage:"let the origins send cookies and Authorization, not with CORS_ALLOW_ORIGINS *"`
+	MaxAge           time.Duration `key:"CORS_MAX_AGE" file:"max_age" default:"10m" usage:"how long browsers may reuse a preflight response"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `key:"HEALTH_CHECK_TIMEOUT" file:"check_timeout" default:"2s" usage:"bound of each dependency check of readyz"`
+	CacheTTL     time.Duration `key:"HEALTH_CACHE_TTL" file:"cache_ttl" default:"5s" usage:"how long readyz answers the same checks, 0 to check on every probe"`
+}
+
+type Auth struct {
+	Enabled     bool `key:"AUTH_ENABLED" file:"enabled" default:"true" usage:"require API keys or JWTs, false lets anyone change characters"`
+	PublicReads bool `key:"AUTH_PUBLIC_READS" file:"public_reads" default:"true" usage:"let requests without an API key or JWT read characters"`
+}
+
+type JWT struct {
+	JWKSURL       string        `key:"JWT_JWKS_URL" file:"jwks_url" usage:"URL of the JWKS verifying the JWTs, JWTs are refused when it and JWT_JWKS_FILE are empty"`
+	JWKSFile      string        `key:"JWT_JWKS_FILE" file:"jwks_file" usage:"file of the JWKS verifying the JWTs, instead of JWT_JWKS_URL"`
+	JWKSRefresh   time.Duration `key:"JWT_JWKS_REFRESH" file:"jwks_refresh" default:"10m" usage:"how long the JWKS of JWT_JWKS_URL is used before being fetched again"`
+	Issuer        string        `key:"JWT_ISSUER" file:"issuer" usage:"iss the JWTs must have, any when empty"`
+	Audience      string        `key:"JWT_AUDIENCE" file:"audience" usage:"aud the JWTs must include, required when JWT_JWKS_URL or JWT_JWKS_FILE is set"`
+	RequireExpiry bool          `key:"JWT_REQUIRE_EXP" file:"require_exp" default:"true" usage:"refuse JWTs without exp"`
+	Leeway        time.Duration `key:"JWT_LEEWAY" file:"leeway" default:"30s" usage:"clock skew allowed when checking exp, nbf and iat"`
+	ReaderScope   string        `key:"JWT_READER_SCOPE" file:"reader_scope" default:"characters:read" usage:"scope granting the reader role, none when empty"`
+	EditorScope   string        `key:"JWT_EDITOR_SCOPE" file:"editor_scope" default:"characters:write" usage:"scope granting the editor role, none when empty"`
+	AdminScope    string        `key:"JWT_ADMIN_SCOPE" file:"admin_scope" default:"characters:admin" usage:"scope granting the admin role, none when empty"`
+}
+
+type Storage struct {
+	Driver     string `key:"STORAGE_DRIVER" file:"driver" default:"postgres" usage:"where characters are kept: postgres, sqlite or memory"`
+	SQLitePath string `key:"SQLITE_PATH" file:"sqlite_path" default:"dragon-ball.db" usage:"SQLite database file, :memory: for one gone on exit"`
+}
+
+type Postgres struct {
+	Host                   string        `key:"PSQL_HOST" file:"host" usage:"host of the primary"`
+	Port                   int           `key:"PSQL_PORT" file:"port" default:"5432" usage:"port of the primary and of the replicas listed without one"`
+	Name                   string        `key:"PSQL_NAME" file:"name" usage:"database name"`
+	User                   string        `key:"PSQL_USER" file:"user" usage:"database user"`
+	Pass                   string        `key:"PSQL_PASS" file:"pass" secret:"true" usage:"database password"`
+	Timeout                time.Duration `key:"PSQL_TIMEOUT" file:"timeout" default:"30s" usage:"bound of every storage call"`
+	MaxConns               int           `key:"PSQL_MAX_CONNS" file:"max_conns" default:"10" usage:"size of each connection pool"`
+	MinConns               int           `key:"PSQL_MIN_CONNS" file:"min_conns" default:"0" usage:"connections each pool keeps open"`
+	MaxConnLifetime        time.Duration `key:"PSQL_MAX_CONN_LIFETIME" file:"max_conn_lifetime" default:"1h" usage:"age after which a connection is replaced"`
+	MaxConnIdleTime        time.Duration `key:"PSQL_MAX_CONN_IDLE_TIME" file:"max_conn_idle_time" default:"30m" usage:"idle time after which a connection is closed"`
+	StatementCacheCapacity int           `key:"PSQL_STATEMENT_CACHE_CAPACITY" file:"statement_cache_capacity" default:"512" usage:"prepared statements cached per connection, 0 behind PgBouncer"`
+	ReplicaHosts           []string      `key:"PSQL_REPLICA_HOSTS" file:"replica_hosts" usage:"comma separated host or host:port of the read replicas"`
+	ReadStickiness         time.Duration `key:"PSQL_READ_STICKINESS" file:"read_stickiness" default:"5s" usage:"how long a writer reads from the primary"`
+	ReplicaMaxLag          time.Duration `key:"PSQL_REPLICA_MAX_LAG" file:"replica_max_lag" default:"10s" usage:"lag past which a replica is skipped"`
+	ReplicaCheckInterval   time.Duration `key:"PSQL_REPLICA_CHECK_INTERVAL" file:"replica_check_interval" default:"5s" usage:"how often the lag of the replicas is checked"`
+}
+
+type Cache struct {
+	Driver           string        `key:"CACHE_DRIVER" file:"driver" default:"memory" usage:"lookup cache: memory or redis"`
+	Size             int           `key:"CACHE_SIZE" file:"size" default:"1000" usage:"entries of the memory cache"`
+	TTL              time.Duration `key:"CACHE_TTL" file:"ttl" default:"5m" usage:"lifetime of a cached character"`
+	NegativeTTL      time.Duration `key:"CACHE_NEGATIVE_TTL" file:"negative_ttl" default:"10s" usage:"lifetime of a cached miss"`
+	ListenMinBackoff time.Duration `key:"CACHE_LISTEN_MIN_BACKOFF" file:"listen_min_backoff" default:"1s" usage:"first wait before the change listener reconnects"`
+	ListenMaxBackoff time.Duration `key:"CACHE_LISTEN_MAX_BACKOFF" file:"listen_max_backoff" default:"30s" usage:"longest wait before the change listener reconnects"`
+}
+
+type Redis struct {
+	Addr     string `key:"REDIS_ADDR" file:"addr" usage:"host:port of Redis"`
+	Password string `key:"REDIS_PASSWORD" file:"password" secret:"true" usage:"Redis password"`
+	DB       int    `key:"REDIS_DB" file:"db" default:"0" usage:"Redis database"`
+}
+
+type HTTPCache struct {
+	CharacterCacheControl string   `key:"CACHE_CONTROL_CHARACTER" file:"character_cache_control" default:"no-cache" usage:"Cache-Control of GET /api/v1/characters/{id}"`
+	CharacterVary         []string `key:"VARY_CHARACTER" file:"character_vary" default:"Accept,Accept-Encoding" usage:"Vary of GET /api/v1/characters/{id}"`
+	SearchCacheControl    string   `key:"CACHE_CONTROL_SEARCH" file:"search_cache_control" default:"public, max-age=30" usage:"Cache-Control of GET /api/characters/search"`
+	SearchVary            []string `key:"VARY_SEARCH" file:"search_vary" default:"Accept,Accept-Encoding" usage:"Vary of GET /api/characters/search"`
+}
+
+type Characters struct {
+	DeleteIdempotent bool `key:"DELETE_IDEMPOTENT" file:"delete_idempotent" default:"false" usage:"answer 204 to deletes of missing characters"`
+}
+
+type Import struct {
+	MaxBytes             int `key:"IMPORT_MAX_BYTES" file:"max_bytes" default:"33554432" usage:"largest archive body POST /api/v1/admin/import reads"`
+	MaxDecompressedBytes int `key:"IMPORT_MAX_DECOMPRESSED_BYTES" file:"max_decompressed_bytes" default:"268435456" usage:"largest an archive may be once decompressed"`
+}
+
+type Trash struct {
+	Retention     time.Duration `key:"TRASH_RETENTION" file:"retention" default:"720h" usage:"how long deleted characters are kept"`
+	PurgeInterval time.Duration `key:"TRASH_PURGE_INTERVAL" file:"purge_interval" default:"1h" usage:"how often the trash is purged"`
+}
+
+type Sync struct {
+	Interval time.Duration `key:"SYNC_INTERVAL" file:"interval" default:"0" usage:"how often characters are synced from the external API, 0 to never"`
+}
+
+// setting is one field of Config.
+type setting struct {
+	key    string
+	path   string
+	usage  string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+func (c *Config) settings() []setting {
+	var settings []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			path := prefix + field.Tag.Get("file")
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+".")
+				continue
+			}
+			settings = append(settings, setting{
+				key:    field.Tag.Get("key"),
+				path:   path,
+				usage:  field.Tag.Get("usage"),
+				def:    field.Tag.Get("default"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+
+	return settings
+}
+
+func (s setting) set(value string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(value)
+	case int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		s.value.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		s.value.SetFloat(f)
+	case bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		s.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return errors.New("must be a duration such as 30s or 1h")
+		}
+		s.value.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	}
+
+	return nil
+}
+
+func (s setting) String() string {
+	switch v := s.value.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	oneOf := func(key string, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s: must be one of %s", key, strings.Join(allowed, ", ")))
+	}
+	required := func(key string, value string, because string) {
+		if value == "" {
+			problems = append(problems, key+": is required "+because)
+		}
+	}
+	atLeast := func(key string, value int, min int) {
+		if value < min {
+			problems = append(problems, fmt.Sprintf("%s: must be at least %d", key, min))
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			problems = append(problems, key+": must be greater than 0")
+		}
+	}
+	notNegative := func(key string, value time.Duration) {
+		if value < 0 {
+			problems = append(problems, key+": must not be negative")
+		}
+	}
+
+	oneOf("SCOPE", c.Scope, "local", "test", "qa", "prod")
+	oneOf("LOG_LEVEL", c.Log.Level, "debug", "info", "warn", "error")
+	oneOf("LOG_FORMAT", c.Log.Format, "json", "text")
+	oneOf("OTEL_TRACES_EXPORTER", c.Tracing.Exporter, "otlp", "stdout", "none")
+	required("OTEL_SERVICE_NAME", c.Tracing.ServiceName, "to name the spans")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "OTEL_TRACES_SAMPLER_ARG: must be between 0 and 1")
+	}
+	if c.Web.Port < 1 || c.Web.Port > 65535 {
+		problems = append(problems, "WEB_PORT: must be between 1 and 65535")
+	}
+	positive("WEB_READ_HEADER_TIMEOUT", c.Web.ReadHeaderTimeout)
+	notNegative("WEB_READ_TIMEOUT", c.Web.ReadTimeout)
+	notNegative("WEB_WRITE_TIMEOUT", c.Web.WriteTimeout)
+	notNegative("WEB_IDLE_TIMEOUT", c.Web.IdleTimeout)
+	notNegative("WEB_DRAIN_DELAY", c.Web.DrainDelay)
+	positive("WEB_SHUTDOWN_TIMEOUT", c.Web.ShutdownTimeout)
+	notNegative("CORS_MAX_AGE", c.CORS.MaxAge)
+	positive("HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout)
+	notNegative("HEALTH_CACHE_TTL", c.Health.CacheTTL)
+	if c.JWT.JWKSURL != "" && c.JWT.JWKSFile != "" {
+		problems = append(problems, "JWT_JWKS_FILE: must be empty when JWT_JWKS_URL is set")
+	}
+	if c.JWT.JWKSURL != "" && !strings.HasPrefix(c.JWT.JWKSURL, "https://") && !strings.HasPrefix(c.JWT.JWKSURL, "http://") {
+		problems = append(problems, "JWT_JWKS_URL: must be an http or https URL")
+	}
+	if c.Auth.Enabled && (c.JWT.JWKSURL != "" || c.JWT.JWKSFile != "") {
+		required("JWT_AUDIENCE", c.JWT.Audience, "when JWT_JWKS_URL or JWT_JWKS_FILE is set, or tokens issued for other services are accepted")
+	}
+	positive("JWT_JWKS_REFRESH", c.JWT.JWKSRefresh)
+	notNegative("JWT_LEEWAY", c.JWT.Leeway)
+
+	oneOf("STORAGE_DRIVER", c.Storage.Driver, "postgres", "sqlite", "memory")
+	switch c.Storage.Driver {
+	case "postgres":
+		required("PSQL_HOST", c.Postgres.Host, "when STORAGE_DRIVER is postgres")
+		required("PSQL_NAME", c.Postgres.Name, "when STORAGE_DRIVER is postgres")
+		required("PSQL_USER", c.Postgres.User, "when STORAGE_DRIVER is postgres")
+		required("PSQL_PASS", c.Postgres.Pass, "when STORAGE_DRIVER is postgres")
+	case "sqlite":
+		required("SQLITE_PATH", c.Storage.SQLitePath, "when STORAGE_DRIVER is sqlite")
+	}
+	if c.Postgres.Port < 1 || c.Postgres.Port > 65535 {
+		problems = append(problems, "PSQL_PORT: must be between 1 and 65535")
+	}
+	positive("PSQL_TIMEOUT", c.Postgres.Timeout)
+	atLeast("PSQL_MAX_CONNS", c.Postgres.MaxConns, 1)
+	atLeast("PSQL_MIN_CONNS", c.Postgres.MinConns, 0)
+	if c.Postgres.MinConns > c.Postgres.MaxConns {
+		problems = append(problems, "PSQL_MIN_CONNS: must not be greater than PSQL_MAX_CONNS")
+	}
+	notNegative("PSQL_MAX_CONN_LIFETIME", c.Postgres.MaxConnLifetime)
+	notNegative("PSQL_MAX_CONN_IDLE_TIME", c.Postgres.MaxConnIdleTime)
+	atLeast("PSQL_STATEMENT_CACHE_CAPACITY", c.Postgres.StatementCacheCapacity, 0)
+	for _, replica := range c.Postgres.ReplicaHosts {
+		if _, port, found := strings.Cut(replica, ":"); found {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				problems = append(problems, fmt.Sprintf("PSQL_REPLICA_HOSTS: port of %q must be between 1 and 65535", replica))
+			}
+		}
+	}
+	notNegative("PSQL_READ_STICKINESS", c.Postgres.ReadStickiness)
+	notNegative("PSQL_REPLICA_MAX_LAG", c.Postgres.ReplicaMaxLag)
+	positive("PSQL_REPLICA_CHECK_INTERVAL", c.Postgres.ReplicaCheckInterval)
+
+	oneOf("CACHE_DRIVER", c.Cache.Driver, "memory", "redis")
+	if c.Cache.Driver == "redis" {
+		required("REDIS_ADDR", c.Redis.Addr, "when CACHE_DRIVER is redis")
+	}
+	atLeast("CACHE_SIZE", c.Cache.Size, 1)
+	notNegative("CACHE_TTL", c.Cache.TTL)
+	notNegative("CACHE_NEGATIVE_TTL", c.Cache.NegativeTTL)
+	positive("CACHE_LISTEN_MIN_BACKOFF", c.Cache.ListenMinBackoff)
+	if c.Cache.ListenMaxBackoff < c.Cache.ListenMinBackoff {
+		problems = append(problems, "CACHE_LISTEN_MAX_BACKOFF: must not be less than CACHE_LISTEN_MIN_BACKOFF")
+	}
+	atLeast("REDIS_DB", c.Redis.DB, 0)
+
+	atLeast("IMPORT_MAX_BYTES", c.Import.MaxBytes, 1)
+	atLeast("IMPORT_MAX_DECOMPRESSED_BYTES", c.Import.MaxDecompressedBytes, 1)
+
+	positive("TRASH_RETENTION", c.Trash.Retention)
+	positive("TRASH_PURGE_INTERVAL", c.Trash.PurgeInterval)
+	notNegative("SYNC_INTERVAL", c.Sync.Interval)
+
+	return problems
+}
+
+// Print writes every setting as KEY=value with the layer it came from.
+// Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	for _, s := range c.settings() {
+		value := s.String()
+		if s.secret && value != "" {
+			value = "[redacted]"
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s # %s\n", s.key, strconv.Quote(value), c.sources[s.key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
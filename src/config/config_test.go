@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func environ(values map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_Load(t *testing.T) {
+	t.Run("given only a scope, it answers the defaults", func(t *testing.T) {
+		cfg, rest, err := Load(nil, environ(map[string]string{"SCOPE": "test", "STORAGE_DRIVER": "memory"}), t.TempDir())
+		require.NoError(t, err)
+		assert.Empty(t, rest)
+
+		assert.Equal(t, "test", cfg.Scope)
+		assert.Equal(t, 8080, cfg.Web.Port)
+		assert.Equal(t, "memory", cfg.Storage.Driver)
+		assert.Equal(t, 30*time.Second, cfg.Postgres.Timeout)
+		assert.Equal(t, []string{"Accept", "Accept-Encoding"}, cfg.HTTPCache.SearchVary)
+		assert.Equal(t, 720*time.Hour, cfg.Trash.Retention)
+		assert.Equal(t, "none", cfg.Tracing.Exporter)
+		assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+		assert.True(t, cfg.Auth.Enabled)
+		assert.True(t, cfg.Auth.PublicReads)
+		assert.Empty(t, cfg.CORS.AllowOrigins)
+		assert.Equal(t, 10*time.Minute, cfg.CORS.MaxAge)
+		assert.True(t, cfg.JWT.RequireExpiry)
+		assert.Equal(t, "characters:write", cfg.JWT.EditorScope)
+	})
+
+	t.Run("given every layer, it answers the value of the highest one", func(t *testing.T) {
+		dir := t.TempDir()
+		configFile := writeFile(t, dir, "config.yaml", `
+web:
+  port: 9000
+storage:
+  driver: memory
+cache:
+  size: 10
+  ttl: 1m
+  negative_ttl: 1s
+psql:
+  replica_hosts: [replica-1, "replica-2:5433"]
+`)
+		writeFile(t, dir, ".env", "CACHE_SIZE=\"20\"\nCACHE_TTL=\"2m\"\n")
+		writeFile(t, dir, ".env.qa", "CACHE_TTL=\"3m\"\nTRASH_RETENTION=\"24h\"\nSCOPE=\"prod\"\n")
+
+		cfg, rest, err := Load(
+			[]string{"-config", configFile, "-trash-retention", "48h", "sync", "-reset-overrides"},
+			environ(map[string]string{"SCOPE": "qa", "CACHE_NEGATIVE_TTL": "5s", "TRASH_RETENTION": "12h"}),
+			dir,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sync", "-reset-overrides"}, rest)
+
+		assert.Equal(t, "qa", cfg.Scope)
+		assert.Equal(t, 9000, cfg.Web.Port)
+		assert.Equal(t, 20, cfg.Cache.Size)
+		assert.Equal(t, 3*time.Minute, cfg.Cache.TTL)
+		assert.Equal(t, 5*time.Second, cfg.Cache.NegativeTTL)
+		assert.Equal(t, 48*time.Hour, cfg.Trash.Retention)
+		assert.Equal(t, []string{"replica-1", "replica-2:5433"}, cfg.Postgres.ReplicaHosts)
+
+		var out bytes.Buffer
+		require.NoError(t, cfg.Print(&out))
+		assert.Contains(t, out.String(), `WEB_PORT="9000" # file `+configFile)
+		assert.Contains(t, out.String(), `CACHE_SIZE="20" # `+filepath.Join(dir, ".env"))
+		assert.Contains(t, out.String(), `CACHE_TTL="3m0s" # `+filepath.Join(dir, ".env.qa"))
+		assert.Contains(t, out.String(), `CACHE_NEGATIVE_TTL="5s" # env`)
+		assert.Contains(t, out.String(), `TRASH_RETENTION="48h0m0s" # flag`)
+		assert.Contains(t, out.String(), `SYNC_INTERVAL="0s" # default`)
+		assert.Contains(t, out.String(), `OTEL_TRACES_SAMPLER_ARG="1" # default`)
+	})
+
+	t.Run("given a TOML config file, it reads it", func(t *testing.T) {
+		dir := t.TempDir()
+		configFile := writeFile(t, dir, "config.toml", `
+scope = "local"
+
+[storage]
+driver = "sqlite"
+sqlite_path = ":memory:"
+
+[characters]
+delete_idempotent = true
+`)
+
+		cfg, _, err := Load(nil, environ(map[string]string{"CONFIG_FILE": configFile}), dir)
+		require.NoError(t, err)
+		assert.Equal(t, "local", cfg.Scope)
+		assert.Equal(t, ":memory:", cfg.Storage.SQLitePath)
+		assert.True(t, cfg.Characters.DeleteIdempotent)
+	})
+
+	t.Run("given secrets in files, it reads them and redacts them when printed", func(t *testing.T) {
+		dir := t.TempDir()
+		passFile := writeFile(t, dir, "psql_pass", "s3cr3t\n")
+
+		cfg, _, err := Load(nil, environ(map[string]string{
+			"SCOPE":          "prod",
+			"PSQL_HOST":      "db",
+			"PSQL_NAME":      "dragonball",
+			"PSQL_USER":      "dragonball",
+			"PSQL_PASS_FILE": passFile,
+			"REDIS_PASSWORD": "another",
+		}), dir)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", cfg.Postgres.Pass)
+
+		var out bytes.Buffer
+		require.NoError(t, cfg.Print(&out))
+		assert.Contains(t, out.String(), `PSQL_PASS="[redacted]" # env PSQL_PASS_FILE`)
+		assert.Contains(t, out.String(), `REDIS_PASSWORD="[redacted]" # env`)
+		assert.NotContains(t, out.String(), "s3cr3t")
+		assert.NotContains(t, out.String(), "another")
+	})
+
+	t.Run("given invalid settings, it reports every problem at once", func(t *testing.T) {
+		dir := t.TempDir()
+		configFile := writeFile(t, dir, "config.yaml", "web:\n  prot: 80\n")
+
+		_, _, err := Load([]string{"-config", configFile}, environ(map[string]string{
+			"SCOPE":                   "staging",
+			"PSQL_TIMEOUT":            "soon",
+			"PSQL_PASS":               "secret",
+			"PSQL_PASS_FILE":          "/run/secrets/psql_pass",
+			"CACHE_DRIVER":            "redis",
+			"PSQL_REPLICA_HOSTS":      "replica-1, replica-2:port",
+			"OTEL_TRACES_SAMPLER_ARG": "2",
+			"JWT_JWKS_URL":            "auth.example.com/jwks.json",
+			"JWT_JWKS_FILE":           "jwks.json",
+		}), dir)
+
+		assert.ErrorIs(t, err, ErrConfigInvalid)
+		assert.EqualError(t, err, "configuration is invalid: "+
+			"web.prot: is not a setting, in "+configFile+"; "+
+			`PSQL_PASS: is set together with PSQL_PASS_FILE, in env; `+
+			`PSQL_TIMEOUT: must be a duration such as 30s or 1h, got "soon" from env; `+
+			"SCOPE: must be one of local, test, qa, prod; "+
+			"OTEL_TRACES_SAMPLER_ARG: must be between 0 and 1; "+
+			"JWT_JWKS_FILE: must be empty when JWT_JWKS_URL is set; "+
+			"JWT_JWKS_URL: must be an http or https URL; "+
+			"JWT_AUDIENCE: is required when JWT_JWKS_URL or JWT_JWKS_FILE is set, or tokens issued for other services are accepted; "+
+			"PSQL_HOST: is required when STORAGE_DRIVER is postgres; "+
+			"PSQL_NAME: is required when STORAGE_DRIVER is postgres; "+
+			"PSQL_USER: is required when STORAGE_DRIVER is postgres; "+
+			"PSQL_PASS: is required when STORAGE_DRIVER is postgres; "+
+			`PSQL_REPLICA_HOSTS: port of "replica-2:port" must be between 1 and 65535; `+
+			"REDIS_ADDR: is required when CACHE_DRIVER is redis")
+	})
+
+	t.Run("given a JWKS, it requires an audience unless auth is disabled", func(t *testing.T) {
+		_, _, err := Load(nil, environ(map[string]string{"SCOPE": "test", "STORAGE_DRIVER": "memory", "JWT_JWKS_FILE": "jwks.json"}), t.TempDir())
+		assert.ErrorIs(t, err, ErrConfigInvalid)
+
+		cfg, _, err := Load(nil, environ(map[string]string{"SCOPE": "test", "STORAGE_DRIVER": "memory", "JWT_JWKS_FILE": "jwks.json", "JWT_AUDIENCE": "dragon-ball"}), t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, "dragon-ball", cfg.JWT.Audience)
+
+		_, _, err = Load(nil, environ(map[string]string{"SCOPE": "test", "STORAGE_DRIVER": "memory", "JWT_JWKS_FILE": "jwks.json", "AUTH_ENABLED": "false"}), t.TempDir())
+		assert.NoError(t, err)
+	})
+
+	t.Run("given an unknown flag, it answers an error", func(t *testing.T) {
+		_, _, err := Load([]string{"-psql-hots", "db"}, environ(map[string]string{"SCOPE": "test"}), t.TempDir())
+		assert.Error(t, err)
+	})
+}
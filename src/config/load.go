@@ -0,0 +1,238 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix names the variable holding the path of a file to read the value
+// of a key from, PSQL_PASS_FILE for PSQL_PASS, as container secrets are
+// mounted.
+const fileSuffix = "_FILE"
+
+// Load layers the settings and validates them, reporting every problem at
+// once. args are the command line without the program name; the flags come
+// first, one per key (-psql-host for PSQL_HOST) plus -config, the path of a
+// YAML or TOML config file, and what follows them is answered as rest.
+// CONFIG_FILE names the config file too. The .env files are read from envDir:
+// .env, then .env.<scope>, when they exist.
+func Load(
+	args []string,
+	lookupEnv func(key string) (string, bool),
+	envDir string,
+) (cfg *Config, rest []string, err error) {
+	cfg = &Config{sources: map[string]string{}}
+	settings := cfg.settings()
+	var problems []string
+
+	for _, s := range settings {
+		if err := s.set(s.def); err != nil {
+			return nil, nil, fmt.Errorf("default of %s %w", s.key, err)
+		}
+		cfg.sources[s.key] = "default"
+	}
+
+	flags := flag.NewFlagSet("web", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", "", "YAML or TOML config file")
+	flagKeys := map[string]string{}
+	for _, s := range settings {
+		flags.String(flagName(s.key), "", s.usage)
+		flagKeys[flagName(s.key)] = s.key
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			flags.SetOutput(os.Stderr)
+			flags.PrintDefaults()
+		}
+		return nil, nil, err
+	}
+	setFlags := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			setFlags[key] = f.Value.String()
+		}
+	})
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		byPath := map[string]setting{}
+		for _, s := range settings {
+			byPath[s.path] = s
+		}
+		for _, path := range sortedKeys(values) {
+			s, ok := byPath[path]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: is not a setting, in %s", path, *configFile))
+				continue
+			}
+			problems = cfg.apply(problems, s, values[path], "file "+*configFile)
+		}
+	}
+
+	// The scope picks the .env files, so it cannot come from one.
+	var scope setting
+	var unscoped []setting
+	for _, s := range settings {
+		if s.key == "SCOPE" {
+			scope = s
+			continue
+		}
+		unscoped = append(unscoped, s)
+	}
+	problems = cfg.applyLayer(problems, []setting{scope}, lookupEnv, "env")
+	if value, ok := setFlags[scope.key]; ok {
+		problems = cfg.apply(problems, scope, value, "flag")
+	}
+
+	for _, name := range []string{".env", ".env." + cfg.Scope} {
+		path := filepath.Join(envDir, name)
+		values, err := godotenv.Read(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, nil, err
+		}
+		problems = cfg.applyLayer(problems, unscoped, func(key string) (string, bool) {
+			value, ok := values[key]
+			return value, ok
+		}, path)
+	}
+
+	problems = cfg.applyLayer(problems, unscoped, lookupEnv, "env")
+
+	for _, s := range unscoped {
+		if value, ok := setFlags[s.key]; ok {
+			problems = cfg.apply(problems, s, value, "flag")
+		}
+	}
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrConfigInvalid, strings.Join(problems, "; "))
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+// applyLayer applies every key set in a layer, reading KEY_FILE when only it
+// is set. An empty value leaves the key as the layers below set it.
+func (c *Config) applyLayer(
+	problems []string,
+	settings []setting,
+	lookup func(key string) (string, bool),
+	source string,
+) []string {
+	for _, s := range settings {
+		value, ok := lookup(s.key)
+		path, fromFile := lookup(s.key + fileSuffix)
+		if ok && value != "" && fromFile && path != "" {
+			problems = append(problems, fmt.Sprintf("%s: is set together with %s%s, in %s", s.key, s.key, fileSuffix, source))
+			continue
+		}
+		if fromFile && path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s%s: %s", s.key, fileSuffix, err.Error()))
+				continue
+			}
+			problems = c.apply(problems, s, strings.TrimRight(string(b), "\r\n"), source+" "+s.key+fileSuffix)
+			continue
+		}
+		if ok && value != "" {
+			problems = c.apply(problems, s, value, source)
+		}
+	}
+
+	return problems
+}
+
+func (c *Config) apply(
+	problems []string,
+	s setting,
+	value string,
+	source string,
+) []string {
+	if err := s.set(value); err != nil {
+		return append(problems, fmt.Sprintf("%s: %s, got %q from %s", s.key, err.Error(), value, source))
+	}
+	c.sources[s.key] = source
+
+	return problems
+}
+
+// readConfigFile flattens the file into dotted paths, psql.host for the host
+// of the psql table.
+func readConfigFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	document := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &document)
+	case ".toml":
+		err = toml.Unmarshal(b, &document)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	var flatten func(prefix string, node map[string]interface{})
+	flatten = func(prefix string, node map[string]interface{}) {
+		for key, value := range node {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				flatten(prefix+key+".", v)
+			case []interface{}:
+				items := make([]string, 0, len(v))
+				for _, item := range v {
+					items = append(items, fmt.Sprint(item))
+				}
+				values[prefix+key] = strings.Join(items, ",")
+			case nil:
+			default:
+				values[prefix+key] = fmt.Sprint(v)
+			}
+		}
+	}
+	flatten("", document)
+
+	return values, nil
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
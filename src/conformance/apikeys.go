@@ -0,0 +1,87 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewAPIKeyRepositoryFunc returns a repository holding no API key.
+type NewAPIKeyRepositoryFunc func(t *testing.T) domains.APIKeyRepository
+
+// TestAPIKeyRepository checks the contracts of domains.APIKeyRepository
+// against the implementations newRepository returns, one per subtest.
+func TestAPIKeyRepository(t *testing.T, newRepository NewAPIKeyRepositoryFunc) {
+	ctx := context.Background()
+
+	t.Run("create a key and find it by its hash", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, err := repo.CreateAPIKeyInDatabase(ctx, "ci", domains.RoleEditor, domains.HashAPIKey("dbk_ci"))
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.Equal(t, "ci", created.Name)
+		assert.Equal(t, domains.RoleEditor, created.Role)
+		assert.False(t, created.CreatedAt.IsZero())
+		assert.Nil(t, created.RevokedAt)
+
+		found, err := repo.GetAPIKeyInDatabaseByHash(ctx, domains.HashAPIKey("dbk_ci"))
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, domains.RoleEditor, found.Role)
+	})
+
+	t.Run("find an unknown key and fail with invalid", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetAPIKeyInDatabaseByHash(ctx, domains.HashAPIKey("dbk_unknown"))
+		assert.ErrorIs(t, err, domains.ErrAPIKeyIsInvalid)
+	})
+
+	t.Run("create a key with a name already taken and fail with already exists", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.CreateAPIKeyInDatabase(ctx, "ci", domains.RoleEditor, domains.HashAPIKey("dbk_one"))
+		require.NoError(t, err)
+		_, err = repo.CreateAPIKeyInDatabase(ctx, "ci", domains.RoleReader, domains.HashAPIKey("dbk_two"))
+		assert.ErrorIs(t, err, domains.ErrAPIKeyAlreadyExists)
+	})
+
+	t.Run("revoke a key and stop finding it, keeping it listed", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, err := repo.CreateAPIKeyInDatabase(ctx, "ci", domains.RoleAdmin, domains.HashAPIKey("dbk_ci"))
+		require.NoError(t, err)
+		_, err = repo.CreateAPIKeyInDatabase(ctx, "dashboard", domains.RoleReader, domains.HashAPIKey("dbk_dashboard"))
+		require.NoError(t, err)
+
+		revoked, err := repo.RevokeAPIKeyInDatabase(ctx, created.ID)
+		require.NoError(t, err)
+		require.NotNil(t, revoked.RevokedAt)
+
+		again, err := repo.RevokeAPIKeyInDatabase(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked.RevokedAt.Equal(*again.RevokedAt))
+
+		_, err = repo.GetAPIKeyInDatabaseByHash(ctx, domains.HashAPIKey("dbk_ci"))
+		assert.ErrorIs(t, err, domains.ErrAPIKeyIsInvalid)
+
+		keys, err := repo.ListAPIKeysInDatabase(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "ci", keys[0].Name)
+		assert.NotNil(t, keys[0].RevokedAt)
+		assert.Equal(t, "dashboard", keys[1].Name)
+		assert.Nil(t, keys[1].RevokedAt)
+	})
+
+	t.Run("revoke an unknown key and fail with not found", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.RevokeAPIKeyInDatabase(ctx, 42)
+		assert.ErrorIs(t, err, domains.ErrAPIKeyNotFound)
+	})
+}
@@ -0,0 +1,167 @@
+// Package conformance holds the behaviour every storage backend must share,
+// as test suites any implementation can be plugged into.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The characters the fake external API knows, named as the real one names
+// them.
+var (
+	Goku    = domains.Character{ID: 1, Name: "Goku", Ki: "60.000.000", Race: "Saiyan", Image: "https://dragonball-api.com/characters/goku_normal.webp"}
+	Vegeta  = domains.Character{ID: 2, Name: "Vegeta", Ki: "54.000.000", Race: "Saiyan", Image: "https://dragonball-api.com/characters/vegeta_normal.webp"}
+	Piccolo = domains.Character{ID: 3, Name: "Piccolo", Ki: "2.000.000", Race: "Namekian", Image: "https://dragonball-api.com/characters/picolo_normal.webp"}
+)
+
+// NewCharacterRepositoryFunc returns an empty repository that fetches from
+// the external API at externalAPIURL.
+type NewCharacterRepositoryFunc func(t *testing.T, externalAPIURL string) domains.CharacterRepository
+
+// NewExternalAPI serves Goku, Vegeta and Piccolo the way the external API
+// does, matching names regardless of case.
+func NewExternalAPI(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		characters := []domains.Character{}
+		for _, character := range []domains.Character{Goku, Vegeta, Piccolo} {
+			if strings.EqualFold(character.Name, r.URL.Query().Get("name")) {
+				characters = append(characters, character)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(characters)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestCharacterRepository checks the contracts of domains.CharacterRepository
+// against the implementations newRepository returns, one per subtest.
+func TestCharacterRepository(t *testing.T, newRepository NewCharacterRepositoryFunc) {
+	externalAPI := NewExternalAPI(t)
+	ctx := domains.WithAuditor(context.Background(), "conformance", domains.AuditSourceAPI)
+
+	stored := func(character domains.Character, version int) domains.Character {
+		character.Name = strings.ToLower(character.Name)
+		character.Version = version
+		return character
+	}
+	insert := func(t *testing.T, repo domains.CharacterRepository, characters ...domains.Character) {
+		for _, character := range characters {
+			_, err := repo.GetCharacterInExternalAPIByName(ctx, character.Name)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("names are stored lowercased and matched regardless of case", func(t *testing.T) {
+		repo := newRepository(t, externalAPI.URL)
+
+		character, err := repo.GetCharacterInExternalAPIByName(ctx, "GOKU")
+		require.NoError(t, err)
+		assert.Equal(t, stored(Goku, 1), character)
+
+		for _, name := range []string{"goku", "Goku", "GOKU"} {
+			character, err := repo.GetCharacterInDatabaseByName(ctx, name)
+			require.NoError(t, err, name)
+			assert.Equal(t, stored(Goku, 1), character, name)
+		}
+
+		character, err = repo.DeleteCharacterInDatabase(ctx, "gOkU", domains.AnyVersion)
+		require.NoError(t, err)
+		assert.Equal(t, "goku", character.Name)
+	})
+
+	t.Run("missing characters answer not found errors", func(t *testing.T) {
+		repo := newRepository(t, externalAPI.URL)
+
+		_, err := repo.GetCharacterInDatabaseByName(ctx, "krillin")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = repo.GetCharacterInDatabaseByID(ctx, 99)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = repo.DeleteCharacterInDatabase(ctx, "krillin", domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = repo.DeleteCharacterInDatabase(ctx, "krillin", 1)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = repo.GetCharacterInExternalAPIByName(ctx, "krillin")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInExternalAPI)
+	})
+
+	t.Run("a character looked up upstream twice answers the stored one and keeps it", func(t *testing.T) {
+		repo := newRepository(t, externalAPI.URL)
+		insert(t, repo, Goku)
+
+		character, err := repo.GetCharacterInExternalAPIByName(ctx, "goku")
+		require.NoError(t, err)
+		assert.Equal(t, Goku.ID, character.ID)
+		assert.Equal(t, "goku", character.Name)
+		assert.Equal(t, 1, character.Version)
+
+		character, err = repo.GetCharacterInDatabaseByID(ctx, Goku.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, character.Version)
+		assert.Nil(t, character.DeletedAt)
+	})
+
+	t.Run("a delete is soft, bumps the version and compares the expected one", func(t *testing.T) {
+		repo := newRepository(t, externalAPI.URL)
+		insert(t, repo, Goku)
+
+		_, err := repo.DeleteCharacterInDatabase(ctx, "goku", 2)
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+		_, err = repo.GetCharacterInDatabaseByName(ctx, "goku")
+		require.NoError(t, err)
+
+		character, err := repo.DeleteCharacterInDatabase(ctx, "goku", 1)
+		require.NoError(t, err)
+		assert.Equal(t, Goku.ID, character.ID)
+		assert.Equal(t, 2, character.Version)
+		assert.NotNil(t, character.DeletedAt)
+
+		character, err = repo.GetCharacterInDatabaseByName(ctx, "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+		assert.Equal(t, Goku.ID, character.ID)
+		assert.Equal(t, 2, character.Version)
+		assert.NotNil(t, character.DeletedAt)
+		character, err = repo.GetCharacterInDatabaseByID(ctx, Goku.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, character.DeletedAt)
+
+		_, err = repo.DeleteCharacterInDatabase(ctx, "goku", domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = repo.GetCharacterInExternalAPIByName(ctx, "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+	})
+
+	t.Run("a search answers at most limit characters in id order", func(t *testing.T) {
+		repo := newRepository(t, externalAPI.URL)
+		insert(t, repo, Piccolo, Goku, Vegeta)
+		_, err := repo.DeleteCharacterInDatabase(ctx, "vegeta", domains.AnyVersion)
+		require.NoError(t, err)
+
+		ids := func(limit int, includeDeleted bool) []uint {
+			characters, err := repo.SearchCharactersInDatabase(ctx, limit, includeDeleted)
+			require.NoError(t, err)
+
+			ids := []uint{}
+			for _, character := range characters {
+				ids = append(ids, character.ID)
+			}
+			return ids
+		}
+
+		assert.Equal(t, []uint{1, 3}, ids(10, false))
+		assert.Equal(t, []uint{1}, ids(1, false))
+		assert.Equal(t, []uint{1, 2, 3}, ids(3, true))
+		assert.Equal(t, []uint{1, 2}, ids(2, true))
+		assert.Equal(t, []uint{}, ids(0, true))
+	})
+}
@@ -0,0 +1,310 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewCharacterStoreFunc returns an empty store that fetches from the
+// external API at externalAPIURL.
+type NewCharacterStoreFunc func(t *testing.T, externalAPIURL string) domains.CharacterStore
+
+// TestCharacterStore checks the contracts of every repository of a
+// domains.CharacterStore, CharacterRepository included, against the stores
+// newStore returns, one per subtest.
+func TestCharacterStore(t *testing.T, newStore NewCharacterStoreFunc) {
+	t.Run("character repository", func(t *testing.T) {
+		TestCharacterRepository(t, func(t *testing.T, externalAPIURL string) domains.CharacterRepository {
+			return newStore(t, externalAPIURL)
+		})
+	})
+
+	lower := func(character domains.Character) domains.Character {
+		character.Name = strings.ToLower(character.Name)
+		return character
+	}
+	goku, vegeta, piccolo := lower(Goku), lower(Vegeta), lower(Piccolo)
+	externalAPI := NewExternalAPI(t)
+
+	seed := func(t *testing.T, store domains.CharacterStore, characters ...domains.Character) {
+		ctx := domains.WithAuditor(context.Background(), "cli:admin", domains.AuditSourceImport)
+		_, err := store.ImportCharactersInDatabase(ctx, characters, domains.ImportModeUpsert, false)
+		require.NoError(t, err)
+	}
+	ctx := domains.WithAuditor(context.Background(), "ip:127.0.0.1", domains.AuditSourceAPI)
+
+	t.Run("deleted characters are listed, restored and purged", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku, vegeta)
+		_, err := store.DeleteCharacterInDatabase(ctx, "goku", domains.AnyVersion)
+		require.NoError(t, err)
+		_, err = store.DeleteCharacterInDatabase(ctx, "vegeta", domains.AnyVersion)
+		require.NoError(t, err)
+
+		deleted, err := store.ListDeletedCharactersInDatabase(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, deleted, 2)
+		assert.Equal(t, []uint{2, 1}, []uint{deleted[0].ID, deleted[1].ID})
+		deleted, err = store.ListDeletedCharactersInDatabase(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, deleted, 1)
+
+		_, err = store.RestoreCharacterInDatabase(ctx, 1, 1)
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+		character, err := store.RestoreCharacterInDatabase(ctx, 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, character.Version)
+		assert.Nil(t, character.DeletedAt)
+		_, err = store.RestoreCharacterInDatabase(ctx, 1, domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = store.RestoreCharacterInDatabase(ctx, 99, domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+
+		purged, err := store.PurgeDeletedCharactersInDatabase(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, purged)
+		purged, err = store.PurgeDeletedCharactersInDatabase(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, uint(2), purged[0].ID)
+
+		_, err = store.GetCharacterInDatabaseByID(ctx, 2)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = store.GetCharacterInDatabaseByID(ctx, 1)
+		assert.NoError(t, err)
+	})
+
+	t.Run("a patch compares the version and its fields are kept through sync", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku, vegeta)
+		ki := "90.000.000"
+
+		_, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &ki}, 5)
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+
+		character, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &ki}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, ki, character.Ki)
+		assert.Equal(t, 2, character.Version)
+
+		upstream := goku
+		upstream.Race = "Saiyan (raised on Earth)"
+		character, err = store.SyncCharacterInDatabase(ctx, upstream, false)
+		require.NoError(t, err)
+		assert.Equal(t, ki, character.Ki)
+		assert.Equal(t, upstream.Race, character.Race)
+		assert.Equal(t, 3, character.Version)
+
+		character, err = store.SyncCharacterInDatabase(ctx, upstream, false)
+		require.NoError(t, err)
+		assert.Equal(t, 3, character.Version)
+
+		character, err = store.SyncCharacterInDatabase(ctx, upstream, true)
+		require.NoError(t, err)
+		assert.Equal(t, goku.Ki, character.Ki)
+		assert.Equal(t, 4, character.Version)
+
+		_, err = store.PatchCharacterInDatabase(ctx, 99, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = store.DeleteCharacterInDatabase(ctx, "vegeta", domains.AnyVersion)
+		require.NoError(t, err)
+		_, err = store.PatchCharacterInDatabase(ctx, 2, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+		_, err = store.SyncCharacterInDatabase(ctx, vegeta, false)
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+	})
+
+	t.Run("a patch does not rename a character to the name of another live one and its upstream name still finds it", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku, vegeta)
+
+		_, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Name: &vegeta.Name}, domains.AnyVersion)
+		assert.ErrorIs(t, err, domains.ErrCharacterAlreadyExistInDatabase)
+		character, err := store.GetCharacterInDatabaseByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, goku.Name, character.Name)
+		assert.Equal(t, 1, character.Version)
+
+		kakarot := "kakarot"
+		character, err = store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Name: &kakarot}, domains.AnyVersion)
+		require.NoError(t, err)
+		assert.Equal(t, kakarot, character.Name)
+		character, err = store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Name: &kakarot}, domains.AnyVersion)
+		require.NoError(t, err)
+		assert.Equal(t, 2, character.Version)
+
+		character, err = store.GetCharacterInExternalAPIByName(ctx, goku.Name)
+		require.NoError(t, err)
+		assert.Equal(t, goku.ID, character.ID)
+		assert.Equal(t, kakarot, character.Name)
+		assert.Equal(t, 2, character.Version)
+	})
+
+	t.Run("a sync run is applied at once and leaves out deleted characters", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku, vegeta, piccolo)
+		ki := "90.000.000"
+		_, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+		require.NoError(t, err)
+		_, err = store.DeleteCharacterInDatabase(ctx, "piccolo", domains.AnyVersion)
+		require.NoError(t, err)
+
+		upstreamGoku := goku
+		upstreamGoku.Race = "Saiyan (raised on Earth)"
+		synced, err := store.SyncCharactersInDatabase(ctx, []domains.Character{piccolo, upstreamGoku, vegeta}, false)
+		require.NoError(t, err)
+		require.Len(t, synced, 2)
+		assert.Equal(t, uint(1), synced[0].ID)
+		assert.Equal(t, ki, synced[0].Ki)
+		assert.Equal(t, upstreamGoku.Race, synced[0].Race)
+		assert.Equal(t, 3, synced[0].Version)
+		assert.Equal(t, uint(2), synced[1].ID)
+		assert.Equal(t, 1, synced[1].Version)
+
+		history, err := store.ListCharacterHistoryInDatabase(ctx, 1, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Equal(t, domains.AuditActionUpdate, history[0].Action)
+		history, err = store.ListCharacterHistoryInDatabase(ctx, 2, 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+
+		third, err := store.GetCharacterVersionInDatabase(ctx, 1, 3)
+		require.NoError(t, err)
+		assert.Equal(t, upstreamGoku.Race, third.Race)
+		assert.Nil(t, third.ValidTo)
+		second, err := store.GetCharacterVersionInDatabase(ctx, 1, 2)
+		require.NoError(t, err)
+		assert.NotNil(t, second.ValidTo)
+
+		synced, err = store.SyncCharactersInDatabase(ctx, []domains.Character{upstreamGoku}, true)
+		require.NoError(t, err)
+		require.Len(t, synced, 1)
+		assert.Equal(t, goku.Ki, synced[0].Ki)
+		assert.Equal(t, 4, synced[0].Version)
+	})
+
+	t.Run("every write is kept in the history and the versions", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku)
+		ki := "90.000.000"
+		_, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+		require.NoError(t, err)
+
+		history, err := store.ListCharacterHistoryInDatabase(ctx, 1, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, domains.AuditActionUpdate, history[0].Action)
+		assert.Equal(t, "ip:127.0.0.1", history[0].Actor)
+		assert.Equal(t, domains.AuditSourceAPI, history[0].Source)
+		var after domains.Character
+		require.NoError(t, json.Unmarshal(history[0].After, &after))
+		assert.Equal(t, ki, after.Ki)
+		assert.Equal(t, domains.AuditActionInsert, history[1].Action)
+		assert.Equal(t, "cli:admin", history[1].Actor)
+		assert.Nil(t, history[1].Before)
+
+		history, err = store.ListCharacterHistoryInDatabase(ctx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, domains.AuditActionInsert, history[0].Action)
+
+		first, err := store.GetCharacterVersionInDatabase(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, goku.Ki, first.Ki)
+		assert.NotNil(t, first.ValidTo)
+		second, err := store.GetCharacterVersionInDatabase(ctx, 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, ki, second.Ki)
+		assert.Nil(t, second.ValidTo)
+		_, err = store.GetCharacterVersionInDatabase(ctx, 1, 3)
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionNotFound)
+
+		asOf, err := store.GetCharacterVersionInDatabaseAsOf(ctx, 1, first.ValidFrom)
+		require.NoError(t, err)
+		assert.Equal(t, 1, asOf.Version)
+		asOf, err = store.GetCharacterVersionInDatabaseAsOf(ctx, 1, second.ValidFrom)
+		require.NoError(t, err)
+		assert.Equal(t, 2, asOf.Version)
+		_, err = store.GetCharacterVersionInDatabaseAsOf(ctx, 1, first.ValidFrom.Add(-time.Hour))
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionNotFound)
+	})
+
+	t.Run("the version of a character is the version of its last version row", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku)
+
+		character, err := store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &goku.Ki}, domains.AnyVersion)
+		require.NoError(t, err)
+		assert.Equal(t, 1, character.Version)
+		ki := "90.000.000"
+		character, err = store.PatchCharacterInDatabase(ctx, 1, domains.CharacterPatch{Ki: &ki}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, character.Version)
+		version, err := store.GetCharacterVersionInDatabase(ctx, 1, character.Version)
+		require.NoError(t, err)
+		assert.Equal(t, ki, version.Ki)
+		assert.Nil(t, version.ValidTo)
+
+		_, err = store.DeleteCharacterInDatabase(ctx, "goku", domains.AnyVersion)
+		require.NoError(t, err)
+		_, err = store.PurgeDeletedCharactersInDatabase(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		seed(t, store, goku)
+
+		character, err = store.GetCharacterInDatabaseByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 4, character.Version)
+		version, err = store.GetCharacterVersionInDatabase(ctx, 1, 4)
+		require.NoError(t, err)
+		assert.Equal(t, goku.Ki, version.Ki)
+		assert.Nil(t, version.ValidTo)
+	})
+
+	t.Run("an import reports before it applies and the export answers what it applied", func(t *testing.T) {
+		store := newStore(t, externalAPI.URL)
+		seed(t, store, goku, vegeta)
+		stronger := goku
+		stronger.Ki = "90.000.000"
+
+		report, err := store.ImportCharactersInDatabase(ctx, []domains.Character{stronger, piccolo}, domains.ImportModeReplace, true)
+		require.NoError(t, err)
+		assert.Equal(t, domains.ImportReport{
+			Mode:    domains.ImportModeReplace,
+			DryRun:  true,
+			Created: []string{"piccolo"},
+			Updated: []string{"goku"},
+			Deleted: []string{"vegeta"},
+		}, report)
+
+		exported, err := store.ExportCharactersInDatabase(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []domains.Character{goku, vegeta}, exported)
+
+		_, err = store.ImportCharactersInDatabase(ctx, []domains.Character{stronger, piccolo}, domains.ImportModeReplace, false)
+		require.NoError(t, err)
+		exported, err = store.ExportCharactersInDatabase(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []domains.Character{stronger, piccolo}, exported)
+
+		character, err := store.GetCharacterInDatabaseByID(ctx, 2)
+		require.NoError(t, err)
+		assert.NotNil(t, character.DeletedAt)
+		assert.Equal(t, 2, character.Version)
+
+		report, err = store.ImportCharactersInDatabase(ctx, []domains.Character{vegeta}, domains.ImportModeUpsert, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vegeta"}, report.Created)
+		character, err = store.GetCharacterInDatabaseByID(ctx, 2)
+		require.NoError(t, err)
+		assert.Nil(t, character.DeletedAt)
+		assert.Equal(t, 3, character.Version)
+	})
+}
@@ -0,0 +1,107 @@
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAPIKeyIsMissing = errors.New("an API key or a JWT is required, send it as Authorization: Bearer <token> or X-API-Key")
+var ErrAPIKeyIsInvalid = errors.New("API key is invalid or revoked")
+var ErrRoleIsNotAllowed = errors.New("the role of the API key is not allowed to do this")
+var ErrRoleIsInvalid = errors.New("role must be reader, editor or admin")
+var ErrAPIKeyNameIsRequired = errors.New("name is required in json of body")
+var ErrAPIKeyNotFound = errors.New("API key not found")
+var ErrAPIKeyAlreadyExists = errors.New("an API key with this name already exists")
+
+// Role is what an API key may do. Each role may do everything the ones
+// before it may: readers read, editors also change characters and admins
+// also manage the web app and its keys.
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("%w, got %q", ErrRoleIsInvalid, value)
+	}
+
+	return role, nil
+}
+
+// Allows tells whether the role may do what required may.
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// APIKey is a key as stored: only the hash of its secret is kept, the
+// secret itself is shown once, when the key is created.
+type APIKey struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// APIKeyPrefix tells the keys of the web app apart from other secrets, for
+// people and for secret scanners.
+const APIKeyPrefix = "dbk_"
+
+// NewAPIKeySecret answers a random secret for a new API key.
+func NewAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey answers the hash an API key is stored and looked up by. The
+// secrets are random, so a fast hash is enough to keep them out of reach of
+// whoever reads the database.
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type APIKeyRepository interface {
+	CreateAPIKeyInDatabase(
+		ctx context.Context,
+		name string,
+		role Role,
+		hash string,
+	) (APIKey, error)
+	// GetAPIKeyInDatabaseByHash answers ErrAPIKeyIsInvalid for hashes of
+	// unknown and revoked keys alike.
+	GetAPIKeyInDatabaseByHash(
+		ctx context.Context,
+		hash string,
+	) (APIKey, error)
+	ListAPIKeysInDatabase(
+		ctx context.Context,
+	) ([]APIKey, error)
+	RevokeAPIKeyInDatabase(
+		ctx context.Context,
+		id uint,
+	) (APIKey, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=APIKeyRepository
@@ -0,0 +1,54 @@
+package domains
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrArchiveInvalid = errors.New("archive is invalid")
+var ErrArchiveVersionNotSupported = errors.New("archive version not supported")
+var ErrArchiveChecksumMismatch = errors.New("archive checksum mismatch")
+var ErrArchiveTooLarge = errors.New("archive is too large")
+var ErrImportModeInvalid = errors.New("import mode must be upsert or replace")
+var ErrDryRunIsInvalid = errors.New("dry_run must be a boolean")
+
+type ImportMode string
+
+const (
+	ImportModeUpsert  ImportMode = "upsert"
+	ImportModeReplace ImportMode = "replace"
+)
+
+func ParseImportMode(mode string) (ImportMode, error) {
+	switch ImportMode(mode) {
+	case "", ImportModeUpsert:
+		return ImportModeUpsert, nil
+	case ImportModeReplace:
+		return ImportModeReplace, nil
+	default:
+		return "", ErrImportModeInvalid
+	}
+}
+
+type ImportReport struct {
+	Mode      ImportMode `json:"mode"`
+	DryRun    bool       `json:"dry_run"`
+	Created   []string   `json:"created"`
+	Updated   []string   `json:"updated"`
+	Deleted   []string   `json:"deleted"`
+	Unchanged int        `json:"unchanged"`
+}
+
+type CharacterArchiveRepository interface {
+	ExportCharactersInDatabase(
+		ctx context.Context,
+	) ([]Character, error)
+	ImportCharactersInDatabase(
+		ctx context.Context,
+		characters []Character,
+		mode ImportMode,
+		dryRun bool,
+	) (ImportReport, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterArchiveRepository
@@ -0,0 +1,82 @@
+package domains
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrOffsetIsInvalid = errors.New("offset must be a positive number")
+
+type AuditAction string
+
+const (
+	AuditActionInsert  AuditAction = "insert"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+	AuditActionPurge   AuditAction = "purge"
+)
+
+type AuditSource string
+
+const (
+	AuditSourceAPI    AuditSource = "api"
+	AuditSourceSync   AuditSource = "sync"
+	AuditSourceImport AuditSource = "import"
+	AuditSourcePurge  AuditSource = "purge"
+)
+
+type AuditEntry struct {
+	ID          uint64          `json:"id"`
+	CharacterID uint            `json:"character_id"`
+	Action      AuditAction     `json:"action"`
+	Actor       string          `json:"actor"`
+	Source      AuditSource     `json:"source"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type Auditor struct {
+	Actor  string
+	Source AuditSource
+}
+
+type auditorKey struct{}
+
+func WithAuditor(
+	ctx context.Context,
+	actor string,
+	source AuditSource,
+) context.Context {
+	return context.WithValue(ctx, auditorKey{}, Auditor{
+		Actor:  actor,
+		Source: source,
+	})
+}
+
+// AuditorFromContext returns who is writing and through which path. Writes
+// that reach the repository without one are recorded as an unknown actor.
+func AuditorFromContext(ctx context.Context) Auditor {
+	if auditor, ok := ctx.Value(auditorKey{}).(Auditor); ok {
+		return auditor
+	}
+
+	return Auditor{
+		Actor:  "unknown",
+		Source: AuditSourceAPI,
+	}
+}
+
+type CharacterAuditRepository interface {
+	ListCharacterHistoryInDatabase(
+		ctx context.Context,
+		characterID uint,
+		limit int,
+		offset int,
+	) ([]AuditEntry, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterAuditRepository
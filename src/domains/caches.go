@@ -0,0 +1,39 @@
+package domains
+
+import (
+	"context"
+	"time"
+)
+
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+type CacheStatsReporter interface {
+	Stats() CacheStats
+}
+
+// Cache stores opaque values by key. A missing or expired key is a miss,
+// not an error; errors are for a cache that cannot be reached.
+type Cache interface {
+	Get(
+		ctx context.Context,
+		key string,
+	) ([]byte, bool, error)
+	Set(
+		ctx context.Context,
+		key string,
+		value []byte,
+		ttl time.Duration,
+	) error
+	Delete(
+		ctx context.Context,
+		keys ...string,
+	) error
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CacheStatsReporter
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=Cache
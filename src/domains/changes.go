@@ -0,0 +1,68 @@
+package domains
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// CharacterChangesChannel is the Postgres channel every committed write of a
+// character is announced on, so each replica can evict what it cached.
+const CharacterChangesChannel = "character_changes"
+
+// CharacterChangePayloadVersion is bumped whenever CharacterChange changes
+// shape. Listeners skip payloads of a version they do not know.
+const CharacterChangePayloadVersion = 1
+
+var ErrCharacterChangePayloadVersion = errors.New("character change payload version is not supported")
+
+type CharacterChange struct {
+	V      int         `json:"v"`
+	ID     uint        `json:"id"`
+	Action AuditAction `json:"action"`
+	Names  []string    `json:"names"`
+}
+
+// NewCharacterChange announces a write of a character, naming it before and
+// after the write so a rename evicts both.
+func NewCharacterChange(
+	id uint,
+	action AuditAction,
+	before *Character,
+	after *Character,
+) CharacterChange {
+	change := CharacterChange{
+		V:      CharacterChangePayloadVersion,
+		ID:     id,
+		Action: action,
+		Names:  []string{},
+	}
+	for _, character := range []*Character{before, after} {
+		if character == nil {
+			continue
+		}
+		if len(change.Names) == 0 || change.Names[0] != character.Name {
+			change.Names = append(change.Names, character.Name)
+		}
+	}
+
+	return change
+}
+
+func ParseCharacterChange(payload string) (CharacterChange, error) {
+	var versioned struct {
+		V int `json:"v"`
+	}
+	if err := json.Unmarshal([]byte(payload), &versioned); err != nil {
+		return CharacterChange{}, err
+	}
+	if versioned.V != CharacterChangePayloadVersion {
+		return CharacterChange{}, ErrCharacterChangePayloadVersion
+	}
+
+	var change CharacterChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return CharacterChange{}, err
+	}
+
+	return change, nil
+}
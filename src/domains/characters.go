@@ -3,6 +3,7 @@ package domains
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var ErrNameIsRequired = errors.New("name is required in json of body")
@@ -10,14 +11,27 @@ var ErrCharacterNotFoundInExternalAPI = errors.New("character not found in exter
 var ErrCharacterNotFoundInDatabase = errors.New("character not found in database")
 var ErrCharacterAlreadyExistInDatabase = errors.New("character already exist in database")
 var ErrCharacterNotSave = errors.New("character not save in local database")
-var ErrCharacterNotDeleted = errors.New("character not deleted in local database")
+var ErrCharacterIsDeleted = errors.New("character is deleted, restore it instead")
+var ErrLimitIsInvalid = errors.New("limit must be a non-negative number")
+var ErrIDIsInvalid = errors.New("id must be a positive number")
+var ErrIncludeDeletedIsInvalid = errors.New("include_deleted must be a boolean")
+var ErrServiceIsShuttingDown = errors.New("service is shutting down")
+var ErrPreconditionRequired = errors.New("If-Match header is required, send the ETag of the character or *")
+var ErrIfMatchIsInvalid = errors.New("If-Match must be * or a single strong ETag")
+var ErrCharacterVersionMismatch = errors.New("character was changed since the ETag was read")
+
+// AnyVersion is the expected version of a write sent with "If-Match: *".
+const AnyVersion = 0
 
 type Character struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Ki    string `json:"ki"`
-	Race  string `json:"race"`
-	Image string `json:"image"`
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Ki        string     `json:"ki"`
+	Race      string     `json:"race"`
+	Image     string     `json:"image"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   int        `json:"-"`
+	UpdatedAt time.Time  `json:"-"`
 }
 
 type CharacterRepository interface {
@@ -25,18 +39,26 @@ type CharacterRepository interface {
 		ctx context.Context,
 		name string,
 	) (Character, error)
+	// GetCharacterInDatabaseByName answers a deleted character along with
+	// ErrCharacterIsDeleted.
 	GetCharacterInDatabaseByName(
 		ctx context.Context,
 		name string,
 	) (Character, error)
+	GetCharacterInDatabaseByID(
+		ctx context.Context,
+		id uint,
+	) (Character, error)
 	SearchCharactersInDatabase(
 		ctx context.Context,
 		limit int,
+		includeDeleted bool,
 	) ([]Character, error)
 	DeleteCharacterInDatabase(
 		ctx context.Context,
 		name string,
-	) error
+		expectedVersion int,
+	) (Character, error)
 }
 
 //go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterRepository
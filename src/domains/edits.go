@@ -0,0 +1,224 @@
+package domains
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var ErrCharacterPatchInvalid = errors.New("character patch is invalid")
+var ErrContentTypeNotSupported = errors.New("content type not supported, use application/merge-patch+json")
+var ErrCharacterPatchTooLarge = errors.New("character patch is too large")
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// MaxCharacterPatchBytes bounds the body of a patch, far above what the
+// editable fields of a character need.
+const MaxCharacterPatchBytes = 16 << 10
+
+// CharacterPatch is a JSON Merge Patch (RFC 7396) over the editable fields of
+// a character. A nil field is left untouched.
+type CharacterPatch struct {
+	Name  *string
+	Ki    *string
+	Race  *string
+	Image *string
+}
+
+type fieldRule struct {
+	maxLength int
+	validate  func(value string) error
+}
+
+var characterPatchRules = map[string]fieldRule{
+	"name":  {maxLength: 64},
+	"ki":    {maxLength: 256},
+	"race":  {maxLength: 64},
+	"image": {maxLength: 256, validate: validateImageURL},
+}
+
+func ParseCharacterMergePatch(body []byte) (CharacterPatch, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(body, &document); err != nil || document == nil {
+		return CharacterPatch{}, fmt.Errorf("%w: body must be a JSON object", ErrCharacterPatchInvalid)
+	}
+
+	var patch CharacterPatch
+	var problems []string
+	for _, field := range sortedKeys(document) {
+		rule, ok := characterPatchRules[field]
+		if !ok {
+			problems = append(problems, field+": is not editable")
+			continue
+		}
+
+		var value *string
+		if err := json.Unmarshal(document[field], &value); err != nil {
+			problems = append(problems, field+": must be a string")
+			continue
+		}
+		if value == nil {
+			problems = append(problems, field+": is required and cannot be removed")
+			continue
+		}
+
+		trimmed := strings.TrimSpace(*value)
+		switch {
+		case trimmed == "":
+			problems = append(problems, field+": must not be empty")
+			continue
+		case len(trimmed) > rule.maxLength:
+			problems = append(problems, fmt.Sprintf("%s: must be at most %d characters", field, rule.maxLength))
+			continue
+		}
+		if rule.validate != nil {
+			if err := rule.validate(trimmed); err != nil {
+				problems = append(problems, field+": "+err.Error())
+				continue
+			}
+		}
+
+		switch field {
+		case "name":
+			lowered := strings.ToLower(trimmed)
+			patch.Name = &lowered
+		case "ki":
+			patch.Ki = &trimmed
+		case "race":
+			patch.Race = &trimmed
+		case "image":
+			patch.Image = &trimmed
+		}
+	}
+
+	if len(problems) > 0 {
+		return CharacterPatch{}, fmt.Errorf("%w: %s", ErrCharacterPatchInvalid, strings.Join(problems, "; "))
+	}
+	if len(patch.Fields()) == 0 {
+		return CharacterPatch{}, fmt.Errorf("%w: no field to change", ErrCharacterPatchInvalid)
+	}
+
+	return patch, nil
+}
+
+func (p CharacterPatch) Apply(character Character) Character {
+	if p.Name != nil {
+		character.Name = *p.Name
+	}
+	if p.Ki != nil {
+		character.Ki = *p.Ki
+	}
+	if p.Race != nil {
+		character.Race = *p.Race
+	}
+	if p.Image != nil {
+		character.Image = *p.Image
+	}
+
+	return character
+}
+
+func (p CharacterPatch) Fields() []string {
+	fields := []string{}
+	if p.Name != nil {
+		fields = append(fields, "name")
+	}
+	if p.Ki != nil {
+		fields = append(fields, "ki")
+	}
+	if p.Race != nil {
+		fields = append(fields, "race")
+	}
+	if p.Image != nil {
+		fields = append(fields, "image")
+	}
+
+	return fields
+}
+
+// MergeUpstreamCharacter takes the upstream values of every field that has not
+// been overridden locally.
+func MergeUpstreamCharacter(
+	local Character,
+	upstream Character,
+	overriddenFields []string,
+) Character {
+	overridden := make(map[string]struct{}, len(overriddenFields))
+	for _, field := range overriddenFields {
+		overridden[field] = struct{}{}
+	}
+
+	merged := local
+	if _, ok := overridden["name"]; !ok {
+		merged.Name = strings.ToLower(upstream.Name)
+	}
+	if _, ok := overridden["ki"]; !ok {
+		merged.Ki = upstream.Ki
+	}
+	if _, ok := overridden["race"]; !ok {
+		merged.Race = upstream.Race
+	}
+	if _, ok := overridden["image"]; !ok {
+		merged.Image = upstream.Image
+	}
+
+	return merged
+}
+
+func validateImageURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+
+	return nil
+}
+
+func sortedKeys(document map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(document))
+	for key := range document {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+type CharacterEditRepository interface {
+	PatchCharacterInDatabase(
+		ctx context.Context,
+		id uint,
+		patch CharacterPatch,
+		expectedVersion int,
+	) (Character, error)
+}
+
+type CharacterSyncRepository interface {
+	FetchCharacterInExternalAPIByName(
+		ctx context.Context,
+		name string,
+	) (Character, error)
+	// FetchCharacterInExternalAPIByID looks a stored character up by the id
+	// it was fetched with, which local edits cannot change.
+	FetchCharacterInExternalAPIByID(
+		ctx context.Context,
+		id uint,
+	) (Character, error)
+	SyncCharacterInDatabase(
+		ctx context.Context,
+		upstream Character,
+		resetOverrides bool,
+	) (Character, error)
+	SyncCharactersInDatabase(
+		ctx context.Context,
+		upstreams []Character,
+		resetOverrides bool,
+	) ([]Character, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterEditRepository
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterSyncRepository
@@ -0,0 +1,44 @@
+package domains
+
+import (
+	"context"
+)
+
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDown     = "down"
+)
+
+// HealthReport is the state of every dependency of the web app. It is down
+// when a critical check fails, degraded when only the others do.
+type HealthReport struct {
+	Status    string              `json:"status"`
+	CheckedAt string              `json:"checked_at"`
+	Checks    []HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) HealthReport
+}
+
+// ExternalAPIPinger tells whether the external API can be reached.
+type ExternalAPIPinger interface {
+	PingExternalAPI(ctx context.Context) error
+}
+
+// SchemaVersioner answers the version of the schema the storage was
+// migrated to.
+type SchemaVersioner interface {
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=HealthChecker
@@ -0,0 +1,53 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+var ErrLogLevelIsInvalid = errors.New("level must be one of debug, info, warn or error")
+
+type loggerKey struct{}
+
+func WithLogger(
+	ctx context.Context,
+	logger *slog.Logger,
+) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithLogAttrs adds attributes, such as the character a request is about, to
+// every line logged with the returned context.
+func WithLogAttrs(
+	ctx context.Context,
+	args ...any,
+) context.Context {
+	return WithLogger(ctx, LoggerFromContext(ctx).With(args...))
+}
+
+// LoggerFromContext returns the logger of the request or job, or the default
+// one when there is none.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// ParseLogLevel reads debug, info, warn or error.
+func ParseLogLevel(value string) (slog.Level, error) {
+	switch value {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, ErrLogLevelIsInvalid
+	}
+}
@@ -14,22 +14,60 @@ type CharacterRepository struct {
 	mock.Mock
 }
 
-// DeleteCharacterInDatabase provides a mock function with given fields: ctx, name
-func (_m *CharacterRepository) DeleteCharacterInDatabase(ctx context.Context, name string) error {
-	ret := _m.Called(ctx, name)
+// DeleteCharacterInDatabase provides a mock function with given fields: ctx, name, expectedVersion
+func (_m *CharacterRepository) DeleteCharacterInDatabase(ctx context.Context, name string, expectedVersion int) (domains.Character, error) {
+	ret := _m.Called(ctx, name, expectedVersion)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteCharacterInDatabase")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, name)
+	var r0 domains.Character
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (domains.Character, error)); ok {
+		return rf(ctx, name, expectedVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) domains.Character); ok {
+		r0 = rf(ctx, name, expectedVersion)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(domains.Character)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, name, expectedVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCharacterInDatabaseByID provides a mock function with given fields: ctx, id
+func (_m *CharacterRepository) GetCharacterInDatabaseByID(ctx context.Context, id uint) (domains.Character, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCharacterInDatabaseByID")
+	}
+
+	var r0 domains.Character
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint) (domains.Character, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint) domains.Character); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(domains.Character)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetCharacterInDatabaseByName provides a mock function with given fields: ctx, name
@@ -88,9 +126,9 @@ func (_m *CharacterRepository) GetCharacterInExternalAPIByName(ctx context.Conte
 	return r0, r1
 }
 
-// SearchCharactersInDatabase provides a mock function with given fields: ctx, limit
-func (_m *CharacterRepository) SearchCharactersInDatabase(ctx context.Context, limit int) ([]domains.Character, error) {
-	ret := _m.Called(ctx, limit)
+// SearchCharactersInDatabase provides a mock function with given fields: ctx, limit, includeDeleted
+func (_m *CharacterRepository) SearchCharactersInDatabase(ctx context.Context, limit int, includeDeleted bool) ([]domains.Character, error) {
+	ret := _m.Called(ctx, limit, includeDeleted)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SearchCharactersInDatabase")
@@ -98,19 +136,19 @@ func (_m *CharacterRepository) SearchCharactersInDatabase(ctx context.Context, l
 
 	var r0 []domains.Character
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) ([]domains.Character, error)); ok {
-		return rf(ctx, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) ([]domains.Character, error)); ok {
+		return rf(ctx, limit, includeDeleted)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) []domains.Character); ok {
-		r0 = rf(ctx, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) []domains.Character); ok {
+		r0 = rf(ctx, limit, includeDeleted)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]domains.Character)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
-		r1 = rf(ctx, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, limit, includeDeleted)
 	} else {
 		r1 = ret.Error(1)
 	}
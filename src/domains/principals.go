@@ -0,0 +1,57 @@
+package domains
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrTokenIsInvalid = errors.New("bearer token is invalid or expired")
+
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// Principal is who a request authenticated as, with an API key or a JWT. A
+// JWT without any scope mapped to a role has an empty Role, which allows
+// nothing.
+type Principal struct {
+	Name   string
+	Role   Role
+	Method string
+}
+
+// Actor is how the writes of the principal are audited.
+func (p Principal) Actor() string {
+	if p.Method == AuthMethodJWT {
+		return "jwt:" + p.Name
+	}
+
+	return "key:" + p.Name
+}
+
+// TokenVerifier checks the JWTs sent as Authorization: Bearer <token>. It
+// answers ErrTokenIsInvalid for every token it does not accept.
+type TokenVerifier interface {
+	VerifyToken(
+		ctx context.Context,
+		token string,
+	) (Principal, error)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(
+	ctx context.Context,
+	principal Principal,
+) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext answers who the request authenticated as, if anyone.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=TokenVerifier
@@ -0,0 +1,23 @@
+package domains
+
+import (
+	"context"
+)
+
+// CharacterStore is a storage backend: every character repository, all of
+// them reading and writing the same characters.
+type CharacterStore interface {
+	CharacterRepository
+	CharacterTrashRepository
+	CharacterAuditRepository
+	CharacterVersionRepository
+	CharacterArchiveRepository
+	CharacterEditRepository
+	CharacterSyncRepository
+	Pinger
+}
+
+// Pinger tells whether the storage can be reached.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
@@ -0,0 +1,24 @@
+package domains
+
+import (
+	"context"
+	"time"
+)
+
+type CharacterTrashRepository interface {
+	ListDeletedCharactersInDatabase(
+		ctx context.Context,
+		limit int,
+	) ([]Character, error)
+	RestoreCharacterInDatabase(
+		ctx context.Context,
+		id uint,
+		expectedVersion int,
+	) (Character, error)
+	PurgeDeletedCharactersInDatabase(
+		ctx context.Context,
+		deletedBefore time.Time,
+	) ([]Character, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterTrashRepository
@@ -0,0 +1,89 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrCharacterVersionNotFound = errors.New("character version not found")
+var ErrAsOfIsInvalid = errors.New("as_of must be an RFC 3339 timestamp")
+var ErrVersionIsInvalid = errors.New("from and to must be positive version numbers")
+
+type CharacterVersion struct {
+	Character
+	Version   int        `json:"version"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type CharacterDiff struct {
+	CharacterID uint          `json:"character_id"`
+	FromVersion int           `json:"from_version"`
+	ToVersion   int           `json:"to_version"`
+	Changes     []FieldChange `json:"changes"`
+}
+
+func DiffCharacterVersions(from, to CharacterVersion) CharacterDiff {
+	diff := CharacterDiff{
+		CharacterID: to.ID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []FieldChange{},
+	}
+
+	addChange := func(field string, fromValue, toValue interface{}) {
+		diff.Changes = append(diff.Changes, FieldChange{
+			Field: field,
+			From:  fromValue,
+			To:    toValue,
+		})
+	}
+
+	if from.Name != to.Name {
+		addChange("name", from.Name, to.Name)
+	}
+	if from.Ki != to.Ki {
+		addChange("ki", from.Ki, to.Ki)
+	}
+	if from.Race != to.Race {
+		addChange("race", from.Race, to.Race)
+	}
+	if from.Image != to.Image {
+		addChange("image", from.Image, to.Image)
+	}
+	if !equalTime(from.DeletedAt, to.DeletedAt) {
+		addChange("deleted_at", from.DeletedAt, to.DeletedAt)
+	}
+
+	return diff
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+type CharacterVersionRepository interface {
+	GetCharacterVersionInDatabaseAsOf(
+		ctx context.Context,
+		id uint,
+		asOf time.Time,
+	) (CharacterVersion, error)
+	GetCharacterVersionInDatabase(
+		ctx context.Context,
+		id uint,
+		version int,
+	) (CharacterVersion, error)
+}
+
+//go:generate mockery --case=snake --outpkg=mocks --output=./mocks --name=CharacterVersionRepository
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyBody struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// createdAPIKey is the only answer that holds the secret of a key.
+type createdAPIKey struct {
+	domains.APIKey
+	Key string `json:"key"`
+}
+
+func ListAPIKeysHandler(apiKeyRepository domains.APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keys, err := apiKeyRepository.ListAPIKeysInDatabase(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, keys)
+	}
+}
+
+func CreateAPIKeyHandler(apiKeyRepository domains.APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body createAPIKeyBody
+		if err := ctx.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+			_ = ctx.Error(domains.ErrAPIKeyNameIsRequired)
+			return
+		}
+
+		role, err := domains.ParseRole(body.Role)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		secret, err := domains.NewAPIKeySecret()
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		key, err := apiKeyRepository.CreateAPIKeyInDatabase(ctx, strings.TrimSpace(body.Name), role, domains.HashAPIKey(secret))
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+		domains.LoggerFromContext(ctx).Info("API key created", "api_key_id", key.ID, "name", key.Name, "role", key.Role)
+
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusCreated, createdAPIKey{APIKey: key, Key: secret})
+	}
+}
+
+func RevokeAPIKeyHandler(apiKeyRepository domains.APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+		if err != nil || id == 0 {
+			_ = ctx.Error(fmt.Errorf("%w: %q", domains.ErrIDIsInvalid, ctx.Param("id")))
+			return
+		}
+
+		key, err := apiKeyRepository.RevokeAPIKeyInDatabase(ctx, uint(id))
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+		domains.LoggerFromContext(ctx).Info("API key revoked", "api_key_id", key.ID, "name", key.Name)
+
+		ctx.JSON(http.StatusOK, key)
+	}
+}
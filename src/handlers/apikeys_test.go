@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListAPIKeysHandler(t *testing.T) {
+	t.Run("given a valid request, it returns 200 without the secrets", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("ListAPIKeysInDatabase", mock.Anything).Return([]domains.APIKey{
+			{ID: 1, Name: "ci", Role: domains.RoleEditor, CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/admin/api-keys", ListAPIKeysHandler(apiKeyRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/api-keys", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"id":1,"name":"ci","role":"editor","created_at":"2024-05-01T10:00:00Z","revoked_at":null}]`, rec.Body.String())
+	})
+}
+
+func Test_CreateAPIKeyHandler(t *testing.T) {
+	newRouter := func(apiKeyRepository domains.APIKeyRepository) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/api-keys", CreateAPIKeyHandler(apiKeyRepository))
+		return r
+	}
+
+	t.Run("given a valid request, it returns 201 with the secret of the stored hash", func(t *testing.T) {
+		var storedHash string
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("CreateAPIKeyInDatabase", mock.Anything, "ci", domains.RoleEditor, mock.Anything).
+			Run(func(args mock.Arguments) { storedHash = args.String(3) }).
+			Return(domains.APIKey{ID: 1, Name: "ci", Role: domains.RoleEditor}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"name":"ci","role":"editor"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+		var body struct {
+			ID  uint   `json:"id"`
+			Key string `json:"key"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, uint(1), body.ID)
+		assert.True(t, strings.HasPrefix(body.Key, "dbk_"))
+		assert.Equal(t, domains.HashAPIKey(body.Key), storedHash)
+	})
+
+	t.Run("given an unknown role, it returns 400", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"name":"ci","role":"root"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"role_invalid"`)
+	})
+
+	t.Run("given no name, it returns 400", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"role":"reader"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"name_required"`)
+	})
+
+	t.Run("given a name already taken, it returns 409", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("CreateAPIKeyInDatabase", mock.Anything, "ci", domains.RoleReader, mock.Anything).
+			Return(domains.APIKey{}, domains.ErrAPIKeyAlreadyExists)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"name":"ci","role":"reader"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func Test_RevokeAPIKeyHandler(t *testing.T) {
+	newRouter := func(apiKeyRepository domains.APIKeyRepository) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.DELETE("/api/v1/admin/api-keys/:id", RevokeAPIKeyHandler(apiKeyRepository))
+		return r
+	}
+
+	t.Run("given a valid request, it returns 200", func(t *testing.T) {
+		revokedAt := time.Now()
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("RevokeAPIKeyInDatabase", mock.Anything, uint(1)).
+			Return(domains.APIKey{ID: 1, Name: "ci", Role: domains.RoleEditor, RevokedAt: &revokedAt}, nil)
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/v1/admin/api-keys/1", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("given an unknown key, it returns 404", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("RevokeAPIKeyInDatabase", mock.Anything, uint(42)).Return(domains.APIKey{}, domains.ErrAPIKeyNotFound)
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/v1/admin/api-keys/42", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("given an invalid id, it returns 400", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/v1/admin/api-keys/abc", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
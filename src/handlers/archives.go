@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/archives"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func ExportCharactersHandler(characterArchiveRepository domains.CharacterArchiveRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		characters, err := characterArchiveRepository.ExportCharactersInDatabase(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		now := time.Now().UTC()
+		ctx.Header("Content-Type", "application/gzip")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="characters-%s.ndjson.gz"`, now.Format("20060102T150405Z")))
+		ctx.Status(http.StatusOK)
+
+		if err := archives.WriteCharacters(ctx.Writer, characters, now); err != nil {
+			domains.LoggerFromContext(ctx).Error("error when execute archives.WriteCharacters", "err", err)
+		}
+	}
+}
+
+// ImportCharactersHandler reads at most maxBytes of the request body and
+// maxDecompressedBytes of the archive in it.
+func ImportCharactersHandler(
+	characterArchiveRepository domains.CharacterArchiveRepository,
+	maxBytes int64,
+	maxDecompressedBytes int64,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mode, err := domains.ParseImportMode(ctx.Query("mode"))
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		dryRun, err := queryBool(ctx, "dry_run", domains.ErrDryRunIsInvalid)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes)
+		_, characters, err := archives.ReadCharacters(body, maxDecompressedBytes)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = domains.ErrArchiveTooLarge
+		}
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		auditCtx := domains.WithAuditor(ctx, domains.AuditorFromContext(ctx).Actor, domains.AuditSourceImport)
+		report, err := characterArchiveRepository.ImportCharactersInDatabase(auditCtx, characters, mode, dryRun)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, report)
+	}
+}
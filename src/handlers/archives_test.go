@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/archives"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportCharactersHandler(t *testing.T) {
+	characterDomain := domains.Character{
+		ID:    1,
+		Name:  "goku",
+		Ki:    "60.000.000",
+		Race:  "Saiyan",
+		Image: "https://dragonball-api.com/characters/goku_normal.webp",
+	}
+
+	t.Run("given a valid request, it returns 200 with an archive", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{characterDomain}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/admin/export", ExportCharactersHandler(characterArchiveRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/export", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/gzip", res.Header.Get("Content-Type"))
+
+		_, characters, err := archives.ReadCharacters(res.Body, 1<<20)
+		require.NoError(t, err)
+		assert.Equal(t, []domains.Character{characterDomain}, characters)
+	})
+
+	t.Run("given a valid request, it returns 500 when error unexpected", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return(nil, errors.New("any error"))
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/admin/export", ExportCharactersHandler(characterArchiveRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/export", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+}
+
+func Test_ImportCharactersHandler(t *testing.T) {
+	characters := []domains.Character{
+		{
+			ID:    1,
+			Name:  "goku",
+			Ki:    "60.000.000",
+			Race:  "Saiyan",
+			Image: "https://dragonball-api.com/characters/goku_normal.webp",
+		},
+	}
+
+	newArchive := func(t *testing.T) *bytes.Buffer {
+		var buf bytes.Buffer
+		require.NoError(t, archives.WriteCharacters(&buf, characters, time.Now()))
+		return &buf
+	}
+
+	t.Run("given a valid archive, it returns 200 with the report", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ImportCharactersInDatabase", mock.Anything, characters, domains.ImportModeReplace, true).
+			Return(domains.ImportReport{Mode: domains.ImportModeReplace, DryRun: true, Created: []string{"goku"}}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 1<<20, 1<<20))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import?mode=replace&dry_run=true", newArchive(t))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("given an invalid mode, it returns 400", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 1<<20, 1<<20))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import?mode=merge", newArchive(t))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("given an invalid archive, it returns 400", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 1<<20, 1<<20))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import", bytes.NewBufferString("not an archive"))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("given a body larger than the limit, it returns 413", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 10, 1<<20))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import", newArchive(t))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), "archive_too_large")
+	})
+
+	t.Run("given an archive larger than the limit once decompressed, it returns 413", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 1<<20, 10))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import", newArchive(t))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), "archive_too_large")
+	})
+
+	t.Run("given a valid archive, it returns 500 when error unexpected", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ImportCharactersInDatabase", mock.Anything, characters, domains.ImportModeUpsert, false).
+			Return(domains.ImportReport{}, errors.New("any error"))
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/admin/import", ImportCharactersHandler(characterArchiveRepoMock, 1<<20, 1<<20))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/import", newArchive(t))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func GetCharacterHistoryHandler(characterAuditRepository domains.CharacterAuditRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := paramID(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		limit, err := queryLimit(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		offset, err := queryOffset(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		entries, err := characterAuditRepository.ListCharacterHistoryInDatabase(ctx, id, limit, offset)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, entries)
+	}
+}
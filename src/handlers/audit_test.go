@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetCharacterHistoryHandler(t *testing.T) {
+	entry := domains.AuditEntry{
+		ID:          1,
+		CharacterID: 1,
+		Action:      domains.AuditActionInsert,
+		Actor:       "ip:127.0.0.1",
+		Source:      domains.AuditSourceAPI,
+		CreatedAt:   time.Now(),
+	}
+
+	t.Run("given a valid request, it returns 200", func(t *testing.T) {
+		characterAuditRepoMock := mocks.NewCharacterAuditRepository(t)
+		characterAuditRepoMock.On("ListCharacterHistoryInDatabase", mock.Anything, uint(1), 10, 20).Return([]domains.AuditEntry{entry}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id/history", GetCharacterHistoryHandler(characterAuditRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1/history?limit=10&offset=20", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("given a negative offset, it returns 400", func(t *testing.T) {
+		characterAuditRepoMock := mocks.NewCharacterAuditRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id/history", GetCharacterHistoryHandler(characterAuditRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1/history?offset=-1", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
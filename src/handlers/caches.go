@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func CacheStatsHandler(cacheStatsReporter domains.CacheStatsReporter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, cacheStatsReporter.Stats())
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CacheStatsHandler(t *testing.T) {
+	t.Run("given a valid request, it returns 200 with the counters", func(t *testing.T) {
+		stats := domains.CacheStats{Hits: 7, Misses: 3, Evictions: 1, Size: 2}
+		cacheStatsReporterMock := mocks.NewCacheStatsReporter(t)
+		cacheStatsReporterMock.On("Stats").Return(stats)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+
+		r.GET("/api/v1/admin/cache", CacheStatsHandler(cacheStatsReporterMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/cache", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var body domains.CacheStats
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, stats, body)
+	})
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/encilab/dragon-ball/src/domains"
 	"github.com/gin-gonic/gin"
@@ -12,86 +14,206 @@ import (
 func GetCharactersHandler(characterRepository domains.CharacterRepository) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		req := make(map[string]string)
-		if err := ctx.BindJSON(&req); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": domains.ErrNameIsRequired.Error()})
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			_ = ctx.Error(domains.ErrNameIsRequired)
 			return
 		}
 
 		if req["name"] == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": domains.ErrNameIsRequired.Error()})
+			_ = ctx.Error(domains.ErrNameIsRequired)
+			return
+		}
+		withLogAttrs(ctx, "character", req["name"])
+
+		includeDeleted, err := queryBool(ctx, "include_deleted", domains.ErrIncludeDeletedIsInvalid)
+		if err != nil {
+			_ = ctx.Error(err)
 			return
 		}
 
 		character, err := characterRepository.GetCharacterInDatabaseByName(ctx, req["name"])
-		if err == nil {
+		if err == nil || (includeDeleted && errors.Is(err, domains.ErrCharacterIsDeleted)) {
+			setCharacterETag(ctx, character)
 			ctx.JSON(http.StatusOK, character)
 			return
 		}
-		log.Println("error getting character in local database")
+		if errors.Is(err, domains.ErrCharacterIsDeleted) {
+			_ = ctx.Error(err)
+			return
+		}
+		domains.LoggerFromContext(ctx).Debug("character is not in the local database, asking the external API", "err", err)
 
 		character, err = characterRepository.GetCharacterInExternalAPIByName(ctx, req["name"])
 		if err != nil {
-			switch {
-			case err == domains.ErrCharacterNotFoundInExternalAPI:
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			_ = ctx.Error(err)
+			return
+		}
+
+		setCharacterETag(ctx, character)
+		ctx.JSON(http.StatusOK, character)
+	}
+}
+
+// GetCharacterHandler reads a character by id. With as_of it answers the
+// version that was current at that instant instead of the live row. The live
+// row answers 304 to a matching If-None-Match or If-Modified-Since.
+func GetCharacterHandler(
+	characterRepository domains.CharacterRepository,
+	characterVersionRepository domains.CharacterVersionRepository,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := paramID(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		includeDeleted, err := queryBool(ctx, "include_deleted", domains.ErrIncludeDeletedIsInvalid)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		if ctx.Query("as_of") != "" {
+			asOf, err := time.Parse(time.RFC3339, ctx.Query("as_of"))
+			if err != nil {
+				_ = ctx.Error(fmt.Errorf("%w: %v", domains.ErrAsOfIsInvalid, err))
 				return
+			}
 
-			default:
-				log.Println(err)
-				ctx.Status(http.StatusInternalServerError)
+			characterVersion, err := characterVersionRepository.GetCharacterVersionInDatabaseAsOf(ctx, id, asOf)
+			if err != nil {
+				_ = ctx.Error(err)
 				return
 			}
+			if characterVersion.DeletedAt != nil && !includeDeleted {
+				_ = ctx.Error(domains.ErrCharacterIsDeleted)
+				return
+			}
+
+			ctx.JSON(http.StatusOK, characterVersion)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, character)
+		character, err := characterRepository.GetCharacterInDatabaseByID(ctx, id)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+		if character.DeletedAt != nil && !includeDeleted {
+			_ = ctx.Error(domains.ErrCharacterIsDeleted)
+			return
+		}
+
+		writeCharacter(ctx, character)
 	}
 }
 
 func SearchCharactersHandler(characterRepository domains.CharacterRepository) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var limit int
-		if ctx.Query("limit") == "" {
-			limit = 100
-		} else {
-			limitConvert, err := strconv.Atoi(ctx.Query("limit"))
-			if err != nil {
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
-			limit = limitConvert
+		limit, err := queryLimit(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
 		}
 
-		character, err := characterRepository.SearchCharactersInDatabase(ctx, limit)
+		includeDeleted, err := queryBool(ctx, "include_deleted", domains.ErrIncludeDeletedIsInvalid)
 		if err != nil {
-			switch {
-			default:
-				log.Println(err)
-				ctx.Status(http.StatusInternalServerError)
-				return
-			}
+			_ = ctx.Error(err)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, character)
+		characters, err := characterRepository.SearchCharactersInDatabase(ctx, limit, includeDeleted)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		writeCharacters(ctx, characters)
 	}
 }
 
-func DeleteCharacterHandler(characterRepository domains.CharacterRepository) gin.HandlerFunc {
+// DeleteCharacterHandler answers 200 with the removed character, or 204 when
+// the client sends "Prefer: return=minimal". When idempotent is set, deleting
+// a character that does not exist is 204 instead of 404. The request must
+// carry If-Match with the ETag of the character or *.
+func DeleteCharacterHandler(
+	characterRepository domains.CharacterRepository,
+	idempotent bool,
+) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		name := ctx.Param("name")
+		withLogAttrs(ctx, "character", name)
 
-		err := characterRepository.DeleteCharacterInDatabase(ctx, name)
+		expectedVersion, err := ifMatchVersion(ctx)
 		if err != nil {
-			switch {
-			case err == domains.ErrCharacterNotDeleted:
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			default:
-				log.Println(err)
-				ctx.Status(http.StatusInternalServerError)
+			_ = ctx.Error(err)
+			return
+		}
+
+		character, err := characterRepository.DeleteCharacterInDatabase(ctx, name, expectedVersion)
+		if err != nil {
+			if idempotent && errors.Is(err, domains.ErrCharacterNotFoundInDatabase) {
+				ctx.Status(http.StatusNoContent)
 				return
 			}
+
+			_ = ctx.Error(err)
+			return
 		}
 
-		ctx.Status(http.StatusOK)
+		setCharacterETag(ctx, character)
+		if ctx.GetHeader("Prefer") == "return=minimal" {
+			ctx.Status(http.StatusNoContent)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, character)
 	}
 }
+
+func queryLimit(ctx *gin.Context) (int, error) {
+	if ctx.Query("limit") == "" {
+		return 100, nil
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", domains.ErrLimitIsInvalid, err)
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("%w: %d", domains.ErrLimitIsInvalid, limit)
+	}
+
+	return limit, nil
+}
+
+func queryOffset(ctx *gin.Context) (int, error) {
+	if ctx.Query("offset") == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.Atoi(ctx.Query("offset"))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: %q", domains.ErrOffsetIsInvalid, ctx.Query("offset"))
+	}
+
+	return offset, nil
+}
+
+func queryBool(
+	ctx *gin.Context,
+	key string,
+	errInvalid error,
+) (bool, error) {
+	if ctx.Query(key) == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(ctx.Query(key))
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errInvalid, err)
+	}
+
+	return value, nil
+}
@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/encilab/dragon-ball/src/domains"
 	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,6 +35,7 @@ func Test_GetCharactersHandler(t *testing.T) {
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -62,6 +65,7 @@ func Test_GetCharactersHandler(t *testing.T) {
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -85,11 +89,88 @@ func Test_GetCharactersHandler(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 	})
 
+	t.Run("given a deleted character, it returns 410 without calling the external api", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, name).Return(domains.Character{}, domains.ErrCharacterIsDeleted)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/characters", bytes.NewBufferString(`{"name": "goku"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusGone, res.StatusCode)
+	})
+
+	t.Run("given a deleted character and include_deleted, it returns 200 with the deleted character", func(t *testing.T) {
+		deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		deleted := characterDomain
+		deleted.DeletedAt = &deletedAt
+		deleted.Version = 2
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, name).Return(deleted, domains.ErrCharacterIsDeleted)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/characters?include_deleted=true", bytes.NewBufferString(`{"name": "goku"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"2"`, res.Header.Get("ETag"))
+		var character domains.Character
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&character))
+		assert.Equal(t, &deletedAt, character.DeletedAt)
+	})
+
+	t.Run("given an invalid include_deleted, it returns 400", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/characters?include_deleted=maybe", bytes.NewBufferString(`{"name": "goku"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
 	t.Run("given a valid request, it returns 400 when not send json in body data", func(t *testing.T) {
 		characterRepoMock := mocks.NewCharacterRepository(t)
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -111,6 +192,7 @@ func Test_GetCharactersHandler(t *testing.T) {
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -141,6 +223,7 @@ func Test_GetCharactersHandler(t *testing.T) {
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -171,6 +254,7 @@ func Test_GetCharactersHandler(t *testing.T) {
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.POST("/api/characters", GetCharactersHandler(characterRepoMock))
 
@@ -207,10 +291,11 @@ func Test_SearchCharactersHandler(t *testing.T) {
 
 	t.Run("given a valid request, it returns 200", func(t *testing.T) {
 		characterRepoMock := mocks.NewCharacterRepository(t)
-		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit).Return([]domains.Character{characterDomain}, nil)
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit, false).Return([]domains.Character{characterDomain}, nil)
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
 
@@ -227,11 +312,57 @@ func Test_SearchCharactersHandler(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 	})
 
+	t.Run("given a request with include_deleted, it returns 200", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit, true).Return([]domains.Character{characterDomain}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/characters/search?include_deleted=true", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("given a negative limit, it returns 400", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/characters/search?limit=-1", nil)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), "limit_invalid")
+	})
+
 	t.Run("given a valid request, it returns 400 when error limit format", func(t *testing.T) {
 		characterRepoMock := mocks.NewCharacterRepository(t)
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
 
@@ -250,10 +381,11 @@ func Test_SearchCharactersHandler(t *testing.T) {
 
 	t.Run("given a valid request, it returns 500 when error unexpected", func(t *testing.T) {
 		characterRepoMock := mocks.NewCharacterRepository(t)
-		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit).Return([]domains.Character{characterDomain}, errors.New("any error"))
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit, false).Return([]domains.Character{characterDomain}, errors.New("any error"))
 
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
 
@@ -270,23 +402,96 @@ func Test_SearchCharactersHandler(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
 
+	t.Run("given the ETag of the current list, it returns 304", func(t *testing.T) {
+		updated := characterDomain
+		updated.UpdatedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit, false).Return([]domains.Character{updated}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/characters/search", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Last-Modified"))
+		etag := rec.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		req, err = http.NewRequest(http.MethodGet, "/api/characters/search", nil)
+		require.NoError(t, err)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("given If-Modified-Since, it returns 200 as lists have no Last-Modified", func(t *testing.T) {
+		updated := characterDomain
+		updated.UpdatedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, limit, false).Return([]domains.Character{updated}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/characters/search", SearchCharactersHandler(characterRepoMock))
+
+		for since, status := range map[string]int{
+			"Wed, 01 May 2024 09:59:59 GMT": http.StatusOK,
+			"Wed, 01 May 2024 10:00:00 GMT": http.StatusOK,
+		} {
+			req, err := http.NewRequest(http.MethodGet, "/api/characters/search", nil)
+			require.NoError(t, err)
+			req.Header.Set("If-Modified-Since", since)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, status, rec.Code, since)
+		}
+	})
+
 }
 
 func Test_DeleteCharacterHandler(t *testing.T) {
 	name := "goku"
+	characterDomain := domains.Character{
+		ID:    1,
+		Name:  "goku",
+		Ki:    "60.000.000",
+		Race:  "Saiyan",
+		Image: "https://dragonball-api.com/characters/goku_normal.webp",
+	}
 
-	t.Run("given a valid request, it returns 200", func(t *testing.T) {
+	t.Run("given a valid request, it returns 200 with the removed character", func(t *testing.T) {
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		characterRepoMock := mocks.NewCharacterRepository(t)
-		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name).Return(nil)
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, 3).Return(characterDomain, nil)
 
-		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock))
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
 
 		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
 		require.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
 
 		rec := httptest.NewRecorder()
 		r.ServeHTTP(rec, req)
@@ -295,20 +500,50 @@ func Test_DeleteCharacterHandler(t *testing.T) {
 		defer res.Body.Close()
 
 		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var character domains.Character
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&character))
+		assert.Equal(t, characterDomain, character)
+	})
+
+	t.Run("given a request preferring a minimal return, it returns 204", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, domains.AnyVersion).Return(characterDomain, nil)
+
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
+		require.NoError(t, err)
+		req.Header.Set("Prefer", "return=minimal")
+		req.Header.Set("If-Match", "*")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
 	})
 
-	t.Run("given a valid request, it returns 500 error domains.ErrCharacterNotDeleted", func(t *testing.T) {
+	t.Run("given a missing character, it returns 404 error domains.ErrCharacterNotFoundInDatabase", func(t *testing.T) {
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		characterRepoMock := mocks.NewCharacterRepository(t)
-		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name).Return(domains.ErrCharacterNotDeleted)
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, domains.AnyVersion).Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase)
 
-		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock))
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
 
 		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
 		require.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		rec := httptest.NewRecorder()
 		r.ServeHTTP(rec, req)
@@ -316,21 +551,47 @@ func Test_DeleteCharacterHandler(t *testing.T) {
 		res := rec.Result()
 		defer res.Body.Close()
 
-		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, middlewares.ProblemContentType, res.Header.Get("Content-Type"))
+	})
+
+	t.Run("given a missing character and idempotent deletes, it returns 204", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, domains.AnyVersion).Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase)
+
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, true))
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
+		require.NoError(t, err)
+		req.Header.Set("If-Match", "*")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
 	})
 
 	t.Run("given a valid request, it returns 500 error unexpected", func(t *testing.T) {
 		gin.SetMode(gin.TestMode)
 		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 		characterRepoMock := mocks.NewCharacterRepository(t)
-		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name).Return(errors.New("any error"))
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, domains.AnyVersion).Return(domains.Character{}, errors.New("any error"))
 
-		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock))
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, true))
 
 		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
 		require.NoError(t, err)
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		rec := httptest.NewRecorder()
 		r.ServeHTTP(rec, req)
@@ -341,4 +602,70 @@ func Test_DeleteCharacterHandler(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
 
+	t.Run("given a request without If-Match, it returns 428", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionRequired, res.StatusCode)
+	})
+
+	t.Run("given a stale ETag, it returns 412", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, name, 2).Return(domains.Character{}, domains.ErrCharacterVersionMismatch)
+
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
+		require.NoError(t, err)
+		req.Header.Set("If-Match", `"2"`)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
+	t.Run("given a weak ETag, it returns 412 without deleting", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+
+		r.DELETE("/api/characters/delete/:name", DeleteCharacterHandler(characterRepoMock, false))
+
+		req, err := http.NewRequest(http.MethodDelete, "/api/characters/delete/goku", nil)
+		require.NoError(t, err)
+		req.Header.Set("If-Match", `W/"3"`)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
 }
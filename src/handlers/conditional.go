@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+// notModified sets the validators of the representation and tells whether
+// the conditional headers of the request let it be answered with 304.
+// If-None-Match takes precedence over If-Modified-Since.
+func notModified(
+	ctx *gin.Context,
+	etag string,
+	lastModified time.Time,
+) bool {
+	if etag != "" {
+		ctx.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		ctx.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if ifNoneMatch := ctx.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagListMatches(ifNoneMatch, etag)
+	}
+
+	if ifModifiedSince := ctx.GetHeader("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagListMatches uses the weak comparison that RFC 9110 asks for in
+// If-None-Match.
+func etagListMatches(list string, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeCharacter(ctx *gin.Context, character domains.Character) {
+	etag := ""
+	if character.Version > 0 {
+		etag = characterETag(character.Version)
+	}
+
+	if notModified(ctx, etag, character.UpdatedAt) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, character)
+}
+
+// writeCharacters answers a list with an ETag hashed from its body, so it
+// changes with any character in it. Lists carry no Last-Modified: deleting
+// or purging a character changes a list without any of the characters left
+// in it being newer.
+func writeCharacters(ctx *gin.Context, characters []domains.Character) {
+	body, err := json.Marshal(characters)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	if notModified(ctx, `"`+hex.EncodeToString(sum[:16])+`"`, time.Time{}) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
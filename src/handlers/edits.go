@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func PatchCharacterHandler(characterEditRepository domains.CharacterEditRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := paramID(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		expectedVersion, err := ifMatchVersion(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		if err != nil || (mediaType != domains.MergePatchContentType && mediaType != "application/json") {
+			_ = ctx.Error(fmt.Errorf("%w: %q", domains.ErrContentTypeNotSupported, ctx.GetHeader("Content-Type")))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, domains.MaxCharacterPatchBytes))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = domains.ErrCharacterPatchTooLarge
+		}
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		patch, err := domains.ParseCharacterMergePatch(body)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		character, err := characterEditRepository.PatchCharacterInDatabase(ctx, id, patch, expectedVersion)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		setCharacterETag(ctx, character)
+		ctx.JSON(http.StatusOK, character)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PatchCharacterHandler(t *testing.T) {
+	characterDomain := domains.Character{
+		ID:    1,
+		Name:  "goku",
+		Ki:    "90.000.000",
+		Race:  "Saiyan",
+		Image: "https://dragonball-api.com/characters/goku_normal.webp",
+	}
+
+	newRouter := func(characterEditRepoMock *mocks.CharacterEditRepository) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+		r.PATCH("/api/v1/characters/:id", PatchCharacterHandler(characterEditRepoMock))
+
+		return r
+	}
+
+	patch := func(r *gin.Engine, contentType string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/characters/1", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", `"3"`)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		return rec.Result()
+	}
+
+	t.Run("given a valid merge patch, it returns 200", func(t *testing.T) {
+		ki := "90.000.000"
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+		updated := characterDomain
+		updated.Version = 4
+		characterEditRepoMock.On("PatchCharacterInDatabase", mock.Anything, uint(1), domains.CharacterPatch{Ki: &ki}, 3).Return(updated, nil)
+
+		res := patch(newRouter(characterEditRepoMock), domains.MergePatchContentType, `{"ki":" 90.000.000 "}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"4"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("given an invalid field, it returns 422", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+
+		res := patch(newRouter(characterEditRepoMock), domains.MergePatchContentType, `{"image":"goku.webp","name":""}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+		var problem middlewares.Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "character patch is invalid: image: must be an absolute http or https URL; name: must not be empty", problem.Detail)
+	})
+
+	t.Run("given a null or read only field, it returns 422", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+
+		res := patch(newRouter(characterEditRepoMock), domains.MergePatchContentType, `{"id":2,"race":null}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
+	t.Run("given a body larger than the limit, it returns 413", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+
+		res := patch(newRouter(characterEditRepoMock), domains.MergePatchContentType, `{"ki":"`+strings.Repeat("9", domains.MaxCharacterPatchBytes)+`"}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		var problem middlewares.Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "character_patch_too_large", problem.Code)
+	})
+
+	t.Run("given a content type other than merge patch, it returns 415", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+
+		res := patch(newRouter(characterEditRepoMock), "text/plain", `{"ki":"1"}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	})
+
+	t.Run("given a deleted character, it returns 410", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+		characterEditRepoMock.On("PatchCharacterInDatabase", mock.Anything, uint(1), mock.Anything, 3).Return(domains.Character{}, domains.ErrCharacterIsDeleted)
+
+		res := patch(newRouter(characterEditRepoMock), "application/json", `{"race":"Human"}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusGone, res.StatusCode)
+	})
+
+	t.Run("given a stale ETag, it returns 412", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+		characterEditRepoMock.On("PatchCharacterInDatabase", mock.Anything, uint(1), mock.Anything, 3).Return(domains.Character{}, domains.ErrCharacterVersionMismatch)
+
+		res := patch(newRouter(characterEditRepoMock), domains.MergePatchContentType, `{"race":"Human"}`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
+	t.Run("given a request without If-Match, it returns 428", func(t *testing.T) {
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+
+		req, err := http.NewRequest(http.MethodPatch, "/api/v1/characters/1", strings.NewReader(`{"race":"Human"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", domains.MergePatchContentType)
+
+		rec := httptest.NewRecorder()
+		newRouter(characterEditRepoMock).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionRequired, res.StatusCode)
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func characterETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setCharacterETag(ctx *gin.Context, character domains.Character) {
+	if character.Version > 0 {
+		ctx.Header("ETag", characterETag(character.Version))
+	}
+}
+
+// ifMatchVersion reads the version a write expects from If-Match. Weak
+// ETags never match under the strong comparison a write needs.
+func ifMatchVersion(ctx *gin.Context) (int, error) {
+	ifMatch := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	switch {
+	case ifMatch == "":
+		return 0, domains.ErrPreconditionRequired
+	case ifMatch == "*":
+		return domains.AnyVersion, nil
+	case strings.Contains(ifMatch, ","):
+		return 0, domains.ErrIfMatchIsInvalid
+	case strings.HasPrefix(ifMatch, "W/"):
+		return 0, domains.ErrCharacterVersionMismatch
+	}
+
+	if len(ifMatch) < 2 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		return 0, domains.ErrIfMatchIsInvalid
+	}
+
+	version, err := strconv.Atoi(ifMatch[1 : len(ifMatch)-1])
+	if err != nil || version <= 0 {
+		return 0, domains.ErrCharacterVersionMismatch
+	}
+
+	return version, nil
+}
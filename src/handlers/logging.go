@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+type logLevelBody struct {
+	Level string `json:"level"`
+}
+
+func GetLogLevelHandler(level *slog.LevelVar) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, logLevelBody{Level: strings.ToLower(level.Level().String())})
+	}
+}
+
+// SetLogLevelHandler changes the level of every logger of the process until
+// it restarts, which goes back to LOG_LEVEL.
+func SetLogLevelHandler(level *slog.LevelVar) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body logLevelBody
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			_ = ctx.Error(domains.ErrLogLevelIsInvalid)
+			return
+		}
+
+		parsed, err := domains.ParseLogLevel(body.Level)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		if parsed != level.Level() {
+			domains.LoggerFromContext(ctx).Warn("log level changed", "from", level.Level(), "to", parsed)
+			level.Set(parsed)
+		}
+
+		ctx.JSON(http.StatusOK, logLevelBody{Level: body.Level})
+	}
+}
+
+// withLogAttrs adds attributes to the lines the rest of the request logs,
+// the access log included.
+func withLogAttrs(
+	ctx *gin.Context,
+	args ...any,
+) {
+	ctx.Request = ctx.Request.WithContext(domains.WithLogAttrs(ctx.Request.Context(), args...))
+}
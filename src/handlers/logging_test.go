@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LogLevelHandlers(t *testing.T) {
+	logLevel := new(slog.LevelVar)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+	r.GET("/api/v1/admin/log-level", GetLogLevelHandler(logLevel))
+	r.PUT("/api/v1/admin/log-level", SetLogLevelHandler(logLevel))
+
+	t.Run("given a valid request, it returns 200 with the current level", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/admin/log-level", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+	})
+
+	t.Run("given a new level, it returns 200 and changes the level", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+		assert.Equal(t, slog.LevelDebug, logLevel.Level())
+	})
+
+	t.Run("given an unknown level, it returns 400 and keeps the level", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/api/v1/admin/log-level", strings.NewReader(`{"level":"verbose"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"log_level_invalid"`)
+		assert.Equal(t, slog.LevelDebug, logLevel.Level())
+	})
+}
@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"database/sql"
-	"log"
 	"net/http"
+	"sync/atomic"
 
+	"github.com/encilab/dragon-ball/src/domains"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,18 +17,25 @@ func LivezHandler() gin.HandlerFunc {
 	}
 }
 
-func ReadyzHandler(sqlClient *sql.DB) gin.HandlerFunc {
+// ReadyzHandler answers the health report of every dependency, with 503
+// when a critical one is down. It fails once draining is set, so load
+// balancers stop sending requests while the in-flight ones finish.
+func ReadyzHandler(
+	healthChecker domains.HealthChecker,
+	draining *atomic.Bool,
+) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if err := sqlClient.Ping(); err != nil {
-			log.Println("error when execute sqlClient.Ping, err: " + err.Error())
-
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "database is down"})
+		if draining.Load() {
+			_ = ctx.Error(domains.ErrServiceIsShuttingDown)
 			return
 		}
 
-		responseMap := make(map[string]string)
-		responseMap["message"] = "ok"
+		report := healthChecker.CheckHealth(ctx)
+		if report.Status == domains.HealthStatusDown {
+			ctx.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
 
-		ctx.JSON(http.StatusOK, responseMap)
+		ctx.JSON(http.StatusOK, report)
 	}
 }
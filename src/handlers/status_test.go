@@ -3,17 +3,22 @@ package handlers
 import (
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
-	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_LivezHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
+	r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
 
 	r.GET("/api/livez", LivezHandler())
 
@@ -35,30 +40,100 @@ func Test_LivezHandler(t *testing.T) {
 }
 
 func Test_ReadyzHandler(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	require.NotNil(t, db)
-	require.NotNil(t, mock)
-
 	gin.SetMode(gin.TestMode)
-	r := gin.New()
 
-	r.GET("/api/readyz", ReadyzHandler(db))
+	newRouter := func(healthChecker domains.HealthChecker, draining *atomic.Bool) *gin.Engine {
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+		r.GET("/api/readyz", ReadyzHandler(healthChecker, draining))
+		return r
+	}
+
+	t.Run("given every dependency up, it returns 200 and the report", func(t *testing.T) {
+		healthChecker := mocks.NewHealthChecker(t)
+		healthChecker.On("CheckHealth", mock.Anything).Return(domains.HealthReport{
+			Status: domains.HealthStatusUp,
+			Checks: []domains.HealthCheckResult{
+				{Name: "postgres", Status: domains.HealthStatusUp, Critical: true, LatencyMS: 1.5},
+			},
+		})
+		var draining atomic.Bool
 
-	t.Run("given a valid request, it returns 200", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/api/readyz", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(healthChecker, &draining).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.JSONEq(t, `{
+			"status": "up",
+			"checked_at": "",
+			"checks": [{"name": "postgres", "status": "up", "critical": true, "latency_ms": 1.5}]
+		}`, rec.Body.String())
+	})
+
+	t.Run("given a dependency that is not critical down, it returns 200", func(t *testing.T) {
+		healthChecker := mocks.NewHealthChecker(t)
+		healthChecker.On("CheckHealth", mock.Anything).Return(domains.HealthReport{Status: domains.HealthStatusDegraded})
+		var draining atomic.Bool
 
 		req, err := http.NewRequest(http.MethodGet, "/api/readyz", nil)
 		require.NoError(t, err)
-		req.Header.Set("Content-Type", "application/json")
 
 		rec := httptest.NewRecorder()
-		r.ServeHTTP(rec, req)
+		newRouter(healthChecker, &draining).ServeHTTP(rec, req)
 
 		res := rec.Result()
 		defer res.Body.Close()
 
-		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), `"status":"degraded"`)
+	})
+
+	t.Run("given a critical dependency down, it returns 503 and the report", func(t *testing.T) {
+		healthChecker := mocks.NewHealthChecker(t)
+		healthChecker.On("CheckHealth", mock.Anything).Return(domains.HealthReport{
+			Status: domains.HealthStatusDown,
+			Checks: []domains.HealthCheckResult{
+				{Name: "postgres", Status: domains.HealthStatusDown, Critical: true, Error: "failed"},
+			},
+		})
+		var draining atomic.Bool
+
+		req, err := http.NewRequest(http.MethodGet, "/api/readyz", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(healthChecker, &draining).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), `"error":"failed"`)
+	})
+
+	t.Run("given a request while draining, it returns 503", func(t *testing.T) {
+		healthChecker := mocks.NewHealthChecker(t)
+		var draining atomic.Bool
+		draining.Store(true)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/readyz", nil)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newRouter(healthChecker, &draining).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Contains(t, rec.Body.String(), `"code":"shutting_down"`)
 	})
 
 }
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func ListDeletedCharactersHandler(characterTrashRepository domains.CharacterTrashRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := queryLimit(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		characters, err := characterTrashRepository.ListDeletedCharactersInDatabase(ctx, limit)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, characters)
+	}
+}
+
+func RestoreCharacterHandler(characterTrashRepository domains.CharacterTrashRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := paramID(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		expectedVersion, err := ifMatchVersion(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		character, err := characterTrashRepository.RestoreCharacterInDatabase(ctx, id, expectedVersion)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		setCharacterETag(ctx, character)
+		ctx.JSON(http.StatusOK, character)
+	}
+}
+
+func paramID(ctx *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: %q", domains.ErrIDIsInvalid, ctx.Param("id"))
+	}
+	withLogAttrs(ctx, "character_id", id)
+
+	return uint(id), nil
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListDeletedCharactersHandler(t *testing.T) {
+	deletedAt := time.Now()
+	characterDomain := domains.Character{
+		ID:        1,
+		Name:      "goku",
+		Ki:        "60.000.000",
+		Race:      "Saiyan",
+		Image:     "https://dragonball-api.com/characters/goku_normal.webp",
+		DeletedAt: &deletedAt,
+	}
+
+	t.Run("given a valid request, it returns 200", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("ListDeletedCharactersInDatabase", mock.Anything, 10).Return([]domains.Character{characterDomain}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/trash", ListDeletedCharactersHandler(characterTrashRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/trash?limit=10", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("given a valid request, it returns 500 when error unexpected", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("ListDeletedCharactersInDatabase", mock.Anything, 100).Return(nil, errors.New("any error"))
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/trash", ListDeletedCharactersHandler(characterTrashRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/trash", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+}
+
+func Test_RestoreCharacterHandler(t *testing.T) {
+	characterDomain := domains.Character{
+		ID:      1,
+		Name:    "goku",
+		Ki:      "60.000.000",
+		Race:    "Saiyan",
+		Image:   "https://dragonball-api.com/characters/goku_normal.webp",
+		Version: 3,
+	}
+
+	restore := func(characterTrashRepository domains.CharacterTrashRepository, id string, ifMatch string) *http.Response {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.POST("/api/v1/characters/:id/restore", RestoreCharacterHandler(characterTrashRepository))
+
+		req, err := http.NewRequest(http.MethodPost, "/api/v1/characters/"+id+"/restore", nil)
+		require.NoError(t, err)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		return rec.Result()
+	}
+
+	t.Run("given a deleted character, it returns 200 with the new ETag", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("RestoreCharacterInDatabase", mock.Anything, uint(1), 2).Return(characterDomain, nil)
+
+		res := restore(characterTrashRepoMock, "1", `"2"`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"3"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("given an invalid id, it returns 400", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+
+		res := restore(characterTrashRepoMock, "goku", `"2"`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("given a character not in the trash, it returns 404", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("RestoreCharacterInDatabase", mock.Anything, uint(1), domains.AnyVersion).Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase)
+
+		res := restore(characterTrashRepoMock, "1", "*")
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("given a stale ETag, it returns 412", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("RestoreCharacterInDatabase", mock.Anything, uint(1), 1).Return(domains.Character{}, domains.ErrCharacterVersionMismatch)
+
+		res := restore(characterTrashRepoMock, "1", `"1"`)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
+	t.Run("given a request without If-Match, it returns 428", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+
+		res := restore(characterTrashRepoMock, "1", "")
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusPreconditionRequired, res.StatusCode)
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+func DiffCharacterVersionsHandler(characterVersionRepository domains.CharacterVersionRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := paramID(ctx)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		fromVersion, err := queryVersion(ctx, "from")
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+		toVersion, err := queryVersion(ctx, "to")
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		from, err := characterVersionRepository.GetCharacterVersionInDatabase(ctx, id, fromVersion)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+		to, err := characterVersionRepository.GetCharacterVersionInDatabase(ctx, id, toVersion)
+		if err != nil {
+			_ = ctx.Error(err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, domains.DiffCharacterVersions(from, to))
+	}
+}
+
+func queryVersion(
+	ctx *gin.Context,
+	key string,
+) (int, error) {
+	version, err := strconv.Atoi(ctx.Query(key))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: %s=%q", domains.ErrVersionIsInvalid, key, ctx.Query(key))
+	}
+
+	return version, nil
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/encilab/dragon-ball/src/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetCharacterHandler(t *testing.T) {
+	characterDomain := domains.Character{
+		ID:      1,
+		Name:    "goku",
+		Ki:      "60.000.000",
+		Race:    "Saiyan",
+		Image:   "https://dragonball-api.com/characters/goku_normal.webp",
+		Version: 2,
+	}
+	asOf := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("given a valid request, it returns 200 with the live character", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, uint(1)).Return(characterDomain, nil)
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id", GetCharacterHandler(characterRepoMock, characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"2"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("given as_of, it returns 200 with the version current at that time", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+		characterVersionRepoMock.On("GetCharacterVersionInDatabaseAsOf", mock.Anything, uint(1), asOf).
+			Return(domains.CharacterVersion{Character: characterDomain, Version: 3, ValidFrom: asOf.Add(-time.Hour)}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id", GetCharacterHandler(characterRepoMock, characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1?as_of=2024-05-01T00:00:00Z", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var characterVersion domains.CharacterVersion
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&characterVersion))
+		assert.Equal(t, 3, characterVersion.Version)
+		assert.Equal(t, "goku", characterVersion.Name)
+	})
+
+	t.Run("given an invalid as_of, it returns 400", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id", GetCharacterHandler(characterRepoMock, characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1?as_of=yesterday", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("given a deleted character, it returns 410 unless include_deleted", func(t *testing.T) {
+		deletedAt := time.Now()
+		deleted := characterDomain
+		deleted.DeletedAt = &deletedAt
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, uint(1)).Return(deleted, nil)
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id", GetCharacterHandler(characterRepoMock, characterVersionRepoMock))
+
+		for target, status := range map[string]int{
+			"/api/v1/characters/1":                      http.StatusGone,
+			"/api/v1/characters/1?include_deleted=true": http.StatusOK,
+		} {
+			req, err := http.NewRequest(http.MethodGet, target, nil)
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, status, rec.Code, target)
+		}
+	})
+
+	t.Run("given If-None-Match with the current ETag, it returns 304", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, uint(1)).Return(characterDomain, nil)
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id", GetCharacterHandler(characterRepoMock, characterVersionRepoMock))
+
+		for ifNoneMatch, status := range map[string]int{
+			`"2"`:   http.StatusNotModified,
+			`W/"2"`: http.StatusNotModified,
+			`"1"`:   http.StatusOK,
+		} {
+			req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1", nil)
+			require.NoError(t, err)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, status, rec.Code, ifNoneMatch)
+		}
+	})
+}
+
+func Test_DiffCharacterVersionsHandler(t *testing.T) {
+	from := domains.CharacterVersion{
+		Character: domains.Character{ID: 1, Name: "goku", Ki: "60.000.000", Race: "Saiyan", Image: "goku.webp"},
+		Version:   1,
+	}
+	to := domains.CharacterVersion{
+		Character: domains.Character{ID: 1, Name: "goku", Ki: "90.000.000", Race: "Saiyan", Image: "goku.webp"},
+		Version:   2,
+	}
+
+	t.Run("given two versions, it returns 200 with the changed fields", func(t *testing.T) {
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+		characterVersionRepoMock.On("GetCharacterVersionInDatabase", mock.Anything, uint(1), 1).Return(from, nil)
+		characterVersionRepoMock.On("GetCharacterVersionInDatabase", mock.Anything, uint(1), 2).Return(to, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id/diff", DiffCharacterVersionsHandler(characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1/diff?from=1&to=2", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var diff domains.CharacterDiff
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&diff))
+		require.Len(t, diff.Changes, 1)
+		assert.Equal(t, "ki", diff.Changes[0].Field)
+		assert.Equal(t, "60.000.000", diff.Changes[0].From)
+		assert.Equal(t, "90.000.000", diff.Changes[0].To)
+	})
+
+	t.Run("given a missing version number, it returns 400", func(t *testing.T) {
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id/diff", DiffCharacterVersionsHandler(characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1/diff?from=1", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("given a version that does not exist, it returns 404", func(t *testing.T) {
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+		characterVersionRepoMock.On("GetCharacterVersionInDatabase", mock.Anything, uint(1), 1).Return(domains.CharacterVersion{}, domains.ErrCharacterVersionNotFound)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(middlewares.Problems(middlewares.NewDomainProblemRegistry()))
+
+		r.GET("/api/v1/characters/:id/diff", DiffCharacterVersionsHandler(characterVersionRepoMock))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1/diff?from=1&to=9", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
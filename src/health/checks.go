@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// cacheProbeKey is read, never written, by the cache check: a miss proves
+// the cache answers.
+const cacheProbeKey = "health:probe"
+
+// StorageCheck is critical: without its storage the web app answers nothing.
+func StorageCheck(
+	name string,
+	pinger domains.Pinger,
+	timeout time.Duration,
+) Check {
+	return Check{
+		Name:     name,
+		Timeout:  timeout,
+		Critical: true,
+		Run:      pinger.PingContext,
+	}
+}
+
+// SchemaCheck is critical: the statements of a web app newer than its schema
+// fail.
+func SchemaCheck(
+	schemaVersioner domains.SchemaVersioner,
+	want int,
+	timeout time.Duration,
+) Check {
+	return Check{
+		Name:     "schema",
+		Timeout:  timeout,
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			version, err := schemaVersioner.SchemaVersion(ctx)
+			if err != nil {
+				return err
+			}
+			if version < want {
+				return fmt.Errorf("schema version is %d, want at least %d", version, want)
+			}
+			return nil
+		},
+	}
+}
+
+// ExternalAPICheck is not critical: only the lookups missing from the
+// storage need the external API.
+func ExternalAPICheck(
+	externalAPIPinger domains.ExternalAPIPinger,
+	timeout time.Duration,
+) Check {
+	return Check{
+		Name:    "external_api",
+		Timeout: timeout,
+		Run:     externalAPIPinger.PingExternalAPI,
+	}
+}
+
+// CacheCheck is not critical: lookups skip a cache that cannot be reached.
+func CacheCheck(
+	cache domains.Cache,
+	timeout time.Duration,
+) Check {
+	return Check{
+		Name:    "cache",
+		Timeout: timeout,
+		Run: func(ctx context.Context) error {
+			_, _, err := cache.Get(ctx, cacheProbeKey)
+			return err
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type schemaVersionerFunc func(ctx context.Context) (int, error)
+
+func (f schemaVersionerFunc) SchemaVersion(ctx context.Context) (int, error) {
+	return f(ctx)
+}
+
+func Test_SchemaCheck(t *testing.T) {
+	t.Run("given the expected schema version, it passes", func(t *testing.T) {
+		check := SchemaCheck(schemaVersionerFunc(func(ctx context.Context) (int, error) { return 2, nil }), 2, time.Second)
+
+		assert.True(t, check.Critical)
+		assert.NoError(t, check.Run(context.Background()))
+	})
+
+	t.Run("given an older schema version, it fails", func(t *testing.T) {
+		check := SchemaCheck(schemaVersionerFunc(func(ctx context.Context) (int, error) { return 1, nil }), 2, time.Second)
+
+		assert.EqualError(t, check.Run(context.Background()), "schema version is 1, want at least 2")
+	})
+}
+
+func Test_CacheCheck(t *testing.T) {
+	t.Run("given a cache answering a miss, it passes", func(t *testing.T) {
+		cache := mocks.NewCache(t)
+		cache.On("Get", mock.Anything, cacheProbeKey).Return(nil, false, nil)
+
+		check := CacheCheck(cache, time.Second)
+
+		assert.False(t, check.Critical)
+		assert.NoError(t, check.Run(context.Background()))
+	})
+
+	t.Run("given a cache that cannot be reached, it fails", func(t *testing.T) {
+		cache := mocks.NewCache(t)
+		cache.On("Get", mock.Anything, cacheProbeKey).Return(nil, false, errors.New("dial tcp: connection refused"))
+
+		check := CacheCheck(cache, time.Second)
+
+		assert.EqualError(t, check.Run(context.Background()), "dial tcp: connection refused")
+	})
+}
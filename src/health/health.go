@@ -0,0 +1,133 @@
+// Package health checks the dependencies of the web app for /api/readyz.
+// Each dependency registers a Check; the report of all of them is cached for
+// a while so probes do not reach the dependencies on every call.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// The error of a failed check is only logged: /api/readyz needs no key, so
+// its report says why a check failed without the addresses or messages of
+// the dependency.
+const (
+	checkErrorTimeout = "timeout"
+	checkErrorFailed  = "failed"
+)
+
+// Check is one dependency. A failing critical check takes the web app out
+// of rotation, the others only degrade it. Run is bounded by Timeout.
+type Check struct {
+	Name     string
+	Timeout  time.Duration
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+type Registry struct {
+	checks   []Check
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	report    domains.HealthReport
+	checkedAt time.Time
+}
+
+func NewRegistry(cacheTTL time.Duration) *Registry {
+
+	return &Registry{
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+	}
+}
+
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check)
+	r.checkedAt = time.Time{}
+}
+
+// CheckHealth answers the cached report while it is younger than cacheTTL,
+// and runs every check at once otherwise. Concurrent callers wait for the
+// same run. The checks do not stop when ctx is cancelled, so a probe giving
+// up does not leave a failed report behind for the next ones.
+func (r *Registry) CheckHealth(ctx context.Context) domains.HealthReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.checkedAt.IsZero() && r.now().Sub(r.checkedAt) < r.cacheTTL {
+		return r.report
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	results := make([]domains.HealthCheckResult, len(r.checks))
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	r.checkedAt = r.now()
+	r.report = domains.HealthReport{
+		Status:    status(results),
+		CheckedAt: r.checkedAt.UTC().Format(time.RFC3339Nano),
+		Checks:    results,
+	}
+
+	return r.report
+}
+
+func run(
+	ctx context.Context,
+	check Check,
+) domains.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := domains.HealthCheckResult{
+		Name:      check.Name,
+		Status:    domains.HealthStatusUp,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute health check", "check", check.Name, "err", err)
+
+		result.Status = domains.HealthStatusDown
+		result.Error = checkErrorFailed
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = checkErrorTimeout
+		}
+	}
+
+	return result
+}
+
+func status(results []domains.HealthCheckResult) string {
+	status := domains.HealthStatusUp
+	for _, result := range results {
+		if result.Status == domains.HealthStatusUp {
+			continue
+		}
+		if result.Critical {
+			return domains.HealthStatusDown
+		}
+		status = domains.HealthStatusDegraded
+	}
+
+	return status
+}
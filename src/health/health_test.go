@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Registry(t *testing.T) {
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+	passing := func(ctx context.Context) error { return nil }
+
+	t.Run("given passing checks, it reports up", func(t *testing.T) {
+		registry := NewRegistry(0)
+		registry.Register(Check{Name: "postgres", Timeout: time.Second, Critical: true, Run: passing})
+		registry.Register(Check{Name: "cache", Timeout: time.Second, Run: passing})
+
+		report := registry.CheckHealth(context.Background())
+
+		assert.Equal(t, domains.HealthStatusUp, report.Status)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "postgres", report.Checks[0].Name)
+		assert.Equal(t, domains.HealthStatusUp, report.Checks[0].Status)
+		assert.True(t, report.Checks[0].Critical)
+		assert.Equal(t, "cache", report.Checks[1].Name)
+	})
+
+	t.Run("given a failing check that is not critical, it reports degraded", func(t *testing.T) {
+		registry := NewRegistry(0)
+		registry.Register(Check{Name: "postgres", Timeout: time.Second, Critical: true, Run: passing})
+		registry.Register(Check{Name: "external_api", Timeout: time.Second, Run: failing})
+
+		report := registry.CheckHealth(context.Background())
+
+		assert.Equal(t, domains.HealthStatusDegraded, report.Status)
+		assert.Equal(t, domains.HealthStatusDown, report.Checks[1].Status)
+		assert.Equal(t, "failed", report.Checks[1].Error)
+	})
+
+	t.Run("given a failing critical check, it reports down", func(t *testing.T) {
+		registry := NewRegistry(0)
+		registry.Register(Check{Name: "postgres", Timeout: time.Second, Critical: true, Run: failing})
+		registry.Register(Check{Name: "external_api", Timeout: time.Second, Run: failing})
+
+		report := registry.CheckHealth(context.Background())
+
+		assert.Equal(t, domains.HealthStatusDown, report.Status)
+	})
+
+	t.Run("given a check slower than its timeout, it reports it down", func(t *testing.T) {
+		registry := NewRegistry(0)
+		registry.Register(Check{Name: "postgres", Timeout: 10 * time.Millisecond, Critical: true, Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+		report := registry.CheckHealth(context.Background())
+
+		assert.Equal(t, domains.HealthStatusDown, report.Status)
+		assert.Equal(t, "timeout", report.Checks[0].Error)
+		assert.GreaterOrEqual(t, report.Checks[0].LatencyMS, float64(10))
+	})
+
+	t.Run("given probes within the cache ttl, it runs the checks once", func(t *testing.T) {
+		var runs atomic.Int32
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		registry := NewRegistry(5 * time.Second)
+		registry.now = func() time.Time { return now }
+		registry.Register(Check{Name: "postgres", Timeout: time.Second, Critical: true, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}})
+
+		first := registry.CheckHealth(context.Background())
+		now = now.Add(4 * time.Second)
+		second := registry.CheckHealth(context.Background())
+
+		assert.Equal(t, int32(1), runs.Load())
+		assert.Equal(t, first, second)
+		assert.Equal(t, "2024-05-01T10:00:00Z", second.CheckedAt)
+
+		now = now.Add(time.Second)
+		registry.CheckHealth(context.Background())
+
+		assert.Equal(t, int32(2), runs.Load())
+	})
+
+	t.Run("given a cancelled probe, it still runs the checks to the end", func(t *testing.T) {
+		registry := NewRegistry(0)
+		registry.Register(Check{Name: "postgres", Timeout: time.Second, Critical: true, Run: func(ctx context.Context) error {
+			return ctx.Err()
+		}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report := registry.CheckHealth(ctx)
+
+		assert.Equal(t, domains.HealthStatusUp, report.Status)
+	})
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// PurgeDeletedCharactersJob hard-deletes soft-deleted characters once they
+// have been in the trash for longer than the retention window.
+type PurgeDeletedCharactersJob struct {
+	characterTrashRepository domains.CharacterTrashRepository
+	retention                time.Duration
+	interval                 time.Duration
+	now                      func() time.Time
+}
+
+func NewPurgeDeletedCharactersJob(
+	characterTrashRepository domains.CharacterTrashRepository,
+	retention time.Duration,
+	interval time.Duration,
+) *PurgeDeletedCharactersJob {
+
+	return &PurgeDeletedCharactersJob{
+		characterTrashRepository: characterTrashRepository,
+		retention:                retention,
+		interval:                 interval,
+		now:                      time.Now,
+	}
+}
+
+func (j *PurgeDeletedCharactersJob) RunOnce(ctx context.Context) (int64, error) {
+	purged, err := j.characterTrashRepository.PurgeDeletedCharactersInDatabase(ctx, j.now().Add(-j.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(purged)), nil
+}
+
+func (j *PurgeDeletedCharactersJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := j.RunOnce(ctx)
+		if err != nil {
+			domains.LoggerFromContext(ctx).Error("error when execute PurgeDeletedCharactersJob.RunOnce", "err", err)
+		} else if purged > 0 {
+			domains.LoggerFromContext(ctx).Info("purged deleted characters", "purged", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PurgeDeletedCharactersJob(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("given a retention window, it purges characters deleted before it", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("PurgeDeletedCharactersInDatabase", mock.Anything, now.Add(-24*time.Hour)).Return([]domains.Character{{ID: 1, Name: "goku"}, {ID: 2, Name: "vegeta"}}, nil)
+
+		job := NewPurgeDeletedCharactersJob(characterTrashRepoMock, 24*time.Hour, time.Hour)
+		job.now = func() time.Time { return now }
+
+		purged, err := job.RunOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+	})
+
+	t.Run("given a cancelled context, it stops after the first run", func(t *testing.T) {
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("PurgeDeletedCharactersInDatabase", mock.Anything, mock.Anything).Return([]domains.Character{}, nil).Once()
+
+		job := NewPurgeDeletedCharactersJob(characterTrashRepoMock, 24*time.Hour, time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			job.Run(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job did not stop after the context was cancelled")
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+type SyncReport struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+	Missing int `json:"missing"`
+	Failed  int `json:"failed"`
+}
+
+// SyncCharactersJob refreshes every stored character from the external API.
+// Fields edited locally keep their value unless resetOverrides is set.
+type SyncCharactersJob struct {
+	characterArchiveRepository domains.CharacterArchiveRepository
+	characterSyncRepository    domains.CharacterSyncRepository
+	interval                   time.Duration
+}
+
+func NewSyncCharactersJob(
+	characterArchiveRepository domains.CharacterArchiveRepository,
+	characterSyncRepository domains.CharacterSyncRepository,
+	interval time.Duration,
+) *SyncCharactersJob {
+
+	return &SyncCharactersJob{
+		characterArchiveRepository: characterArchiveRepository,
+		characterSyncRepository:    characterSyncRepository,
+		interval:                   interval,
+	}
+}
+
+func (j *SyncCharactersJob) RunOnce(
+	ctx context.Context,
+	resetOverrides bool,
+) (SyncReport, error) {
+	characters, err := j.characterArchiveRepository.ExportCharactersInDatabase(ctx)
+	if err != nil {
+		return SyncReport{}, err
+	}
+
+	var report SyncReport
+	var upstreams []domains.Character
+	for _, character := range characters {
+		report.Checked++
+
+		upstream, err := j.characterSyncRepository.FetchCharacterInExternalAPIByID(ctx, character.ID)
+		if err != nil {
+			if errors.Is(err, domains.ErrCharacterNotFoundInExternalAPI) {
+				report.Missing++
+				continue
+			}
+			domains.LoggerFromContext(ctx).Error("error when sync character", "character_id", character.ID, "character", character.Name, "err", err)
+			report.Failed++
+			continue
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+	if len(upstreams) == 0 {
+		return report, nil
+	}
+
+	synced, err := j.characterSyncRepository.SyncCharactersInDatabase(ctx, upstreams, resetOverrides)
+	if err != nil {
+		report.Failed += len(upstreams)
+		return report, fmt.Errorf("failed to sync %d characters: %w", len(upstreams), err)
+	}
+
+	stored := make(map[uint]domains.Character, len(characters))
+	for _, character := range characters {
+		stored[character.ID] = character
+	}
+	for _, character := range synced {
+		if !sameContent(character, stored[character.ID]) {
+			report.Updated++
+		}
+	}
+	// The others were deleted since the export.
+	report.Missing += len(upstreams) - len(synced)
+
+	return report, nil
+}
+
+// sameContent compares the fields taken from the external API; the version
+// and timestamps of an exported character are not set.
+func sameContent(a domains.Character, b domains.Character) bool {
+	return a.Name == b.Name && a.Ki == b.Ki && a.Race == b.Race && a.Image == b.Image
+}
+
+func (j *SyncCharactersJob) Enabled() bool {
+	return j.interval > 0
+}
+
+func (j *SyncCharactersJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.RunOnce(ctx, false)
+		if err != nil {
+			domains.LoggerFromContext(ctx).Error("error when execute SyncCharactersJob.RunOnce", "err", err)
+		} else if report.Updated > 0 {
+			domains.LoggerFromContext(ctx).Info("synced characters", "checked", report.Checked, "updated", report.Updated)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
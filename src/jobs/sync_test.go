@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SyncCharactersJob(t *testing.T) {
+	goku := domains.Character{ID: 1, Name: "goku", Ki: "60.000.000", Race: "Saiyan", Image: "goku.webp"}
+	vegeta := domains.Character{ID: 2, Name: "vegeta", Ki: "54.000.000", Race: "Saiyan", Image: "vegeta.webp"}
+	krillin := domains.Character{ID: 3, Name: "krillin", Ki: "1.000.000", Race: "Human", Image: "krillin.webp"}
+
+	t.Run("given stored characters, it syncs each one and reports the outcome", func(t *testing.T) {
+		upstreamGoku := goku
+		upstreamGoku.Ki = "90.000.000"
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{goku, vegeta, krillin}, nil)
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(1)).Return(upstreamGoku, nil)
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, []domains.Character{upstreamGoku}, true).Return([]domains.Character{upstreamGoku}, nil)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(2)).Return(domains.Character{}, domains.ErrCharacterNotFoundInExternalAPI)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(3)).Return(domains.Character{}, errors.New("any error"))
+
+		job := NewSyncCharactersJob(characterArchiveRepoMock, characterSyncRepoMock, 0)
+
+		report, err := job.RunOnce(context.Background(), true)
+		require.NoError(t, err)
+		assert.Equal(t, SyncReport{Checked: 3, Updated: 1, Missing: 1, Failed: 1}, report)
+		assert.False(t, job.Enabled())
+	})
+
+	t.Run("given a character renamed locally, it looks it up by id", func(t *testing.T) {
+		renamed := goku
+		renamed.Name = "kakarot"
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{renamed}, nil)
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, []domains.Character{goku}, false).Return([]domains.Character{renamed}, nil)
+
+		job := NewSyncCharactersJob(characterArchiveRepoMock, characterSyncRepoMock, 0)
+
+		report, err := job.RunOnce(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, SyncReport{Checked: 1}, report)
+	})
+
+	t.Run("given an unchanged upstream, it does not report the synced version as updated", func(t *testing.T) {
+		synced := goku
+		synced.Version = 4
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{goku}, nil)
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, []domains.Character{goku}, false).Return([]domains.Character{synced}, nil)
+
+		job := NewSyncCharactersJob(characterArchiveRepoMock, characterSyncRepoMock, 0)
+
+		report, err := job.RunOnce(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, SyncReport{Checked: 1}, report)
+	})
+
+	t.Run("given a character deleted since the export, it reports it missing", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{goku, vegeta}, nil)
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(2)).Return(vegeta, nil)
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, []domains.Character{goku, vegeta}, false).Return([]domains.Character{goku}, nil)
+
+		job := NewSyncCharactersJob(characterArchiveRepoMock, characterSyncRepoMock, 0)
+
+		report, err := job.RunOnce(context.Background(), false)
+		require.NoError(t, err)
+		assert.Equal(t, SyncReport{Checked: 2, Missing: 1}, report)
+	})
+
+	t.Run("given the batch fails, it returns the error and reports every fetched character failed", func(t *testing.T) {
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{goku, vegeta}, nil)
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, uint(2)).Return(vegeta, nil)
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, []domains.Character{goku, vegeta}, false).Return(nil, errors.New("any error"))
+
+		job := NewSyncCharactersJob(characterArchiveRepoMock, characterSyncRepoMock, 0)
+
+		report, err := job.RunOnce(context.Background(), false)
+		assert.ErrorContains(t, err, "any error")
+		assert.Equal(t, SyncReport{Checked: 2, Failed: 2}, report)
+	})
+}
@@ -0,0 +1,163 @@
+// Package metrics collects the Prometheus metrics of the web app: its HTTP
+// requests, the storage and external API calls of the repositories, the
+// lookup cache and the database connection pools.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dragonball"
+
+type Metrics struct {
+	registry         *prometheus.Registry
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	queryDuration    *prometheus.HistogramVec
+	upstreamDuration *prometheus.HistogramVec
+	upstreamErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests answered, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time to answer an HTTP request, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time of a storage call, by repository method and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "outcome"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Time of a call to the external API, by repository method and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "outcome"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Calls to the external API that failed, by repository method.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.upstreamDuration,
+		m.upstreamErrors,
+	)
+
+	return m
+}
+
+// Handler serves every metric in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveHTTP(
+	method string,
+	route string,
+	status int,
+	elapsed time.Duration,
+) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObserveQuery(
+	method string,
+	outcome string,
+	elapsed time.Duration,
+) {
+	m.queryDuration.WithLabelValues(method, outcome).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObserveUpstream(
+	operation string,
+	outcome string,
+	elapsed time.Duration,
+) {
+	m.upstreamDuration.WithLabelValues(operation, outcome).Observe(elapsed.Seconds())
+	if outcome == "error" {
+		m.upstreamErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterCache reports the counters of the lookup cache each time the
+// metrics are scraped.
+func (m *Metrics) RegisterCache(cacheStatsReporter domains.CacheStatsReporter) error {
+	cacheCounter := func(name string, help string, value func(stats domains.CacheStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return value(cacheStatsReporter.Stats()) })
+	}
+	cacheGauge := func(name string, help string, value func(stats domains.CacheStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return value(cacheStatsReporter.Stats()) })
+	}
+
+	for _, collector := range []prometheus.Collector{
+		cacheCounter("cache_hits_total", "Character lookups answered by the cache.", func(stats domains.CacheStats) float64 {
+			return float64(stats.Hits)
+		}),
+		cacheCounter("cache_misses_total", "Character lookups the cache could not answer.", func(stats domains.CacheStats) float64 {
+			return float64(stats.Misses)
+		}),
+		cacheCounter("cache_evictions_total", "Entries evicted from the memory cache to make room.", func(stats domains.CacheStats) float64 {
+			return float64(stats.Evictions)
+		}),
+		cacheGauge("cache_entries", "Entries in the memory cache.", func(stats domains.CacheStats) float64 {
+			return float64(stats.Size)
+		}),
+		cacheGauge("cache_hit_ratio", "Share of the character lookups answered by the cache since start.", func(stats domains.CacheStats) float64 {
+			if stats.Hits+stats.Misses == 0 {
+				return 0
+			}
+			return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+		}),
+	} {
+		if err := m.registry.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterDB reports the connection pool of sqlClient as go_sql_* metrics
+// labelled db_name=name.
+func (m *Metrics) RegisterDB(
+	name string,
+	sqlClient *sql.DB,
+) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(sqlClient, name))
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func Test_Metrics(t *testing.T) {
+	t.Run("given storage and external API calls, it exposes their durations and errors", func(t *testing.T) {
+		m := New()
+		m.ObserveQuery("GetCharacterInDatabaseByID", "ok", 3*time.Millisecond)
+		m.ObserveQuery("GetCharacterInDatabaseByID", "rejected", time.Millisecond)
+		m.ObserveUpstream("FetchCharacterInExternalAPIByName", "ok", 200*time.Millisecond)
+		m.ObserveUpstream("FetchCharacterInExternalAPIByName", "error", time.Second)
+
+		body := scrape(t, m)
+		assert.Contains(t, body, `dragonball_db_query_duration_seconds_count{method="GetCharacterInDatabaseByID",outcome="ok"} 1`)
+		assert.Contains(t, body, `dragonball_db_query_duration_seconds_count{method="GetCharacterInDatabaseByID",outcome="rejected"} 1`)
+		assert.Contains(t, body, `dragonball_upstream_request_duration_seconds_count{operation="FetchCharacterInExternalAPIByName",outcome="error"} 1`)
+		assert.Contains(t, body, `dragonball_upstream_errors_total{operation="FetchCharacterInExternalAPIByName"} 1`)
+		assert.Contains(t, body, "go_goroutines")
+	})
+
+	t.Run("given a cache, it exposes its counters and hit ratio", func(t *testing.T) {
+		cacheStatsReporterMock := mocks.NewCacheStatsReporter(t)
+		cacheStatsReporterMock.On("Stats").Return(domains.CacheStats{Hits: 3, Misses: 1, Evictions: 2, Size: 5})
+
+		m := New()
+		require.NoError(t, m.RegisterCache(cacheStatsReporterMock))
+
+		body := scrape(t, m)
+		assert.Contains(t, body, "dragonball_cache_hits_total 3")
+		assert.Contains(t, body, "dragonball_cache_misses_total 1")
+		assert.Contains(t, body, "dragonball_cache_evictions_total 2")
+		assert.Contains(t, body, "dragonball_cache_entries 5")
+		assert.Contains(t, body, "dragonball_cache_hit_ratio 0.75")
+	})
+
+	t.Run("given a database, it exposes its connection pool", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		m := New()
+		require.NoError(t, m.RegisterDB("primary", db))
+		assert.Error(t, m.RegisterDB("primary", db))
+
+		body := scrape(t, m)
+		assert.Contains(t, body, `go_sql_max_open_connections{db_name="primary"} 0`)
+		assert.Contains(t, body, `go_sql_open_connections{db_name="primary"}`)
+	})
+
+	t.Run("given an HTTP request, it exposes it by route and status", func(t *testing.T) {
+		m := New()
+		m.ObserveHTTP(http.MethodGet, "/api/v1/characters/:id", http.StatusNotFound, 2*time.Millisecond)
+
+		body := scrape(t, m)
+		assert.Contains(t, body, `dragonball_http_requests_total{method="GET",route="/api/v1/characters/:id",status="404"} 1`)
+	})
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+// Auditor records the client address as the actor of every write made by
+// the request. The engine needs ContextWithFallback so that the repository
+// sees it through the *gin.Context handlers pass down.
+func Auditor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(domains.WithAuditor(
+			ctx.Request.Context(),
+			"ip:"+ctx.ClientIP(),
+			domains.AuditSourceAPI,
+		))
+
+		ctx.Next()
+	}
+}
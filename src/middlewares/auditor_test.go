@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Auditor(t *testing.T) {
+	audit := func(t *testing.T, trustedProxies []string, forwardedFor string) domains.Auditor {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.ContextWithFallback = true
+		require.NoError(t, r.SetTrustedProxies(trustedProxies))
+		r.Use(Auditor())
+
+		var auditor domains.Auditor
+		r.GET("/api/test", func(ctx *gin.Context) {
+			var c context.Context = ctx
+			auditor = domains.AuditorFromContext(c)
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		return auditor
+	}
+
+	t.Run("given a request, it exposes the client as auditor through the gin context", func(t *testing.T) {
+		auditor := audit(t, nil, "")
+
+		assert.Equal(t, "ip:10.0.0.1", auditor.Actor)
+		assert.Equal(t, domains.AuditSourceAPI, auditor.Source)
+	})
+
+	t.Run("given a spoofed X-Forwarded-For and no trusted proxy, it keeps the peer as actor", func(t *testing.T) {
+		auditor := audit(t, nil, "203.0.113.7")
+
+		assert.Equal(t, "ip:10.0.0.1", auditor.Actor)
+	})
+
+	t.Run("given X-Forwarded-For from a trusted proxy, it takes the forwarded client as actor", func(t *testing.T) {
+		auditor := audit(t, []string{"10.0.0.0/8"}, "203.0.113.7")
+
+		assert.Equal(t, "ip:203.0.113.7", auditor.Actor)
+	})
+}
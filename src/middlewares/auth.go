@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// authenticateHeader is sent with every 401, as RFC 9110 asks.
+const authenticateHeader = `Bearer realm="dragon-ball"`
+
+// Authenticate looks up the API key sent as X-API-Key or as Authorization:
+// Bearer <key>, or verifies the JWT sent as Authorization: Bearer <token>,
+// and makes whoever it names the actor of the writes of the request. Bearer
+// tokens starting with the API key prefix are API keys; the others are JWTs
+// when tokenVerifier is not nil. A request without credentials goes on
+// anonymously, for RequireRole to turn away; one with unknown, revoked or
+// expired credentials is answered 401 at once. It goes after Auditor and
+// Logger.
+func Authenticate(
+	apiKeyRepository domains.APIKeyRepository,
+	tokenVerifier domains.TokenVerifier,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		secret, isAPIKey := credentialsFromRequest(ctx.Request)
+		if secret == "" {
+			ctx.Next()
+			return
+		}
+
+		var principal domains.Principal
+		var err error
+		if isAPIKey || tokenVerifier == nil {
+			principal, err = principalOfAPIKey(ctx, apiKeyRepository, secret)
+		} else {
+			principal, err = tokenVerifier.VerifyToken(ctx, secret)
+		}
+		if err != nil {
+			if errors.Is(err, domains.ErrAPIKeyIsInvalid) || errors.Is(err, domains.ErrTokenIsInvalid) {
+				ctx.Header("WWW-Authenticate", authenticateHeader+`, error="invalid_token"`)
+			}
+			_ = ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		requestCtx := domains.WithPrincipal(ctx.Request.Context(), principal)
+		requestCtx = domains.WithAuditor(requestCtx, principal.Actor(), domains.AuditSourceAPI)
+		requestCtx = domains.WithLogAttrs(requestCtx, principal.Method, principal.Name)
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+
+		ctx.Next()
+	}
+}
+
+// RequireRole answers 401 to requests without credentials and 403 to those
+// whose API key or JWT has a role below role. It goes after Authenticate.
+func RequireRole(role domains.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := domains.PrincipalFromContext(ctx.Request.Context())
+		if !ok {
+			ctx.Header("WWW-Authenticate", authenticateHeader)
+			_ = ctx.Error(domains.ErrAPIKeyIsMissing)
+			ctx.Abort()
+			return
+		}
+
+		if !principal.Role.Allows(role) {
+			_ = ctx.Error(fmt.Errorf("%w: %s is required, got %q", domains.ErrRoleIsNotAllowed, role, principal.Role))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func principalOfAPIKey(
+	ctx *gin.Context,
+	apiKeyRepository domains.APIKeyRepository,
+	secret string,
+) (domains.Principal, error) {
+	key, err := apiKeyRepository.GetAPIKeyInDatabaseByHash(ctx, domains.HashAPIKey(secret))
+	if err != nil {
+		return domains.Principal{}, err
+	}
+
+	return domains.Principal{
+		Name:   key.Name,
+		Role:   key.Role,
+		Method: domains.AuthMethodAPIKey,
+	}, nil
+}
+
+// credentialsFromRequest answers the credentials of the request and whether
+// they are an API key rather than a JWT.
+func credentialsFromRequest(req *http.Request) (string, bool) {
+	if key := req.Header.Get(APIKeyHeader); key != "" {
+		return key, true
+	}
+
+	scheme, token, found := strings.Cut(req.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(token)
+		return token, strings.HasPrefix(token, domains.APIKeyPrefix)
+	}
+
+	return "", false
+}
@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Authenticate(t *testing.T) {
+	editor := domains.APIKey{ID: 1, Name: "ci", Role: domains.RoleEditor}
+
+	newRouter := func(apiKeyRepository domains.APIKeyRepository, tokenVerifier domains.TokenVerifier, role domains.Role, auditor *domains.Auditor) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.ContextWithFallback = true
+		r.Use(Problems(NewDomainProblemRegistry()), Auditor(), Authenticate(apiKeyRepository, tokenVerifier))
+
+		r.DELETE("/api/test", RequireRole(role), func(ctx *gin.Context) {
+			var c context.Context = ctx
+			*auditor = domains.AuditorFromContext(c)
+			ctx.Status(http.StatusNoContent)
+		})
+		return r
+	}
+
+	t.Run("given a bearer key with the role, it returns 204 and audits the key", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, domains.HashAPIKey("dbk_secret")).Return(editor, nil)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer dbk_secret")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "key:ci", auditor.Actor)
+		assert.Equal(t, domains.AuditSourceAPI, auditor.Source)
+	})
+
+	t.Run("given an X-API-Key with a role above the required one, it returns 204", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, domains.HashAPIKey("dbk_secret")).Return(editor, nil)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set(APIKeyHeader, "dbk_secret")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleReader, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("given no key, it returns 401 with WWW-Authenticate", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer realm="dragon-ball"`, rec.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, rec.Body.String(), `"code":"unauthenticated"`)
+		assert.Empty(t, auditor.Actor)
+	})
+
+	t.Run("given an unknown or revoked key, it returns 401", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, domains.HashAPIKey("dbk_revoked")).Return(domains.APIKey{}, domains.ErrAPIKeyIsInvalid)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer dbk_revoked")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer realm="dragon-ball", error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, rec.Body.String(), `"code":"api_key_invalid"`)
+	})
+
+	t.Run("given a key with a role below the required one, it returns 403", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, domains.HashAPIKey("dbk_secret")).Return(editor, nil)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer dbk_secret")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleAdmin, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"forbidden"`)
+		assert.Contains(t, rec.Body.String(), `admin is required, got \"editor\"`)
+	})
+
+	t.Run("given a key store that cannot be reached, it returns 500", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, mock.Anything).Return(domains.APIKey{}, errors.New("connection refused"))
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer dbk_secret")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, nil, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Empty(t, rec.Header().Get("WWW-Authenticate"))
+	})
+	t.Run("given a bearer JWT with the role, it returns 204 and audits its subject", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		tokenVerifierMock := mocks.NewTokenVerifier(t)
+		tokenVerifierMock.On("VerifyToken", mock.Anything, "eyJ.token").Return(domains.Principal{Name: "ci-bot", Role: domains.RoleAdmin, Method: domains.AuthMethodJWT}, nil)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer eyJ.token")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, tokenVerifierMock, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "jwt:ci-bot", auditor.Actor)
+	})
+
+	t.Run("given a bearer API key and a token verifier, it looks the key up", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		apiKeyRepoMock.On("GetAPIKeyInDatabaseByHash", mock.Anything, domains.HashAPIKey("dbk_secret")).Return(editor, nil)
+		tokenVerifierMock := mocks.NewTokenVerifier(t)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer dbk_secret")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, tokenVerifierMock, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "key:ci", auditor.Actor)
+	})
+
+	t.Run("given an invalid JWT, it returns 401", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		tokenVerifierMock := mocks.NewTokenVerifier(t)
+		tokenVerifierMock.On("VerifyToken", mock.Anything, "eyJ.expired").Return(domains.Principal{}, domains.ErrTokenIsInvalid)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer eyJ.expired")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, tokenVerifierMock, domains.RoleEditor, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, `Bearer realm="dragon-ball", error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, rec.Body.String(), `"code":"token_invalid"`)
+	})
+
+	t.Run("given a JWT without a role, it returns 403", func(t *testing.T) {
+		apiKeyRepoMock := mocks.NewAPIKeyRepository(t)
+		tokenVerifierMock := mocks.NewTokenVerifier(t)
+		tokenVerifierMock.On("VerifyToken", mock.Anything, "eyJ.token").Return(domains.Principal{Name: "someone", Method: domains.AuthMethodJWT}, nil)
+
+		var auditor domains.Auditor
+		req, err := http.NewRequest(http.MethodDelete, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer eyJ.token")
+
+		rec := httptest.NewRecorder()
+		newRouter(apiKeyRepoMock, tokenVerifierMock, domains.RoleReader, &auditor).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
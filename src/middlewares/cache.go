@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CachePolicy struct {
+	CacheControl string
+	Vary         []string
+}
+
+// Private is the policy for a route that answers only authenticated
+// requests: shared caches must not keep its responses, so public and
+// s-maxage give way to private, and Vary names the credentials.
+func (p CachePolicy) Private() CachePolicy {
+	directives := []string{}
+	private := false
+	for _, directive := range strings.Split(p.CacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		name, _, _ := strings.Cut(strings.ToLower(directive), "=")
+		switch name {
+		case "", "public", "s-maxage":
+			continue
+		case "private", "no-store":
+			private = true
+		}
+		directives = append(directives, directive)
+	}
+	if !private {
+		directives = append([]string{"private"}, directives...)
+	}
+
+	vary := append(append([]string{}, p.Vary...), "Authorization", "X-API-Key")
+
+	return CachePolicy{
+		CacheControl: strings.Join(directives, ", "),
+		Vary:         vary,
+	}
+}
+
+// CacheHeaders sets the caching headers of a route. Problem responses
+// replace Cache-Control with no-store, so errors are never cached. Vary is
+// added to the one CORS sets, so responses to one origin are not served to
+// another.
+func CacheHeaders(policy CachePolicy) gin.HandlerFunc {
+	vary := strings.Join(policy.Vary, ", ")
+
+	return func(ctx *gin.Context) {
+		if policy.CacheControl != "" {
+			ctx.Header("Cache-Control", policy.CacheControl)
+		}
+		if vary != "" {
+			ctx.Writer.Header().Add("Vary", vary)
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CacheHeaders(t *testing.T) {
+	newRouter := func(err error) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(Problems(NewDomainProblemRegistry()))
+
+		r.GET("/api/test", CacheHeaders(CachePolicy{
+			CacheControl: "public, max-age=60",
+			Vary:         []string{"Accept", "Accept-Encoding"},
+		}), func(ctx *gin.Context) {
+			if err != nil {
+				_ = ctx.Error(err)
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{})
+		})
+
+		return r
+	}
+
+	t.Run("given a successful response, it sets the route policy", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(nil).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, "public, max-age=60", res.Header.Get("Cache-Control"))
+		assert.Equal(t, "Accept, Accept-Encoding", res.Header.Get("Vary"))
+	})
+
+	t.Run("given a problem response, it is not cached", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		newRouter(domains.ErrCharacterNotFoundInDatabase).ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+	})
+}
+
+func Test_CachePolicyPrivate(t *testing.T) {
+	t.Run("given a public policy, it keeps it out of shared caches", func(t *testing.T) {
+		policy := CachePolicy{
+			CacheControl: "public, max-age=30, s-maxage=60",
+			Vary:         []string{"Accept", "Accept-Encoding"},
+		}.Private()
+
+		assert.Equal(t, "private, max-age=30", policy.CacheControl)
+		assert.Equal(t, []string{"Accept", "Accept-Encoding", "Authorization", "X-API-Key"}, policy.Vary)
+	})
+
+	t.Run("given a policy that is not stored, it keeps it", func(t *testing.T) {
+		policy := CachePolicy{CacheControl: "no-store"}.Private()
+
+		assert.Equal(t, "no-store", policy.CacheControl)
+	})
+
+	t.Run("given a policy that revalidates, it makes it private", func(t *testing.T) {
+		policy := CachePolicy{CacheControl: "no-cache"}.Private()
+
+		assert.Equal(t, "private, no-cache", policy.CacheControl)
+	})
+}
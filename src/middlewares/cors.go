@@ -0,0 +1,151 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+var ErrCORSPolicyIsInvalid = errors.New("CORS policy is invalid")
+
+// CORSPolicy is which browser origins may call the API, and how. An origin
+// is a scheme and host, with a port if not the default one, such as
+// https://example.com; https://*.example.com is any single subdomain of
+// example.com, and * is any origin.
+type CORSPolicy struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+var corsMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"OPTIONS": true,
+}
+
+// CORS answers the preflight requests of the origins of policy and lets
+// browsers read the responses to them; requests from other origins are
+// answered 403. Without origins no request is answered as a CORS one, so
+// browsers keep the API to its own origin. It answers ErrCORSPolicyIsInvalid
+// for combinations browsers refuse or that would expose credentials, such as
+// * with AllowCredentials.
+func CORS(policy CORSPolicy) (gin.HandlerFunc, error) {
+	if len(policy.AllowOrigins) == 0 {
+		return func(ctx *gin.Context) { ctx.Next() }, nil
+	}
+
+	config := cors.Config{
+		AllowMethods:     policy.AllowMethods,
+		AllowHeaders:     policy.AllowHeaders,
+		ExposeHeaders:    policy.ExposeHeaders,
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           policy.MaxAge,
+	}
+
+	var problems []string
+	if len(policy.AllowOrigins) == 1 && policy.AllowOrigins[0] == "*" {
+		config.AllowAllOrigins = true
+		if policy.AllowCredentials {
+			problems = append(problems, "origin * cannot be allowed with credentials, list the origins instead")
+		}
+	} else {
+		matchers := make([]originMatcher, 0, len(policy.AllowOrigins))
+		for _, origin := range policy.AllowOrigins {
+			matcher, err := parseOrigin(origin)
+			if err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+			matchers = append(matchers, matcher)
+		}
+		config.AllowOriginFunc = func(origin string) bool {
+			for _, matcher := range matchers {
+				if matcher.matches(origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	for _, method := range policy.AllowMethods {
+		if !corsMethods[method] {
+			problems = append(problems, fmt.Sprintf("method %q is not one of GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS", method))
+		}
+	}
+	for _, header := range policy.AllowHeaders {
+		if header == "*" && policy.AllowCredentials {
+			problems = append(problems, "header * cannot be allowed with credentials, list the headers instead")
+		}
+	}
+	if policy.MaxAge < 0 {
+		problems = append(problems, "max age must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCORSPolicyIsInvalid, strings.Join(problems, "; "))
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCORSPolicyIsInvalid, err)
+	}
+
+	return cors.New(config), nil
+}
+
+// originMatcher is an allowed origin; with a wildcard, host is the parent
+// domain, without its leading dot.
+type originMatcher struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+func parseOrigin(origin string) (originMatcher, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return originMatcher{}, fmt.Errorf("origin %q must be an http or https scheme and host, such as https://example.com", origin)
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return originMatcher{}, fmt.Errorf("origin %q must have no user, path, query or fragment", origin)
+	}
+
+	matcher := originMatcher{scheme: u.Scheme, host: u.Host}
+	if parent, ok := strings.CutPrefix(u.Host, "*."); ok {
+		matcher.host = parent
+		matcher.wildcard = true
+	}
+	if strings.Contains(matcher.host, "*") {
+		return originMatcher{}, fmt.Errorf("origin %q may only have * as the first label of its host, such as https://*.example.com", origin)
+	}
+	if matcher.wildcard && !strings.Contains(strings.Split(matcher.host, ":")[0], ".") {
+		return originMatcher{}, fmt.Errorf("origin %q must have a wildcard below a registrable domain, such as https://*.example.com", origin)
+	}
+
+	return matcher, nil
+}
+
+func (m originMatcher) matches(origin string) bool {
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || scheme != m.scheme {
+		return false
+	}
+	if !m.wildcard {
+		return host == m.host
+	}
+
+	label, parent, ok := strings.Cut(host, ".")
+	return ok && parent == m.host && label != "" && !strings.ContainsAny(label, ":/@")
+}
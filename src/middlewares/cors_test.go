@@ -0,0 +1,140 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CORS(t *testing.T) {
+	policy := CORSPolicy{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.preview.example.com", "http://localhost:3000"},
+		AllowMethods:     []string{"GET", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	newRouter := func(t *testing.T, policy CORSPolicy) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		corsMiddleware, err := CORS(policy)
+		require.NoError(t, err)
+
+		r := gin.New()
+		r.Use(corsMiddleware, Problems(NewDomainProblemRegistry()))
+		r.GET("/api/test", CacheHeaders(CachePolicy{Vary: []string{"Accept"}}), func(ctx *gin.Context) {
+			ctx.Header("ETag", `"1"`)
+			ctx.JSON(http.StatusOK, gin.H{})
+		})
+		r.PATCH("/api/test", func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		})
+		return r
+	}
+	preflight := func(origin string, method string) *http.Request {
+		req, _ := http.NewRequest(http.MethodOptions, "/api/test", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,if-match")
+		return req
+	}
+
+	t.Run("given a preflight from an allowed origin, it returns 204 with the policy", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(t, policy).ServeHTTP(rec, preflight("https://app.example.com", http.MethodPatch))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "GET,PATCH,DELETE", rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type,Authorization,If-Match", rec.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("given a preflight from a subdomain of a wildcard origin, it returns 204", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(t, policy).ServeHTTP(rec, preflight("https://pr-42.preview.example.com", http.MethodPatch))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "https://pr-42.preview.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	for _, origin := range []string{
+		"https://evil.example.com",
+		"http://app.example.com",
+		"https://preview.example.com",
+		"https://a.b.preview.example.com",
+		"https://evilpreview.example.com",
+		"https://app.example.com.evil.com",
+		"http://localhost:3001",
+	} {
+		t.Run("given a preflight from "+origin+", it returns 403 without CORS headers", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newRouter(t, policy).ServeHTTP(rec, preflight(origin, http.MethodPatch))
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+
+	t.Run("given a request from an allowed origin, it exposes the headers and keeps Vary", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "http://localhost:3000")
+
+		rec := httptest.NewRecorder()
+		newRouter(t, policy).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Etag", rec.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, []string{"Origin", "Accept"}, rec.Header().Values("Vary"))
+	})
+
+	t.Run("given any origin without credentials, it returns *", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(t, CORSPolicy{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET"}}).ServeHTTP(rec, preflight("https://anyone.test", http.MethodGet))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("given no origins, it answers no request as a CORS one", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(t, CORSPolicy{}).ServeHTTP(rec, preflight("https://app.example.com", http.MethodPatch))
+
+		assert.NotEqual(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	invalid := []struct {
+		name   string
+		policy CORSPolicy
+		reason string
+	}{
+		{"any origin with credentials", CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true}, "origin * cannot be allowed with credentials"},
+		{"any origin among others", CORSPolicy{AllowOrigins: []string{"*", "https://app.example.com"}}, `origin "*" must be an http or https scheme and host`},
+		{"any header with credentials", CORSPolicy{AllowOrigins: []string{"https://app.example.com"}, AllowHeaders: []string{"*"}, AllowCredentials: true}, "header * cannot be allowed with credentials"},
+		{"an origin with a path", CORSPolicy{AllowOrigins: []string{"https://app.example.com/app"}}, "must have no user, path, query or fragment"},
+		{"an origin without scheme", CORSPolicy{AllowOrigins: []string{"app.example.com"}}, "must be an http or https scheme and host"},
+		{"a wildcard inside the host", CORSPolicy{AllowOrigins: []string{"https://app-*.example.com"}}, "may only have * as the first label"},
+		{"a wildcard over a top level domain", CORSPolicy{AllowOrigins: []string{"https://*.com"}}, "must have a wildcard below a registrable domain"},
+		{"an unknown method", CORSPolicy{AllowOrigins: []string{"https://app.example.com"}, AllowMethods: []string{"get"}}, `method "get" is not one of`},
+	}
+	for _, tc := range invalid {
+		t.Run("given "+tc.name+", it returns ErrCORSPolicyIsInvalid", func(t *testing.T) {
+			_, err := CORS(tc.policy)
+
+			assert.ErrorIs(t, err, ErrCORSPolicyIsInvalid)
+			assert.ErrorContains(t, err, tc.reason)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func NewDomainProblemRegistry() *ProblemRegistry {
+	return NewProblemRegistry().
+		Register(domains.ErrNameIsRequired, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "name_required",
+			Title:  "Name is required",
+		}).
+		Register(domains.ErrLimitIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "limit_invalid",
+			Title:  "Limit is invalid",
+		}).
+		Register(domains.ErrIDIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "id_invalid",
+			Title:  "ID is invalid",
+		}).
+		Register(domains.ErrOffsetIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "offset_invalid",
+			Title:  "Offset is invalid",
+		}).
+		Register(domains.ErrIncludeDeletedIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "include_deleted_invalid",
+			Title:  "Include deleted flag is invalid",
+		}).
+		Register(domains.ErrAsOfIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "as_of_invalid",
+			Title:  "As of timestamp is invalid",
+		}).
+		Register(domains.ErrVersionIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "version_invalid",
+			Title:  "Version is invalid",
+		}).
+		Register(domains.ErrCharacterPatchInvalid, ProblemType{
+			Status: http.StatusUnprocessableEntity,
+			Code:   "character_patch_invalid",
+			Title:  "Character patch is invalid",
+		}).
+		Register(domains.ErrCharacterPatchTooLarge, ProblemType{
+			Status: http.StatusRequestEntityTooLarge,
+			Code:   "character_patch_too_large",
+			Title:  "Character patch is too large",
+		}).
+		Register(domains.ErrContentTypeNotSupported, ProblemType{
+			Status: http.StatusUnsupportedMediaType,
+			Code:   "content_type_not_supported",
+			Title:  "Content type is not supported",
+		}).
+		Register(domains.ErrIfMatchIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "if_match_invalid",
+			Title:  "If-Match is invalid",
+		}).
+		Register(domains.ErrPreconditionRequired, ProblemType{
+			Status: http.StatusPreconditionRequired,
+			Code:   "precondition_required",
+			Title:  "Precondition required",
+		}).
+		Register(domains.ErrCharacterVersionMismatch, ProblemType{
+			Status: http.StatusPreconditionFailed,
+			Code:   "precondition_failed",
+			Title:  "Precondition failed",
+		}).
+		Register(domains.ErrCharacterNotFoundInExternalAPI, ProblemType{
+			Status: http.StatusNotFound,
+			Code:   "character_not_found",
+			Title:  "Character not found",
+		}).
+		Register(domains.ErrCharacterNotFoundInDatabase, ProblemType{
+			Status: http.StatusNotFound,
+			Code:   "character_not_found",
+			Title:  "Character not found",
+		}).
+		Register(domains.ErrCharacterVersionNotFound, ProblemType{
+			Status: http.StatusNotFound,
+			Code:   "character_version_not_found",
+			Title:  "Character version not found",
+		}).
+		Register(domains.ErrCharacterIsDeleted, ProblemType{
+			Status: http.StatusGone,
+			Code:   "character_deleted",
+			Title:  "Character is deleted",
+		}).
+		Register(domains.ErrCharacterAlreadyExistInDatabase, ProblemType{
+			Status: http.StatusConflict,
+			Code:   "character_already_exists",
+			Title:  "Character already exists",
+		}).
+		Register(domains.ErrAPIKeyIsMissing, ProblemType{
+			Status: http.StatusUnauthorized,
+			Code:   "unauthenticated",
+			Title:  "Authentication is required",
+		}).
+		Register(domains.ErrAPIKeyIsInvalid, ProblemType{
+			Status: http.StatusUnauthorized,
+			Code:   "api_key_invalid",
+			Title:  "API key is invalid",
+		}).
+		Register(domains.ErrTokenIsInvalid, ProblemType{
+			Status: http.StatusUnauthorized,
+			Code:   "token_invalid",
+			Title:  "Bearer token is invalid",
+		}).
+		Register(domains.ErrRoleIsNotAllowed, ProblemType{
+			Status: http.StatusForbidden,
+			Code:   "forbidden",
+			Title:  "Role is not allowed",
+		}).
+		Register(domains.ErrRoleIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "role_invalid",
+			Title:  "Role is invalid",
+		}).
+		Register(domains.ErrAPIKeyNameIsRequired, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "name_required",
+			Title:  "Name is required",
+		}).
+		Register(domains.ErrAPIKeyNotFound, ProblemType{
+			Status: http.StatusNotFound,
+			Code:   "api_key_not_found",
+			Title:  "API key not found",
+		}).
+		Register(domains.ErrAPIKeyAlreadyExists, ProblemType{
+			Status: http.StatusConflict,
+			Code:   "api_key_already_exists",
+			Title:  "API key already exists",
+		}).
+		Register(domains.ErrServiceIsShuttingDown, ProblemType{
+			Status: http.StatusServiceUnavailable,
+			Code:   "shutting_down",
+			Title:  "Service is shutting down",
+		}).
+		Register(domains.ErrLogLevelIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "log_level_invalid",
+			Title:  "Log level is invalid",
+		}).
+		Register(domains.ErrImportModeInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "import_mode_invalid",
+			Title:  "Import mode is invalid",
+		}).
+		Register(domains.ErrDryRunIsInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "dry_run_invalid",
+			Title:  "Dry run flag is invalid",
+		}).
+		Register(domains.ErrArchiveInvalid, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "archive_invalid",
+			Title:  "Archive is invalid",
+		}).
+		Register(domains.ErrArchiveVersionNotSupported, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "archive_version_not_supported",
+			Title:  "Archive version is not supported",
+		}).
+		Register(domains.ErrArchiveChecksumMismatch, ProblemType{
+			Status: http.StatusBadRequest,
+			Code:   "archive_checksum_mismatch",
+			Title:  "Archive checksum mismatch",
+		}).
+		Register(domains.ErrArchiveTooLarge, ProblemType{
+			Status: http.StatusRequestEntityTooLarge,
+			Code:   "archive_too_large",
+			Title:  "Archive is too large",
+		})
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger gives every request a logger carrying its request id, method and
+// route, which handlers and repositories read with domains.LoggerFromContext,
+// and logs one line per request once it is answered. It goes after RequestID
+// and, for the lines to carry the trace_id and span_id of the request, after
+// the tracing middleware.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		attrs := []any{
+			slog.String("request_id", ctx.GetString(RequestIDKey)),
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+		}
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.IsValid() {
+			attrs = append(attrs,
+				slog.String("trace_id", spanContext.TraceID().String()),
+				slog.String("span_id", spanContext.SpanID().String()),
+			)
+		}
+		ctx.Request = ctx.Request.WithContext(domains.WithLogger(ctx.Request.Context(), logger.With(attrs...)))
+
+		ctx.Next()
+
+		domains.LoggerFromContext(ctx.Request.Context()).LogAttrs(
+			ctx.Request.Context(),
+			slog.LevelInfo,
+			"request",
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", ctx.Writer.Status()),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", ctx.ClientIP()),
+		)
+	}
+}
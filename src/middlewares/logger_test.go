@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_Logger(t *testing.T) {
+	t.Run("given a request, it correlates the lines of the handler and the access log", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.ContextWithFallback = true
+		r.Use(RequestID(), Logger(logger))
+
+		r.GET("/api/v1/characters/:id", func(ctx *gin.Context) {
+			ctx.Request = ctx.Request.WithContext(domains.WithLogAttrs(ctx.Request.Context(), "character_id", 1))
+			var c context.Context = ctx
+			domains.LoggerFromContext(c).Info("inside the handler")
+			ctx.Status(http.StatusNoContent)
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1", nil)
+		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "abc123")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 2)
+
+		var handlerLine, accessLine map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+
+		assert.Equal(t, "inside the handler", handlerLine["msg"])
+		assert.Equal(t, "abc123", handlerLine["request_id"])
+		assert.Equal(t, "/api/v1/characters/:id", handlerLine["route"])
+		assert.Equal(t, float64(1), handlerLine["character_id"])
+
+		assert.Equal(t, "request", accessLine["msg"])
+		assert.Equal(t, "abc123", accessLine["request_id"])
+		assert.Equal(t, "GET", accessLine["method"])
+		assert.Equal(t, "/api/v1/characters/1", accessLine["path"])
+		assert.Equal(t, float64(http.StatusNoContent), accessLine["status"])
+		assert.Equal(t, float64(1), accessLine["character_id"])
+	})
+
+	t.Run("given a traced request, it logs the trace of the caller", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&out, nil))
+		exporter := tracetest.NewInMemoryExporter()
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.ContextWithFallback = true
+		r.Use(
+			otelgin.Middleware("dragon-ball",
+				otelgin.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))),
+				otelgin.WithPropagators(propagation.TraceContext{}),
+			),
+			RequestID(),
+			Logger(logger),
+		)
+		r.GET("/api/v1/characters/:id", func(ctx *gin.Context) {
+			ctx.Status(http.StatusNoContent)
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/characters/1", nil)
+		require.NoError(t, err)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "/api/v1/characters/:id", spans[0].Name)
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+
+		var accessLine map[string]interface{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &accessLine))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", accessLine["trace_id"])
+		assert.Equal(t, spans[0].SpanContext.SpanID().String(), accessLine["span_id"])
+	})
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HTTPObserver interface {
+	ObserveHTTP(method string, route string, status int, elapsed time.Duration)
+}
+
+// Metrics observes every request by its route template, /api/v1/characters/:id
+// rather than the path, so that the labels stay bounded. Requests matching no
+// route are observed as unmatched.
+func Metrics(httpObserver HTTPObserver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpObserver.ObserveHTTP(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(start))
+	}
+}
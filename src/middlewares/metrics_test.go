@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Metrics(t *testing.T) {
+	t.Run("given requests, it exposes them by route and status", func(t *testing.T) {
+		m := metrics.New()
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(Metrics(m))
+
+		r.GET("/api/v1/characters/:id", func(ctx *gin.Context) {
+			ctx.Status(http.StatusNoContent)
+		})
+		r.GET("/metrics", gin.WrapH(m.Handler()))
+
+		for _, path := range []string{"/api/v1/characters/1", "/api/v1/characters/2", "/api/missing"} {
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(body), `dragonball_http_requests_total{method="GET",route="/api/v1/characters/:id",status="204"} 2`)
+		assert.Contains(t, string(body), `dragonball_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+		assert.Contains(t, string(body), `dragonball_http_request_duration_seconds_count{method="GET",route="/api/v1/characters/:id",status="204"} 2`)
+	})
+}
@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body written for every error surfaced through
+// ctx.Error. Code is stable and meant for clients to switch on.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id"`
+}
+
+type ProblemType struct {
+	Status int
+	Code   string
+	Title  string
+}
+
+var internalProblemType = ProblemType{
+	Status: http.StatusInternalServerError,
+	Code:   "internal_error",
+	Title:  "Internal server error",
+}
+
+type problemMapping struct {
+	err         error
+	problemType ProblemType
+}
+
+type ProblemRegistry struct {
+	mappings []problemMapping
+}
+
+func NewProblemRegistry() *ProblemRegistry {
+	return &ProblemRegistry{}
+}
+
+func (r *ProblemRegistry) Register(
+	err error,
+	problemType ProblemType,
+) *ProblemRegistry {
+	r.mappings = append(r.mappings, problemMapping{
+		err:         err,
+		problemType: problemType,
+	})
+
+	return r
+}
+
+func (r *ProblemRegistry) Problem(err error) Problem {
+	for _, mapping := range r.mappings {
+		if errors.Is(err, mapping.err) {
+			return newProblem(mapping.problemType, err.Error())
+		}
+	}
+
+	return newProblem(internalProblemType, "an unexpected error occurred")
+}
+
+func newProblem(
+	problemType ProblemType,
+	detail string,
+) Problem {
+	return Problem{
+		Type:   "urn:dragon-ball:problem:" + problemType.Code,
+		Title:  problemType.Title,
+		Status: problemType.Status,
+		Detail: detail,
+		Code:   problemType.Code,
+	}
+}
+
+func Problems(registry *ProblemRegistry) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		problem := registry.Problem(err)
+		problem.Instance = ctx.Request.URL.Path
+		problem.RequestID = ctx.GetString(RequestIDKey)
+
+		if problem.Status >= http.StatusInternalServerError {
+			domains.LoggerFromContext(ctx.Request.Context()).Error("error when handle request", "status", problem.Status, "err", err)
+		}
+
+		ctx.Header("Content-Type", ProblemContentType)
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(problem.Status, problem)
+	}
+}
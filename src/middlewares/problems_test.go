@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Problems(t *testing.T) {
+	newRouter := func(err error) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(RequestID(), Problems(NewDomainProblemRegistry()))
+
+		r.GET("/api/test", func(ctx *gin.Context) {
+			_ = ctx.Error(err)
+		})
+
+		return r
+	}
+
+	t.Run("given a registered domain error, it returns its problem", func(t *testing.T) {
+		r := newRouter(fmt.Errorf("%w: strconv.Atoi: parsing \"asd\"", domains.ErrLimitIsInvalid))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "abc-123")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, ProblemContentType, res.Header.Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "limit_invalid", problem.Code)
+		assert.Equal(t, "urn:dragon-ball:problem:limit_invalid", problem.Type)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "/api/test", problem.Instance)
+		assert.Equal(t, "abc-123", problem.RequestID)
+		assert.Contains(t, problem.Detail, domains.ErrLimitIsInvalid.Error())
+	})
+
+	t.Run("given an unknown error, it returns an internal problem without leaking the detail", func(t *testing.T) {
+		r := newRouter(errors.New("pq: password authentication failed"))
+
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+		var problem Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "internal_error", problem.Code)
+		assert.NotContains(t, problem.Detail, "password")
+		assert.NotEmpty(t, problem.RequestID)
+		assert.Equal(t, problem.RequestID, res.Header.Get(RequestIDHeader))
+	})
+}
+
+func Test_ProblemRegistry(t *testing.T) {
+	errCustom := errors.New("custom error")
+
+	t.Run("given a registered error, it maps it declaratively", func(t *testing.T) {
+		registry := NewProblemRegistry().
+			Register(errCustom, ProblemType{Status: http.StatusTeapot, Code: "custom", Title: "Custom"})
+
+		problem := registry.Problem(fmt.Errorf("wrapped: %w", errCustom))
+
+		assert.Equal(t, http.StatusTeapot, problem.Status)
+		assert.Equal(t, "custom", problem.Code)
+		assert.Equal(t, "Custom", problem.Title)
+	})
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic into an error for Problems to answer, so it must be
+// registered after it. The stack is logged with the logger of the request,
+// so the line carries its request id.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(ctx *gin.Context, err any) {
+		domains.LoggerFromContext(ctx.Request.Context()).Error("panic when handle request", "panic", fmt.Sprint(err), "stack", string(debug.Stack()))
+
+		_ = ctx.Error(fmt.Errorf("panic when handle request: %v", err))
+		ctx.Abort()
+	})
+}
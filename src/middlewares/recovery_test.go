@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Recovery(t *testing.T) {
+	t.Run("given a handler that panics, it returns an internal problem", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		var out bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&out, nil))
+		r.Use(RequestID(), Logger(logger), Problems(NewDomainProblemRegistry()), Recovery())
+
+		r.GET("/api/test", func(ctx *gin.Context) {
+			panic("nil map")
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/api/test", nil)
+		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "abc-123")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, ProblemContentType, res.Header.Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+		assert.Equal(t, "internal_error", problem.Code)
+		assert.Equal(t, "abc-123", problem.RequestID)
+		assert.NotContains(t, problem.Detail, "nil map")
+
+		var line struct {
+			Msg       string `json:"msg"`
+			RequestID string `json:"request_id"`
+			Panic     string `json:"panic"`
+			Stack     string `json:"stack"`
+		}
+		require.NoError(t, json.NewDecoder(&out).Decode(&line))
+		assert.Equal(t, "panic when handle request", line.Msg)
+		assert.Equal(t, "abc-123", line.RequestID)
+		assert.Equal(t, "nil map", line.Panic)
+		assert.Contains(t, line.Stack, "recovery_test.go")
+	})
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// The API keys are always read from the primary, so a revoked key stops
+// working at once rather than once the replicas replay the revocation.
+
+func (r *CharacterRepository) CreateAPIKeyInDatabase(
+	ctx context.Context,
+	name string,
+	role domains.Role,
+	hash string,
+) (domains.APIKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	key, err := scanAPIKey(r.sqlClient.QueryRowContext(
+		ctxTimeout,
+		`INSERT INTO "api_key" ("name", "role", "key_hash") VALUES ($1, $2, $3) RETURNING "id", "name", "role", "created_at", "revoked_at"`,
+		name,
+		role,
+		hash,
+	))
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return domains.APIKey{}, domains.ErrAPIKeyAlreadyExists
+		}
+		return domains.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *CharacterRepository) GetAPIKeyInDatabaseByHash(
+	ctx context.Context,
+	hash string,
+) (domains.APIKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	key, err := scanAPIKey(r.sqlClient.QueryRowContext(
+		ctxTimeout,
+		`SELECT "id", "name", "role", "created_at", "revoked_at" FROM "api_key" WHERE "key_hash" = $1 AND "revoked_at" IS NULL`,
+		hash,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.APIKey{}, domains.ErrAPIKeyIsInvalid
+		}
+		return domains.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *CharacterRepository) ListAPIKeysInDatabase(ctx context.Context) ([]domains.APIKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	rows, err := r.sqlClient.QueryContext(
+		ctxTimeout,
+		`SELECT "id", "name", "role", "created_at", "revoked_at" FROM "api_key" ORDER BY "id"`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domains.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RevokeAPIKeyInDatabase keeps the time of the first revocation when the key
+// is revoked again.
+func (r *CharacterRepository) RevokeAPIKeyInDatabase(
+	ctx context.Context,
+	id uint,
+) (domains.APIKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	key, err := scanAPIKey(r.sqlClient.QueryRowContext(
+		ctxTimeout,
+		`UPDATE "api_key" SET "revoked_at" = COALESCE("revoked_at", NOW()) WHERE "id" = $1 RETURNING "id", "name", "role", "created_at", "revoked_at"`,
+		id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.APIKey{}, domains.ErrAPIKeyNotFound
+		}
+		return domains.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (domains.APIKey, error) {
+	var key domains.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Role,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	)
+
+	return key, err
+}
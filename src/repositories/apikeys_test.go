@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CreateAPIKeyInDatabase(t *testing.T) {
+
+	t.Run("execute insert and success", func(t *testing.T) {
+		createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`INSERT INTO "api_key" ("name", "role", "key_hash") VALUES ($1, $2, $3) RETURNING "id", "name", "role", "created_at", "revoked_at"`),
+		).WithArgs("ci", domains.RoleEditor, "hash").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role", "created_at", "revoked_at"}).
+				AddRow(1, "ci", "editor", createdAt, nil))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		key, err := repo.CreateAPIKeyInDatabase(context.Background(), "ci", domains.RoleEditor, "hash")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, domains.APIKey{ID: 1, Name: "ci", Role: domains.RoleEditor, CreatedAt: createdAt}, key)
+	})
+
+	t.Run("execute insert of a taken name and return ErrAPIKeyAlreadyExists", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`INSERT INTO "api_key" ("name", "role", "key_hash") VALUES ($1, $2, $3)`),
+		).WithArgs("ci", domains.RoleEditor, "hash").
+			WillReturnError(&pgconn.PgError{Code: pgUniqueViolation, Message: "duplicate key value violates unique constraint"})
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.CreateAPIKeyInDatabase(context.Background(), "ci", domains.RoleEditor, "hash")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrAPIKeyAlreadyExists)
+	})
+}
+
+func Test_GetAPIKeyInDatabaseByHash(t *testing.T) {
+
+	t.Run("execute get of a revoked or unknown key and return ErrAPIKeyIsInvalid", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "role", "created_at", "revoked_at" FROM "api_key" WHERE "key_hash" = $1 AND "revoked_at" IS NULL`),
+		).WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role", "created_at", "revoked_at"}))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.GetAPIKeyInDatabaseByHash(context.Background(), "hash")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrAPIKeyIsInvalid)
+	})
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func (r *CharacterRepository) ExportCharactersInDatabase(
+	ctx context.Context,
+) ([]domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	return selectAllCharacters(ctxTimeout, r.sqlClient)
+}
+
+func (r *CharacterRepository) ImportCharactersInDatabase(
+	ctx context.Context,
+	characters []domains.Character,
+	mode domains.ImportMode,
+	dryRun bool,
+) (domains.ImportReport, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return domains.ImportReport{}, err
+	}
+	defer func() {
+		if err != nil || dryRun {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	existing, err := selectAllCharacters(ctxTimeout, tx)
+	if err != nil {
+		return domains.ImportReport{}, err
+	}
+
+	report, changes := diffCharacters(existing, characters, mode)
+	report.DryRun = dryRun
+	if dryRun {
+		return report, nil
+	}
+
+	for _, change := range changes {
+		switch change.action {
+		case domains.AuditActionDelete:
+			after := *change.before
+			err = tx.QueryRowContext(
+				ctxTimeout,
+				`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "id" = $1 AND "deleted_at" IS NULL RETURNING "deleted_at", "version"`,
+				change.before.ID,
+			).Scan(&after.DeletedAt, &after.Version)
+			change.after = &after
+		default:
+			err = tx.QueryRowContext(
+				ctxTimeout,
+				`INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image", "version") VALUES ($1, $2, $3, $4, $5, `+nextVersion+`) `+
+					`ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "ki" = EXCLUDED."ki", "race" = EXCLUDED."race", "image" = EXCLUDED."image", "deleted_at" = NULL, "version" = "character_dragonball"."version" + 1 `+
+					`RETURNING "version"`,
+				change.after.ID,
+				change.after.Name,
+				change.after.Ki,
+				change.after.Race,
+				change.after.Image,
+			).Scan(&change.after.Version)
+		}
+		if err != nil {
+			return domains.ImportReport{}, err
+		}
+
+		err = recordChange(ctxTimeout, tx, change.characterID(), change.action, change.before, change.after)
+		if err != nil {
+			return domains.ImportReport{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domains.ImportReport{}, err
+	}
+	r.wrote(ctx)
+
+	return report, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func selectAllCharacters(
+	ctx context.Context,
+	q queryer,
+) ([]domains.Character, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "deleted_at" IS NULL ORDER BY "id"`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domains.Character{}
+	for rows.Next() {
+		var character domains.Character
+		if err := rows.Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+		); err != nil {
+			return nil, err
+		}
+
+		results = append(results, character)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+type characterChange struct {
+	action domains.AuditAction
+	before *domains.Character
+	after  *domains.Character
+}
+
+func (c characterChange) characterID() uint {
+	if c.after != nil {
+		return c.after.ID
+	}
+
+	return c.before.ID
+}
+
+func diffCharacters(
+	existing []domains.Character,
+	incoming []domains.Character,
+	mode domains.ImportMode,
+) (domains.ImportReport, []characterChange) {
+	report := domains.ImportReport{
+		Mode:    mode,
+		Created: []string{},
+		Updated: []string{},
+		Deleted: []string{},
+	}
+
+	existingByID := make(map[uint]domains.Character, len(existing))
+	for _, character := range existing {
+		existingByID[character.ID] = character
+	}
+
+	incomingIDs := make(map[uint]struct{}, len(incoming))
+	changes := []characterChange{}
+	for _, character := range incoming {
+		character.Name = strings.ToLower(character.Name)
+		character.DeletedAt = nil
+		incomingIDs[character.ID] = struct{}{}
+
+		current, ok := existingByID[character.ID]
+		switch {
+		case !ok:
+			report.Created = append(report.Created, character.Name)
+			changes = append(changes, characterChange{
+				action: domains.AuditActionInsert,
+				after:  &character,
+			})
+		case current != character:
+			report.Updated = append(report.Updated, character.Name)
+			changes = append(changes, characterChange{
+				action: domains.AuditActionUpdate,
+				before: &current,
+				after:  &character,
+			})
+		default:
+			report.Unchanged++
+		}
+	}
+
+	if mode == domains.ImportModeReplace {
+		for _, character := range existing {
+			if _, ok := incomingIDs[character.ID]; !ok {
+				report.Deleted = append(report.Deleted, character.Name)
+				changes = append(changes, characterChange{
+					action: domains.AuditActionDelete,
+					before: &character,
+				})
+			}
+		}
+	}
+
+	return report, changes
+}
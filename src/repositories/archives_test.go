@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExportCharactersInDatabase(t *testing.T) {
+	t.Run("execute export and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp").
+			AddRow(2, "vegeta", "54.000.000", "Saiyan", "vegeta.webp")
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "deleted_at" IS NULL ORDER BY "id"`),
+		).WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		results, err := repo.ExportCharactersInDatabase(context.Background())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+}
+
+func Test_ImportCharactersInDatabase(t *testing.T) {
+	incoming := []domains.Character{
+		{ID: 1, Name: "Goku", Ki: "90.000.000", Race: "Saiyan", Image: "goku.webp"},
+		{ID: 3, Name: "piccolo", Ki: "2.000.000", Race: "Namekian", Image: "piccolo.webp"},
+	}
+
+	existingRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "ki", "race", "image"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp").
+			AddRow(2, "vegeta", "54.000.000", "Saiyan", "vegeta.webp")
+	}
+
+	t.Run("execute import in dry run and rollback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "deleted_at" IS NULL ORDER BY "id"`),
+		).WillReturnRows(existingRows())
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		report, err := repo.ImportCharactersInDatabase(context.Background(), incoming, domains.ImportModeReplace, true)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, []string{"piccolo"}, report.Created)
+		assert.Equal(t, []string{"goku"}, report.Updated)
+		assert.Equal(t, []string{"vegeta"}, report.Deleted)
+	})
+
+	t.Run("execute import in upsert mode and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "deleted_at" IS NULL ORDER BY "id"`),
+		).WillReturnRows(existingRows())
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "character_dragonball"`)).
+			WithArgs(uint(1), "goku", "90.000.000", "Saiyan", "goku.webp").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(1), "update", "cli:admin", "import", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WithArgs(uint(1), 2, "goku", "90.000.000", "Saiyan", "goku.webp", nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "character_dragonball"`)).
+			WithArgs(uint(3), "piccolo", "2.000.000", "Namekian", "piccolo.webp").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(3), "insert", "cli:admin", "import", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		ctx := domains.WithAuditor(context.Background(), "cli:admin", domains.AuditSourceImport)
+		report, err := repo.ImportCharactersInDatabase(ctx, incoming, domains.ImportModeUpsert, false)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Empty(t, report.Deleted)
+	})
+
+	t.Run("execute import in replace mode and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "deleted_at" IS NULL ORDER BY "id"`),
+		).WillReturnRows(existingRows())
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "character_dragonball"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "character_dragonball"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "id" = $1 AND "deleted_at" IS NULL RETURNING "deleted_at", "version"`)).
+			WithArgs(uint(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"deleted_at", "version"}).AddRow(time.Now(), 4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(2), "delete", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		report, err := repo.ImportCharactersInDatabase(context.Background(), incoming, domains.ImportModeReplace, false)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"vegeta"}, report.Deleted)
+	})
+}
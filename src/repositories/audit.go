@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func (r *CharacterRepository) ListCharacterHistoryInDatabase(
+	ctx context.Context,
+	characterID uint,
+	limit int,
+	offset int,
+) ([]domains.AuditEntry, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	var results []domains.AuditEntry
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		var err error
+		results, err = selectCharacterHistory(ctxTimeout, sqlClient, characterID, limit, offset)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func selectCharacterHistory(
+	ctx context.Context,
+	q queryer,
+	characterID uint,
+	limit int,
+	offset int,
+) ([]domains.AuditEntry, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT "id", "character_id", "action", "actor", "source", "before", "after", "created_at" FROM "character_audit" WHERE "character_id" = $1 ORDER BY "id" DESC LIMIT $2 OFFSET $3`,
+		characterID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domains.AuditEntry{}
+	for rows.Next() {
+		var entry domains.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.CharacterID,
+			&entry.Action,
+			&entry.Actor,
+			&entry.Source,
+			&before,
+			&after,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if before != nil {
+			entry.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			entry.After = json.RawMessage(after)
+		}
+
+		results = append(results, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertAudit must run on the same transaction as the write it records, so
+// the entry and the change are committed or rolled back together.
+func insertAudit(
+	ctx context.Context,
+	e execer,
+	characterID uint,
+	action domains.AuditAction,
+	before *domains.Character,
+	after *domains.Character,
+) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	auditor := domains.AuditorFromContext(ctx)
+	_, err = e.ExecContext(
+		ctx,
+		`INSERT INTO "character_audit" ("character_id", "action", "actor", "source", "before", "after") VALUES ($1, $2, $3, $4, $5, $6)`,
+		characterID,
+		string(action),
+		auditor.Actor,
+		string(auditor.Source),
+		beforeJSON,
+		afterJSON,
+	)
+
+	return err
+}
+
+func auditJSON(character *domains.Character) (interface{}, error) {
+	if character == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(character)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
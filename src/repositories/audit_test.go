@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListCharacterHistoryInDatabase(t *testing.T) {
+	t.Run("execute list and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "character_id", "action", "actor", "source", "before", "after", "created_at"}).
+			AddRow(2, 1, "delete", "ip:127.0.0.1", "api", []byte(`{"id":1}`), []byte(`{"id":1,"deleted_at":"2024-01-01T00:00:00Z"}`), time.Now()).
+			AddRow(1, 1, "insert", "unknown", "api", nil, []byte(`{"id":1}`), time.Now())
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "character_id", "action", "actor", "source", "before", "after", "created_at" FROM "character_audit" WHERE "character_id" = $1 ORDER BY "id" DESC LIMIT $2 OFFSET $3`),
+		).WithArgs(uint(1), 10, 20).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		entries, err := repo.ListCharacterHistoryInDatabase(context.Background(), 1, 10, 20)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, domains.AuditActionDelete, entries[0].Action)
+		assert.Nil(t, entries[1].Before)
+		assert.JSONEq(t, `{"id":1}`, string(entries[1].After))
+	})
+}
@@ -0,0 +1,389 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+// cacheKeyPrefix is bumped whenever cachedLookup changes shape, so replicas
+// running different releases never read each other's entries.
+const cacheKeyPrefix = "character:v2:name:"
+
+const (
+	cachedMissingNotFound = "not_found"
+	cachedMissingDeleted  = "deleted"
+)
+
+type cachedLookup struct {
+	Character domains.Character `json:"character"`
+	Version   int               `json:"version,omitempty"`
+	Missing   string            `json:"missing,omitempty"`
+}
+
+func (l cachedLookup) result() (domains.Character, error) {
+	character := l.Character
+	character.Version = l.Version
+	switch l.Missing {
+	case cachedMissingNotFound:
+		return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+	case cachedMissingDeleted:
+		return character, domains.ErrCharacterIsDeleted
+	}
+
+	return character, nil
+}
+
+// CachedCharacterRepository answers GetCharacterInDatabaseByName from a
+// domains.Cache, which may be shared by every replica. Characters that are
+// missing or deleted are cached too, for the shorter negativeTTL. It wraps the
+// whole store, so every write made through it drops the entries it touches;
+// writes made by other replicas are evicted by HandleCharacterChange. A cache
+// that cannot be reached is logged and skipped.
+//
+// For primaryReadWindow after a name is evicted, or after a flush, its misses
+// are read from the primary: a read replica that has not replayed the write
+// yet would put back the entry the eviction dropped. Every other miss may go
+// to a replica.
+type CachedCharacterRepository struct {
+	domains.CharacterStore
+	cache             domains.Cache
+	ttl               time.Duration
+	negativeTTL       time.Duration
+	primaryReadWindow time.Duration
+	now               func() time.Time
+	hits              atomic.Uint64
+	misses            atomic.Uint64
+
+	mu        sync.Mutex
+	evictedAt map[string]time.Time
+	flushedAt time.Time
+}
+
+func NewCachedCharacterRepository(
+	characterStore domains.CharacterStore,
+	cache domains.Cache,
+	ttl time.Duration,
+	negativeTTL time.Duration,
+	primaryReadWindow time.Duration,
+) *CachedCharacterRepository {
+
+	return &CachedCharacterRepository{
+		CharacterStore:    characterStore,
+		cache:             cache,
+		ttl:               ttl,
+		negativeTTL:       negativeTTL,
+		primaryReadWindow: primaryReadWindow,
+		now:               time.Now,
+		evictedAt:         map[string]time.Time{},
+	}
+}
+
+func (r *CachedCharacterRepository) GetCharacterInDatabaseByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	if lookup, ok := r.get(ctx, name); ok {
+		r.hits.Add(1)
+		return lookup.result()
+	}
+	r.misses.Add(1)
+
+	if r.evictedRecently(name) {
+		ctx = withPrimaryReads(ctx)
+	}
+	character, err := r.CharacterStore.GetCharacterInDatabaseByName(ctx, name)
+	switch {
+	case err == nil:
+		r.set(ctx, name, cachedLookup{Character: character, Version: character.Version}, r.ttl)
+	case errors.Is(err, domains.ErrCharacterNotFoundInDatabase):
+		r.set(ctx, name, cachedLookup{Missing: cachedMissingNotFound}, r.negativeTTL)
+	case errors.Is(err, domains.ErrCharacterIsDeleted):
+		r.set(ctx, name, cachedLookup{Character: character, Version: character.Version, Missing: cachedMissingDeleted}, r.negativeTTL)
+	}
+
+	return character, err
+}
+
+func (r *CachedCharacterRepository) GetCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	character, err := r.CharacterStore.GetCharacterInExternalAPIByName(ctx, name)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	r.invalidate(ctx, name, character.Name)
+	return character, nil
+}
+
+func (r *CachedCharacterRepository) DeleteCharacterInDatabase(
+	ctx context.Context,
+	name string,
+	expectedVersion int,
+) (domains.Character, error) {
+	character, err := r.CharacterStore.DeleteCharacterInDatabase(ctx, name, expectedVersion)
+	r.invalidate(ctx, name)
+
+	return character, err
+}
+
+func (r *CachedCharacterRepository) PatchCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	patch domains.CharacterPatch,
+	expectedVersion int,
+) (domains.Character, error) {
+	before, err := r.CharacterStore.GetCharacterInDatabaseByID(ctx, id)
+	if err == nil {
+		defer r.invalidate(ctx, before.Name)
+	}
+
+	character, err := r.CharacterStore.PatchCharacterInDatabase(ctx, id, patch, expectedVersion)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	r.invalidate(ctx, character.Name)
+	return character, nil
+}
+
+func (r *CachedCharacterRepository) RestoreCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	expectedVersion int,
+) (domains.Character, error) {
+	character, err := r.CharacterStore.RestoreCharacterInDatabase(ctx, id, expectedVersion)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	r.invalidate(ctx, character.Name)
+	return character, nil
+}
+
+func (r *CachedCharacterRepository) PurgeDeletedCharactersInDatabase(
+	ctx context.Context,
+	deletedBefore time.Time,
+) ([]domains.Character, error) {
+	purged, err := r.CharacterStore.PurgeDeletedCharactersInDatabase(ctx, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, characterNames(purged)...)
+	return purged, nil
+}
+
+// ImportCharactersInDatabase also drops the names the characters had before
+// the import, since the report only lists the names they end up with.
+func (r *CachedCharacterRepository) ImportCharactersInDatabase(
+	ctx context.Context,
+	characters []domains.Character,
+	mode domains.ImportMode,
+	dryRun bool,
+) (domains.ImportReport, error) {
+	if dryRun {
+		return r.CharacterStore.ImportCharactersInDatabase(ctx, characters, mode, dryRun)
+	}
+
+	before, err := r.CharacterStore.ExportCharactersInDatabase(ctx)
+	if err == nil {
+		defer r.invalidate(ctx, characterNames(before)...)
+	}
+
+	report, err := r.CharacterStore.ImportCharactersInDatabase(ctx, characters, mode, dryRun)
+	if err != nil {
+		return domains.ImportReport{}, err
+	}
+
+	r.invalidate(ctx, slices.Concat(report.Created, report.Updated, report.Deleted)...)
+	return report, nil
+}
+
+func (r *CachedCharacterRepository) SyncCharacterInDatabase(
+	ctx context.Context,
+	upstream domains.Character,
+	resetOverrides bool,
+) (domains.Character, error) {
+	before, err := r.CharacterStore.GetCharacterInDatabaseByID(ctx, upstream.ID)
+	if err == nil {
+		defer r.invalidate(ctx, before.Name)
+	}
+
+	character, err := r.CharacterStore.SyncCharacterInDatabase(ctx, upstream, resetOverrides)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	r.invalidate(ctx, character.Name)
+	return character, nil
+}
+
+func (r *CachedCharacterRepository) SyncCharactersInDatabase(
+	ctx context.Context,
+	upstreams []domains.Character,
+	resetOverrides bool,
+) ([]domains.Character, error) {
+	before, err := r.CharacterStore.ExportCharactersInDatabase(ctx)
+	if err == nil {
+		defer r.invalidate(ctx, characterNames(before)...)
+	}
+
+	characters, err := r.CharacterStore.SyncCharactersInDatabase(ctx, upstreams, resetOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, characterNames(characters)...)
+	return characters, nil
+}
+
+// HandleCharacterChange evicts the names of a write announced by another
+// replica.
+func (r *CachedCharacterRepository) HandleCharacterChange(
+	ctx context.Context,
+	change domains.CharacterChange,
+) {
+	r.invalidate(ctx, change.Names...)
+}
+
+// Flush drops every entry of a cache local to the process, once changes may
+// have been missed. A shared cache is left alone: the other replicas keep
+// evicting from it and its entries expire with their TTL.
+func (r *CachedCharacterRepository) Flush(ctx context.Context) {
+	if cache, ok := r.cache.(interface{ Clear() }); ok {
+		cache.Clear()
+		domains.LoggerFromContext(ctx).Info("flushed the cached characters")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushedAt = r.now()
+}
+
+// Stats reports size and evictions only for caches that track them; a
+// shared cache is sized and evicted by its server.
+func (r *CachedCharacterRepository) Stats() domains.CacheStats {
+	stats := domains.CacheStats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+	}
+	if cache, ok := r.cache.(interface{ Len() int }); ok {
+		stats.Size = cache.Len()
+	}
+	if cache, ok := r.cache.(interface{ Evictions() uint64 }); ok {
+		stats.Evictions = cache.Evictions()
+	}
+
+	return stats
+}
+
+func (r *CachedCharacterRepository) get(
+	ctx context.Context,
+	name string,
+) (cachedLookup, bool) {
+	value, ok, err := r.cache.Get(ctx, cacheKey(name))
+	if err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute cache.Get", "character", name, "err", err)
+		return cachedLookup{}, false
+	}
+	if !ok {
+		return cachedLookup{}, false
+	}
+
+	var lookup cachedLookup
+	if err := json.Unmarshal(value, &lookup); err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute json.Unmarshal of a cached character", "character", name, "err", err)
+		return cachedLookup{}, false
+	}
+
+	return lookup, true
+}
+
+func (r *CachedCharacterRepository) set(
+	ctx context.Context,
+	name string,
+	lookup cachedLookup,
+	ttl time.Duration,
+) {
+	value, err := json.Marshal(lookup)
+	if err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute json.Marshal of a cached character", "character", name, "err", err)
+		return
+	}
+
+	if err := r.cache.Set(ctx, cacheKey(name), value, ttl); err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute cache.Set", "character", name, "err", err)
+	}
+}
+
+func (r *CachedCharacterRepository) invalidate(
+	ctx context.Context,
+	names ...string,
+) {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, cacheKey(name))
+	}
+
+	if err := r.cache.Delete(ctx, keys...); err != nil {
+		domains.LoggerFromContext(ctx).Warn("error when execute cache.Delete", "characters", names, "err", err)
+	}
+	r.evicted(keys)
+}
+
+// evicted records when keys were evicted, and forgets the keys evicted
+// longer than primaryReadWindow ago.
+func (r *CachedCharacterRepository) evicted(keys []string) {
+	if r.primaryReadWindow <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for key, evictedAt := range r.evictedAt {
+		if now.Sub(evictedAt) >= r.primaryReadWindow {
+			delete(r.evictedAt, key)
+		}
+	}
+	for _, key := range keys {
+		r.evictedAt[key] = now
+	}
+}
+
+func (r *CachedCharacterRepository) evictedRecently(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.flushedAt) < r.primaryReadWindow {
+		return true
+	}
+	evictedAt, ok := r.evictedAt[cacheKey(name)]
+	return ok && now.Sub(evictedAt) < r.primaryReadWindow
+}
+
+func characterNames(characters []domains.Character) []string {
+	names := make([]string, 0, len(characters))
+	for _, character := range characters {
+		names = append(names, character.Name)
+	}
+
+	return names
+}
+
+// cacheKey folds the case of name, as the lookup it caches does.
+func cacheKey(name string) string {
+	return cacheKeyPrefix + strings.ToLower(name)
+}
@@ -0,0 +1,380 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/encilab/dragon-ball/src/caches"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CachedCharacterRepository(t *testing.T) {
+	goku := domains.Character{ID: 1, Name: "goku", Ki: "60.000.000", Race: "Saiyan", Image: "goku.webp", Version: 1}
+
+	t.Run("execute the same lookup twice and hit the database once", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Second, 0)
+
+		for i := 0; i < 2; i++ {
+			character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+			require.NoError(t, err)
+			assert.Equal(t, goku, character)
+		}
+		assert.Equal(t, domains.CacheStats{Hits: 1, Misses: 1, Size: 1}, repo.Stats())
+	})
+
+	t.Run("execute a lookup of a missing character and cache it until the negative ttl", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Twice()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, 20*time.Millisecond, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+			assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		assert.Equal(t, uint64(2), repo.Stats().Misses)
+	})
+
+	t.Run("execute the lookup of a deleted character twice and answer it with the error both times", func(t *testing.T) {
+		deletedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		deleted := goku
+		deleted.DeletedAt = &deletedAt
+		deleted.Version = 2
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(deleted, domains.ErrCharacterIsDeleted).Once()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		for i := 0; i < 2; i++ {
+			character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+			assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+			assert.Equal(t, 2, character.Version)
+			assert.True(t, deletedAt.Equal(*character.DeletedAt))
+		}
+	})
+
+	t.Run("execute a lookup that fails unexpectedly and do not cache it", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, errors.New("any error")).Twice()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		for i := 0; i < 2; i++ {
+			_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("execute a delete and drop the cached character", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, "goku", domains.AnyVersion).Return(goku, nil)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterIsDeleted).Once()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = repo.DeleteCharacterInDatabase(context.Background(), "goku", domains.AnyVersion)
+		require.NoError(t, err)
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+	})
+
+	t.Run("execute a patch that renames and drop the old and the new name", func(t *testing.T) {
+		name := "kakarot"
+		renamed := goku
+		renamed.Name = name
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "kakarot").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "kakarot").Return(renamed, nil).Once()
+
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+		characterEditRepoMock.On("PatchCharacterInDatabase", mock.Anything, uint(1), domains.CharacterPatch{Name: &name}, 1).Return(renamed, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterEditRepository: characterEditRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, _ = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		_, _ = repo.GetCharacterInDatabaseByName(context.Background(), "kakarot")
+		_, err := repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Name: &name}, 1)
+		require.NoError(t, err)
+
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		character, err := repo.GetCharacterInDatabaseByName(context.Background(), "kakarot")
+		require.NoError(t, err)
+		assert.Equal(t, renamed, character)
+	})
+
+	t.Run("execute a restore and drop the cached deleted character", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterIsDeleted).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("RestoreCharacterInDatabase", mock.Anything, uint(1), domains.AnyVersion).Return(goku, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterTrashRepository: characterTrashRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+		_, err = repo.RestoreCharacterInDatabase(context.Background(), 1, domains.AnyVersion)
+		require.NoError(t, err)
+
+		character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		assert.Equal(t, goku, character)
+	})
+
+	t.Run("execute a purge and drop the purged characters", func(t *testing.T) {
+		deletedBefore := time.Now()
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterIsDeleted).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Once()
+
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("PurgeDeletedCharactersInDatabase", mock.Anything, deletedBefore).Return([]domains.Character{goku}, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterTrashRepository: characterTrashRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+		_, err = repo.PurgeDeletedCharactersInDatabase(context.Background(), deletedBefore)
+		require.NoError(t, err)
+
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+
+	t.Run("execute an import that renames and drop the old and the new name", func(t *testing.T) {
+		renamed := goku
+		renamed.Name = "kakarot"
+		renamed.Version = 2
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Once()
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return([]domains.Character{goku}, nil)
+		characterArchiveRepoMock.On("ImportCharactersInDatabase", mock.Anything, []domains.Character{renamed}, domains.ImportModeUpsert, false).
+			Return(domains.ImportReport{Mode: domains.ImportModeUpsert, Updated: []string{"kakarot"}}, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterArchiveRepository: characterArchiveRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = repo.ImportCharactersInDatabase(context.Background(), []domains.Character{renamed}, domains.ImportModeUpsert, false)
+		require.NoError(t, err)
+
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+
+	t.Run("execute a dry run import and keep the cached characters", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ImportCharactersInDatabase", mock.Anything, []domains.Character{goku}, domains.ImportModeReplace, true).
+			Return(domains.ImportReport{Mode: domains.ImportModeReplace, DryRun: true, Unchanged: 1}, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterArchiveRepository: characterArchiveRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = repo.ImportCharactersInDatabase(context.Background(), []domains.Character{goku}, domains.ImportModeReplace, true)
+		require.NoError(t, err)
+
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), repo.Stats().Hits)
+	})
+
+	t.Run("execute a sync and drop the synced character", func(t *testing.T) {
+		synced := goku
+		synced.Ki = "90.000.000"
+		synced.Version = 2
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, uint(1)).Return(goku, nil)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(synced, nil).Once()
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("SyncCharacterInDatabase", mock.Anything, synced, false).Return(synced, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock, CharacterSyncRepository: characterSyncRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = repo.SyncCharacterInDatabase(context.Background(), synced, false)
+		require.NoError(t, err)
+
+		character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		assert.Equal(t, synced, character)
+	})
+
+	t.Run("execute more names than its size and evict the oldest", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, mock.Anything).Return(goku, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(2), time.Minute, time.Minute, 0)
+
+		for _, name := range []string{"goku", "vegeta", "krillin", "goku"} {
+			_, err := repo.GetCharacterInDatabaseByName(context.Background(), name)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, domains.CacheStats{Misses: 4, Evictions: 2, Size: 2}, repo.Stats())
+	})
+
+	t.Run("execute concurrent lookups and count every one of them", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil)
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+				assert.NoError(t, err)
+				assert.Equal(t, goku, character)
+			}()
+		}
+		wg.Wait()
+
+		stats := repo.Stats()
+		assert.Equal(t, uint64(50), stats.Hits+stats.Misses)
+		assert.GreaterOrEqual(t, stats.Misses, uint64(1))
+	})
+
+	t.Run("execute lookups from two replicas sharing a redis cache and hit the database once", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "krillin").Return(domains.Character{}, domains.ErrCharacterIsDeleted).Once()
+
+		first := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewRedis(client, "dragon-ball:"), time.Minute, time.Minute, 0)
+		second := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewRedis(client, "dragon-ball:"), time.Minute, time.Minute, 0)
+
+		_, err := first.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = first.GetCharacterInDatabaseByName(context.Background(), "krillin")
+		require.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+
+		character, err := second.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		assert.Equal(t, goku, character)
+		_, err = second.GetCharacterInDatabaseByName(context.Background(), "krillin")
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+		assert.Equal(t, domains.CacheStats{Hits: 2}, second.Stats())
+	})
+
+	t.Run("execute a lookup while the cache is down and fall through to the database", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil)
+
+		cacheMock := mocks.NewCache(t)
+		cacheMock.On("Get", mock.Anything, mock.Anything).Return(nil, false, errors.New("connection refused"))
+		cacheMock.On("Set", mock.Anything, mock.Anything, mock.Anything, time.Minute).Return(errors.New("connection refused"))
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, cacheMock, time.Minute, time.Minute, 0)
+
+		character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		assert.Equal(t, goku, character)
+	})
+
+	t.Run("execute a flush and drop every cached character", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Twice()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		repo.Flush(context.Background())
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+
+		assert.Equal(t, domains.CacheStats{Misses: 2, Size: 1}, repo.Stats())
+	})
+
+	t.Run("execute a change announced by another replica and drop the cached character", func(t *testing.T) {
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(domains.Character{}, domains.ErrCharacterIsDeleted).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, "goku").Return(goku, nil).Once()
+
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 0)
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+
+		repo.HandleCharacterChange(context.Background(), domains.NewCharacterChange(1, domains.AuditActionRestore, &goku, &goku))
+
+		character, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		assert.Equal(t, goku, character)
+	})
+	t.Run("execute lookups missed after an eviction and read them from the primary within the window", func(t *testing.T) {
+		fromPrimary := mock.MatchedBy(func(ctx context.Context) bool {
+			primary, _ := ctx.Value(primaryReadsKey{}).(bool)
+			return primary
+		})
+		fromAnyReplica := mock.MatchedBy(func(ctx context.Context) bool {
+			primary, _ := ctx.Value(primaryReadsKey{}).(bool)
+			return !primary
+		})
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInDatabaseByName", fromAnyReplica, "goku").Return(goku, nil).Once()
+		characterRepoMock.On("GetCharacterInDatabaseByName", fromPrimary, "goku").Return(goku, nil).Twice()
+		characterRepoMock.On("GetCharacterInDatabaseByName", fromAnyReplica, "vegeta").Return(domains.Character{}, domains.ErrCharacterNotFoundInDatabase).Once()
+
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		repo := NewCachedCharacterRepository(&mockCharacterStore{CharacterRepository: characterRepoMock}, caches.NewMemory(10), time.Minute, time.Minute, 5*time.Second)
+		repo.now = func() time.Time { return now }
+
+		_, err := repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+
+		repo.HandleCharacterChange(context.Background(), domains.NewCharacterChange(1, domains.AuditActionUpdate, &goku, &goku))
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "vegeta")
+		require.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+
+		now = now.Add(time.Second)
+		repo.Flush(context.Background())
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), "goku")
+		require.NoError(t, err)
+	})
+}
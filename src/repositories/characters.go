@@ -4,71 +4,163 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// CharacterRepository runs the same SQL on Postgres and SQLite through
+// sqlClient. On Postgres sqlClient is a view over pool, which the statements
+// only pgx can run, such as COPY, use directly, and the reads may go to
+// readReplicas instead.
 type CharacterRepository struct {
-	sqlClient     *sql.DB
-	clientTimeout time.Duration
+	sqlClient      *sql.DB
+	pool           *pgxpool.Pool
+	readReplicas   *ReadReplicas
+	clientTimeout  time.Duration
+	externalAPIURL string
+	dialect        dialect
 }
 
 func NewCharacterRepository(
+	pool *pgxpool.Pool,
+	clientTimeout time.Duration,
+) *CharacterRepository {
+	r := newCharacterRepository(stdlib.OpenDBFromPool(pool), clientTimeout, postgresDialect)
+	r.pool = pool
+
+	return r
+}
+
+// NewReplicatedCharacterRepository writes to the primary behind pool and
+// reads from readReplicas.
+func NewReplicatedCharacterRepository(
+	pool *pgxpool.Pool,
+	readReplicas *ReadReplicas,
+	clientTimeout time.Duration,
+) *CharacterRepository {
+	r := NewCharacterRepository(pool, clientTimeout)
+	r.readReplicas = readReplicas
+
+	return r
+}
+
+func newCharacterRepository(
 	sqlClient *sql.DB,
 	clientTimeout time.Duration,
+	d dialect,
 ) *CharacterRepository {
 
 	return &CharacterRepository{
-		sqlClient:     sqlClient,
-		clientTimeout: clientTimeout,
+		sqlClient:      sqlClient,
+		clientTimeout:  clientTimeout,
+		externalAPIURL: defaultExternalAPIURL,
+		dialect:        d,
 	}
 }
 
-func (r *CharacterRepository) GetCharacterInExternalAPIByName(
-	ctx context.Context,
-	name string,
-) (domains.Character, error) {
-	client := &http.Client{
-		Timeout: r.clientTimeout,
+func (r *CharacterRepository) PingContext(ctx context.Context) error {
+	return r.sqlClient.PingContext(ctx)
+}
+
+// SQLClients names the connection pools of the repository: primary and,
+// with read replicas, replica-0, replica-1 and so on.
+func (r *CharacterRepository) SQLClients() map[string]*sql.DB {
+	sqlClients := map[string]*sql.DB{"primary": r.sqlClient}
+	if r.readReplicas != nil {
+		for i, replica := range r.readReplicas.replicas {
+			sqlClients[fmt.Sprintf("replica-%d", i)] = replica.sqlClient
+		}
 	}
-	url := fmt.Sprintf("https://dragonball-api.com/api/characters?name=%s", name)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	return sqlClients
+}
+
+// SchemaVersion is the version of conf/init.sql and sqlite.sql the
+// repositories are written against.
+const SchemaVersion = 2
+
+// SchemaVersion answers the version recorded by the last run of the schema
+// script on the primary.
+func (r *CharacterRepository) SchemaVersion(ctx context.Context) (int, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	var version int
+	err := r.sqlClient.QueryRowContext(
+		ctxTimeout,
+		`SELECT COALESCE(MAX("version"), 0) FROM "schema_version"`,
+	).Scan(&version)
 	if err != nil {
-		return domains.Character{}, fmt.Errorf("failed to create request: %w", err)
+		return 0, err
 	}
 
-	resp, err := client.Do(req)
+	return version, nil
+}
+
+func (r *CharacterRepository) PingExternalAPI(ctx context.Context) error {
+	return pingExternalAPI(ctx, r.externalAPIURL, r.clientTimeout)
+}
+
+func (r *CharacterRepository) GetCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	character, err := r.FetchCharacterInExternalAPIByName(ctx, name)
 	if err != nil {
-		return domains.Character{}, fmt.Errorf("failed to perform request: %w", err)
+		return domains.Character{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return domains.Character{}, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
+	character.Version, err = r.setCharacterInDatabase(ctx, character.ID, character.Name, character.Ki, character.Race, character.Image)
+	if errors.Is(err, domains.ErrCharacterAlreadyExistInDatabase) {
+		return storedCharacterByID(withPrimaryReads(ctx), r, character.ID)
 	}
-
-	var characters []domains.Character
-	if err := json.NewDecoder(resp.Body).Decode(&characters); err != nil {
-		return domains.Character{}, fmt.Errorf("failed to decode response: %w", err)
+	if err != nil {
+		return domains.Character{}, err
 	}
 
-	if len(characters) == 0 {
-		return domains.Character{}, domains.ErrCharacterNotFoundInExternalAPI
-	}
+	return character, nil
+}
 
-	characters[0].Name = strings.ToLower(characters[0].Name)
-	err = r.setCharacterInDatabase(ctx, characters[0].ID, characters[0].Name, characters[0].Ki, characters[0].Race, characters[0].Image)
+// storedCharacterByID answers the character a lookup by name found upstream but
+// could not store because its id is: renamed since it was fetched, or stored
+// by a concurrent lookup. A deleted one answers ErrCharacterIsDeleted, as the
+// lookup by name in the database does.
+func storedCharacterByID(
+	ctx context.Context,
+	characterRepository domains.CharacterRepository,
+	id uint,
+) (domains.Character, error) {
+	character, err := characterRepository.GetCharacterInDatabaseByID(ctx, id)
 	if err != nil {
 		return domains.Character{}, err
 	}
+	if character.DeletedAt != nil {
+		return domains.Character{}, domains.ErrCharacterIsDeleted
+	}
 
-	return characters[0], nil
+	return character, nil
+}
+
+func (r *CharacterRepository) FetchCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	return fetchCharacterInExternalAPIByName(ctx, r.externalAPIURL, r.clientTimeout, name)
+}
+
+func (r *CharacterRepository) FetchCharacterInExternalAPIByID(
+	ctx context.Context,
+	id uint,
+) (domains.Character, error) {
+	return fetchCharacterInExternalAPIByID(ctx, r.externalAPIURL, r.clientTimeout, id)
 }
 
 func (r *CharacterRepository) setCharacterInDatabase(
@@ -78,19 +170,19 @@ func (r *CharacterRepository) setCharacterInDatabase(
 	ki,
 	race,
 	image string,
-) error {
+) (int, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
 	defer cancel()
 
 	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				log.Println(rbErr)
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
 			}
 		}
 	}()
@@ -98,7 +190,9 @@ func (r *CharacterRepository) setCharacterInDatabase(
 	var query string
 	var args []interface{}
 
-	query = `INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image") VALUES ($1, $2, $3, $4, $5)`
+	// A character purged and stored again carries on from the versions it
+	// had, which the purge keeps.
+	query = `INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image", "version") VALUES ($1, $2, $3, $4, $5, ` + nextVersion + `) RETURNING "version"`
 	args = []interface{}{
 		id,
 		strings.ToLower(name),
@@ -107,19 +201,33 @@ func (r *CharacterRepository) setCharacterInDatabase(
 		image,
 	}
 
-	_, err = tx.ExecContext(ctxTimeout, query, args...)
+	var version int
+	err = tx.QueryRowContext(ctxTimeout, query, args...).Scan(&version)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		if r.dialect.isUniqueViolation(err) {
 			err = domains.ErrCharacterAlreadyExistInDatabase
 		}
-		return err
+		return 0, err
+	}
+
+	err = recordChange(ctxTimeout, tx, id, domains.AuditActionInsert, nil, &domains.Character{
+		ID:      id,
+		Name:    strings.ToLower(name),
+		Ki:      ki,
+		Race:    race,
+		Image:   image,
+		Version: version,
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return 0, err
 	}
+	r.wrote(ctx)
 
-	return nil
+	return version, nil
 }
 
 func (r *CharacterRepository) GetCharacterInDatabaseByName(
@@ -130,17 +238,60 @@ func (r *CharacterRepository) GetCharacterInDatabaseByName(
 	defer cancel()
 
 	var character domains.Character
-	err := r.sqlClient.QueryRowContext(
-		ctxTimeout,
-		`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "name" = $1`,
-		name,
-	).Scan(
-		&character.ID,
-		&character.Name,
-		&character.Ki,
-		&character.Race,
-		&character.Image,
-	)
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		return sqlClient.QueryRowContext(
+			ctxTimeout,
+			`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "name" = $1`,
+			strings.ToLower(name),
+		).Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+			&character.DeletedAt,
+			&character.Version,
+		)
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+		}
+		return domains.Character{}, err
+	}
+
+	if character.DeletedAt != nil {
+		return character, domains.ErrCharacterIsDeleted
+	}
+
+	return character, nil
+}
+
+func (r *CharacterRepository) GetCharacterInDatabaseByID(
+	ctx context.Context,
+	id uint,
+) (domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	var character domains.Character
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		return sqlClient.QueryRowContext(
+			ctxTimeout,
+			`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at" FROM "character_dragonball" WHERE "id" = $1`,
+			id,
+		).Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+			&character.DeletedAt,
+			&character.Version,
+			&character.UpdatedAt,
+		)
+	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -155,15 +306,38 @@ func (r *CharacterRepository) GetCharacterInDatabaseByName(
 func (r *CharacterRepository) SearchCharactersInDatabase(
 	ctx context.Context,
 	limit int,
+	includeDeleted bool,
 ) ([]domains.Character, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
 	defer cancel()
 
-	query := `SELECT id, name, ki, race, image FROM "character_dragonball"`
+	var results []domains.Character
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		var err error
+		results, err = searchCharacters(ctxTimeout, sqlClient, limit, includeDeleted)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func searchCharacters(
+	ctx context.Context,
+	q queryer,
+	limit int,
+	includeDeleted bool,
+) ([]domains.Character, error) {
+	query := `SELECT id, name, ki, race, image, deleted_at, updated_at FROM "character_dragonball"`
 	var args []interface{}
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
 	query += " ORDER BY id"
 
-	rows, err := r.sqlClient.QueryContext(ctxTimeout, query, args...)
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +356,8 @@ func (r *CharacterRepository) SearchCharactersInDatabase(
 			&character.Ki,
 			&character.Race,
 			&character.Image,
+			&character.DeletedAt,
+			&character.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -199,42 +375,196 @@ func (r *CharacterRepository) SearchCharactersInDatabase(
 func (r *CharacterRepository) DeleteCharacterInDatabase(
 	ctx context.Context,
 	name string,
-) error {
+	expectedVersion int,
+) (domains.Character, error) {
 	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
 	defer cancel()
 
 	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
 	if err != nil {
-		return err
+		return domains.Character{}, err
 	}
 	defer func() {
 		if err != nil {
-			if err := tx.Rollback(); err != nil {
-				log.Println(err)
-			}
-		} else {
-			if err := tx.Commit(); err != nil {
-				log.Println(err)
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
 			}
 		}
 	}()
 
-	result, err := tx.ExecContext(
+	var character domains.Character
+	err = tx.QueryRowContext(
 		ctxTimeout,
-		`DELETE FROM "character_dragonball" WHERE "name" = $1`,
+		`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "name" = $1 AND "deleted_at" IS NULL AND ($2 = 0 OR "version" = $2) RETURNING "id", "name", "ki", "race", "image", "deleted_at", "version"`,
 		strings.ToLower(name),
+		expectedVersion,
+	).Scan(
+		&character.ID,
+		&character.Name,
+		&character.Ki,
+		&character.Race,
+		&character.Image,
+		&character.DeletedAt,
+		&character.Version,
 	)
 	if err != nil {
-		return err
+		if err == sql.ErrNoRows {
+			err = domains.ErrCharacterNotFoundInDatabase
+			if expectedVersion != domains.AnyVersion {
+				err = characterMissingOrChanged(ctxTimeout, tx, name)
+			}
+		}
+		return domains.Character{}, err
 	}
 
-	rowAffected, err := result.RowsAffected()
+	before := character
+	before.DeletedAt = nil
+	before.Version--
+	err = recordChange(ctxTimeout, tx, character.ID, domains.AuditActionDelete, &before, &character)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domains.Character{}, err
+	}
+	r.wrote(ctx)
+
+	return character, nil
+}
+
+// characterMissingOrChanged tells apart the two reasons a compare-and-swap
+// delete by name matches no row.
+func characterMissingOrChanged(
+	ctx context.Context,
+	tx *sql.Tx,
+	name string,
+) error {
+	var version int
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT "version" FROM "character_dragonball" WHERE "name" = $1 AND "deleted_at" IS NULL`,
+		strings.ToLower(name),
+	).Scan(&version)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.ErrCharacterNotFoundInDatabase
+		}
 		return err
 	}
-	if rowAffected == 0 {
-		return domains.ErrCharacterNotDeleted
+
+	return domains.ErrCharacterVersionMismatch
+}
+
+const defaultExternalAPIURL = "https://dragonball-api.com"
+
+// pingExternalAPI asks the external API for one character; any answer but a
+// server error means it can be reached.
+func pingExternalAPI(
+	ctx context.Context,
+	externalAPIURL string,
+	clientTimeout time.Duration,
+) error {
+	client := &http.Client{
+		Timeout:   clientTimeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, externalAPIURL+"/api/characters?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("received server error status code: %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+func fetchCharacterInExternalAPIByName(
+	ctx context.Context,
+	externalAPIURL string,
+	clientTimeout time.Duration,
+	name string,
+) (domains.Character, error) {
+	client := &http.Client{
+		Timeout:   clientTimeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+	url := fmt.Sprintf("%s/api/characters?name=%s", externalAPIURL, name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return domains.Character{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return domains.Character{}, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return domains.Character{}, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
+	}
+
+	var characters []domains.Character
+	if err := json.NewDecoder(resp.Body).Decode(&characters); err != nil {
+		return domains.Character{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(characters) == 0 {
+		return domains.Character{}, domains.ErrCharacterNotFoundInExternalAPI
+	}
+
+	characters[0].Name = strings.ToLower(characters[0].Name)
+
+	return characters[0], nil
+}
+
+func fetchCharacterInExternalAPIByID(
+	ctx context.Context,
+	externalAPIURL string,
+	clientTimeout time.Duration,
+	id uint,
+) (domains.Character, error) {
+	client := &http.Client{
+		Timeout:   clientTimeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+	url := fmt.Sprintf("%s/api/characters/%d", externalAPIURL, id)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return domains.Character{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return domains.Character{}, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return domains.Character{}, domains.ErrCharacterNotFoundInExternalAPI
+	}
+	if resp.StatusCode != http.StatusOK {
+		return domains.Character{}, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
+	}
+
+	var character domains.Character
+	if err := json.NewDecoder(resp.Body).Decode(&character); err != nil {
+		return domains.Character{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	character.Name = strings.ToLower(character.Name)
+
+	return character, nil
+}
@@ -2,12 +2,17 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,25 +25,98 @@ func Test_GetCharacterInExternalAPIByName(t *testing.T) {
 		race := "Saiyan"
 		image := "https://dragonball-api.com/characters/goku_normal.webp"
 
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/characters", r.URL.Path)
+			assert.Equal(t, name, r.URL.Query().Get("name"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id":1,"name":"Goku","ki":"60.000.000","race":"Saiyan","image":"https://dragonball-api.com/characters/goku_normal.webp"}]`))
+		}))
+		defer server.Close()
+
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
 		require.NotNil(t, db)
 		require.NotNil(t, mock)
 
 		mock.ExpectBegin()
-		mock.ExpectExec(
-			regexp.QuoteMeta(`INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image") VALUES ($1, $2, $3, $4, $5)`)).
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image", "version") VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX("version"), 0) + 1 FROM "character_version" WHERE "character_id" = $1)) RETURNING "version"`)).
 			WithArgs(id, name, ki, race, image).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		mock.ExpectExec(
+			regexp.QuoteMeta(`INSERT INTO "character_audit" ("character_id", "action", "actor", "source", "before", "after") VALUES ($1, $2, $3, $4, $5, $6)`)).
+			WithArgs(id, "insert", "unknown", "api", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version" ("character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from") VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())`)).
+			WithArgs(id, 3, name, ki, race, image, nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
 
-		repo := NewCharacterRepository(db, 2000*time.Millisecond)
+		repo := newCharacterRepository(db, 2000*time.Millisecond, postgresDialect)
+		repo.externalAPIURL = server.URL
 		characterDomain, err := repo.GetCharacterInExternalAPIByName(context.Background(), name)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, err)
 
 		assert.Equal(t, name, strings.ToLower(characterDomain.Name))
+		assert.Equal(t, 3, characterDomain.Version)
+	})
+
+	t.Run("execute get of a character stored under another name and return the stored one", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id":1,"name":"Goku","ki":"60.000.000","race":"Saiyan","image":"goku.webp"}]`))
+		}))
+		defer server.Close()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "character_dragonball"`)).
+			WithArgs(uint(1), "goku", "60.000.000", "Saiyan", "goku.webp").
+			WillReturnError(&pgconn.PgError{Code: pgUniqueViolation, Message: "duplicate key value violates unique constraint"})
+		mock.ExpectRollback()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at" FROM "character_dragonball" WHERE "id" = $1`),
+		).WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at"}).
+				AddRow(1, "kakarot", "60.000.000", "Saiyan", "goku.webp", nil, 2, time.Now()))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		repo.externalAPIURL = server.URL
+		characterDomain, err := repo.GetCharacterInExternalAPIByName(context.Background(), "goku")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, "kakarot", characterDomain.Name)
+		assert.Equal(t, 2, characterDomain.Version)
+	})
+}
+
+func Test_SetCharacterInDatabase(t *testing.T) {
+	t.Run("execute insert of a stored character and return ErrCharacterAlreadyExistInDatabase", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`INSERT INTO "character_dragonball" ("id", "name", "ki", "race", "image", "version") VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX("version"), 0) + 1 FROM "character_version" WHERE "character_id" = $1)) RETURNING "version"`)).
+			WithArgs(uint(1), "goku", "60.000.000", "Saiyan", "goku.webp").
+			WillReturnError(&pgconn.PgError{Code: pgUniqueViolation, Message: "duplicate key value violates unique constraint"})
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.setCharacterInDatabase(context.Background(), 1, "Goku", "60.000.000", "Saiyan", "goku.webp")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterAlreadyExistInDatabase)
 	})
 }
 
@@ -56,17 +134,17 @@ func Test_GetCharacterInDatabaseByName(t *testing.T) {
 		require.NotNil(t, mock)
 
 		rows := sqlmock.NewRows([]string{
-			"id", "name", "ki", "race", "image",
+			"id", "name", "ki", "race", "image", "deleted_at", "version",
 		}).AddRow(
-			id, name, ki, race, image,
+			id, name, ki, race, image, nil, 3,
 		)
 
 		mock.ExpectQuery(
-			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image" FROM "character_dragonball" WHERE "name" = $1`),
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "name" = $1`),
 		).WithArgs(name).
 			WillReturnRows(rows)
 
-		repo := NewCharacterRepository(db, 1000*time.Millisecond)
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
 
 		auditDomain, err := repo.GetCharacterInDatabaseByName(context.Background(), name)
 
@@ -78,6 +156,60 @@ func Test_GetCharacterInDatabaseByName(t *testing.T) {
 		assert.Equal(t, ki, auditDomain.Ki)
 		assert.Equal(t, race, auditDomain.Race)
 		assert.Equal(t, image, auditDomain.Image)
+		assert.Equal(t, 3, auditDomain.Version)
+	})
+
+	t.Run("execute get of a deleted character and return ErrCharacterIsDeleted", func(t *testing.T) {
+		name := "goku"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		require.NotNil(t, db)
+		require.NotNil(t, mock)
+
+		rows := sqlmock.NewRows([]string{
+			"id", "name", "ki", "race", "image", "deleted_at", "version",
+		}).AddRow(
+			1, name, "60.000.000", "Saiyan", "goku.webp", time.Now(), 2,
+		)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "name" = $1`),
+		).WithArgs(name).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.GetCharacterInDatabaseByName(context.Background(), name)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+	})
+
+}
+
+func Test_GetCharacterInDatabaseByID(t *testing.T) {
+
+	t.Run("execute get by id and success", func(t *testing.T) {
+		updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", nil, 3, updatedAt)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at" FROM "character_dragonball" WHERE "id" = $1`),
+		).WithArgs(uint(1)).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.GetCharacterInDatabaseByID(context.Background(), 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, character.Version)
+		assert.Equal(t, updatedAt, character.UpdatedAt)
 	})
 
 }
@@ -98,15 +230,15 @@ func Test_SearchCharactersInDatabase(t *testing.T) {
 		require.NotNil(t, db)
 		require.NotNil(t, mock)
 
-		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image"}).
-			AddRow(id, name, ki, race, image)
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "updated_at"}).
+			AddRow(id, name, ki, race, image, nil, time.Now())
 
 		mock.ExpectQuery(
-			regexp.QuoteMeta(`SELECT id, name, ki, race, image FROM "character_dragonball" ORDER BY id`),
+			regexp.QuoteMeta(`SELECT id, name, ki, race, image, deleted_at, updated_at FROM "character_dragonball" WHERE deleted_at IS NULL ORDER BY id`),
 		).WillReturnRows(rows)
 
-		repo := NewCharacterRepository(db, 1000*time.Millisecond)
-		results, err := repo.SearchCharactersInDatabase(context.Background(), limit)
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		results, err := repo.SearchCharactersInDatabase(context.Background(), limit, false)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, err)
@@ -118,25 +250,184 @@ func Test_SearchCharactersInDatabase(t *testing.T) {
 func Test_DeleteCharacterInDatabase(t *testing.T) {
 
 	t.Run("execute delete and success", func(t *testing.T) {
+		id := uint(1)
 		name := "goku"
+		ki := "60.000.000"
+		race := "Saiyan"
+		image := "https://dragonball-api.com/characters/goku_normal.webp"
 
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
 		require.NotNil(t, db)
 		require.NotNil(t, mock)
 
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}).
+			AddRow(id, name, ki, race, image, time.Now(), 4)
+
 		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "name" = $1 AND "deleted_at" IS NULL AND ($2 = 0 OR "version" = $2) RETURNING "id", "name", "ki", "race", "image", "deleted_at", "version"`),
+		).WithArgs(name, 3).
+			WillReturnRows(rows)
 		mock.ExpectExec(
-			regexp.QuoteMeta(`DELETE FROM "character_dragonball" WHERE "name" = $1`),
-		).WithArgs(name).
+			regexp.QuoteMeta(`INSERT INTO "character_audit" ("character_id", "action", "actor", "source", "before", "after") VALUES ($1, $2, $3, $4, $5, $6)`)).
+			WithArgs(id, "delete", "ip:127.0.0.1", "api", sqlmock.AnyArg(), sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, `{"v":1,"id":1,"action":"delete","names":["goku"]}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
 
-		repo := NewCharacterRepository(db, 1000*time.Millisecond)
-		err = repo.DeleteCharacterInDatabase(context.Background(), name)
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		ctx := domains.WithAuditor(context.Background(), "ip:127.0.0.1", domains.AuditSourceAPI)
+		character, err := repo.DeleteCharacterInDatabase(ctx, name, 3)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 		assert.NoError(t, err)
+		assert.Equal(t, id, character.ID)
+		assert.Equal(t, 4, character.Version)
+		assert.NotNil(t, character.DeletedAt)
+	})
+
+	t.Run("execute delete whose commit fails and return the error", func(t *testing.T) {
+		name := "goku"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}).
+			AddRow(1, name, "60.000.000", "Saiyan", "goku.webp", time.Now(), 2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NOW()`)).
+			WithArgs(name, 0).
+			WillReturnRows(rows)
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.DeleteCharacterInDatabase(context.Background(), name, domains.AnyVersion)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.EqualError(t, err, "connection reset")
+	})
+
+	t.Run("execute delete of a missing character and return not found", func(t *testing.T) {
+		name := "goku"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		require.NotNil(t, db)
+		require.NotNil(t, mock)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "name" = $1 AND "deleted_at" IS NULL AND ($2 = 0 OR "version" = $2) RETURNING "id", "name", "ki", "race", "image", "deleted_at", "version"`),
+		).WithArgs(name, domains.AnyVersion).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.DeleteCharacterInDatabase(context.Background(), name, domains.AnyVersion)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+
+	t.Run("execute delete with a stale version and return version mismatch", func(t *testing.T) {
+		name := "goku"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE "name" = $1`),
+		).WithArgs(name, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}))
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "version" FROM "character_dragonball" WHERE "name" = $1 AND "deleted_at" IS NULL`),
+		).WithArgs(name).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.DeleteCharacterInDatabase(context.Background(), name, 2)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+	})
+
+}
+
+func Test_SchemaVersion(t *testing.T) {
+
+	t.Run("execute get schema version and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT COALESCE(MAX("version"), 0) FROM "schema_version"`),
+		).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(SchemaVersion))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		version, err := repo.SchemaVersion(context.Background())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, SchemaVersion, version)
+	})
+
+	t.Run("execute get schema version on a new sqlite database and success", func(t *testing.T) {
+		sqlClient, err := NewSQLiteClient(":memory:")
+		require.NoError(t, err)
+		defer sqlClient.Close()
+
+		repo := NewSQLiteCharacterRepository(sqlClient, time.Second)
+		version, err := repo.SchemaVersion(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, SchemaVersion, version)
+	})
+
+}
+
+func Test_PingExternalAPI(t *testing.T) {
+
+	t.Run("execute ping of an external api answering a client error and success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/characters", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		repo := newCharacterRepository(nil, time.Second, postgresDialect)
+		repo.externalAPIURL = server.URL
+
+		assert.NoError(t, repo.PingExternalAPI(context.Background()))
+	})
+
+	t.Run("execute ping of an external api answering a server error and return an error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		repo := newCharacterRepository(nil, time.Second, postgresDialect)
+		repo.externalAPIURL = server.URL
+
+		assert.EqualError(t, repo.PingExternalAPI(context.Background()), "received server error status code: 503")
 	})
 
 }
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/caches"
+	"github.com/encilab/dragon-ball/src/conformance"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Test_CharacterStoreConformance runs the conformance suite against every
+// storage backend. Postgres runs only when CONFORMANCE_PSQL_DSN points at a
+// database it may wipe.
+func Test_CharacterStoreConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+			repo := NewMemoryCharacterRepository(time.Second)
+			repo.externalAPIURL = externalAPIURL
+			return repo
+		})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+			sqlClient, err := NewSQLiteClient(":memory:")
+			require.NoError(t, err)
+			t.Cleanup(func() { sqlClient.Close() })
+
+			repo := NewSQLiteCharacterRepository(sqlClient, time.Second)
+			repo.externalAPIURL = externalAPIURL
+			return repo
+		})
+	})
+
+	t.Run("memory api keys", func(t *testing.T) {
+		conformance.TestAPIKeyRepository(t, func(t *testing.T) domains.APIKeyRepository {
+			return NewMemoryCharacterRepository(time.Second)
+		})
+	})
+
+	t.Run("sqlite api keys", func(t *testing.T) {
+		conformance.TestAPIKeyRepository(t, func(t *testing.T) domains.APIKeyRepository {
+			sqlClient, err := NewSQLiteClient(":memory:")
+			require.NoError(t, err)
+			t.Cleanup(func() { sqlClient.Close() })
+
+			return NewSQLiteCharacterRepository(sqlClient, time.Second)
+		})
+	})
+
+	t.Run("memory behind the cache", func(t *testing.T) {
+		conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+			repo := NewMemoryCharacterRepository(time.Second)
+			repo.externalAPIURL = externalAPIURL
+			return NewCachedCharacterRepository(repo, caches.NewMemory(10), time.Minute, time.Minute, 0)
+		})
+	})
+
+	t.Run("memory instrumented", func(t *testing.T) {
+		conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+			repo := NewMemoryCharacterRepository(time.Second)
+			repo.externalAPIURL = externalAPIURL
+			return NewInstrumentedCharacterStore(repo, &recordingStoreObserver{}, noop.NewTracerProvider().Tracer(""))
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("CONFORMANCE_PSQL_DSN")
+		if dsn == "" {
+			t.Skip("CONFORMANCE_PSQL_DSN is not set")
+		}
+
+		pool, err := pgxpool.New(context.Background(), dsn)
+		require.NoError(t, err)
+		defer pool.Close()
+
+		schema, err := os.ReadFile("../../conf/init.sql")
+		require.NoError(t, err)
+		_, err = pool.Exec(context.Background(), string(schema))
+		require.NoError(t, err)
+
+		conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+			_, err := pool.Exec(context.Background(), `TRUNCATE "character_dragonball", "character_audit", "character_version"`)
+			require.NoError(t, err)
+
+			repo := NewCharacterRepository(pool, 5*time.Second)
+			repo.externalAPIURL = externalAPIURL
+			return repo
+		})
+
+		conformance.TestAPIKeyRepository(t, func(t *testing.T) domains.APIKeyRepository {
+			_, err := pool.Exec(context.Background(), `TRUNCATE "api_key" RESTART IDENTITY`)
+			require.NoError(t, err)
+
+			return NewCharacterRepository(pool, 5*time.Second)
+		})
+	})
+}
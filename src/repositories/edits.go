@@ -0,0 +1,559 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *CharacterRepository) PatchCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	patch domains.CharacterPatch,
+	expectedVersion int,
+) (domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return domains.Character{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	before, overriddenFields, err := selectCharacterForUpdate(ctxTimeout, tx, r.dialect, id)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if expectedVersion != domains.AnyVersion && expectedVersion != before.Version {
+		err = domains.ErrCharacterVersionMismatch
+		return domains.Character{}, err
+	}
+
+	character := patch.Apply(before)
+	if character.Name != before.Name {
+		err = checkNameIsFree(ctxTimeout, tx, character)
+		if err != nil {
+			return domains.Character{}, err
+		}
+	}
+
+	changed := character != before
+	overriddenFields = mergeFields(overriddenFields, patch.Fields())
+	character.Version, err = updateCharacter(ctxTimeout, tx, character, overriddenFields, changed)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if changed {
+		err = recordChange(ctxTimeout, tx, id, domains.AuditActionUpdate, &before, &character)
+		if err != nil {
+			return domains.Character{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domains.Character{}, err
+	}
+	r.wrote(ctx)
+
+	return character, nil
+}
+
+func (r *CharacterRepository) SyncCharacterInDatabase(
+	ctx context.Context,
+	upstream domains.Character,
+	resetOverrides bool,
+) (domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return domains.Character{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	before, overriddenFields, err := selectCharacterForUpdate(ctxTimeout, tx, r.dialect, upstream.ID)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if resetOverrides {
+		overriddenFields = []string{}
+	}
+	character := domains.MergeUpstreamCharacter(before, upstream, overriddenFields)
+	if character == before && !resetOverrides {
+		err = tx.Rollback()
+		return character, err
+	}
+
+	changed := character != before
+	character.Version, err = updateCharacter(ctxTimeout, tx, character, overriddenFields, changed)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if changed {
+		err = recordChange(ctxTimeout, tx, character.ID, domains.AuditActionUpdate, &before, &character)
+		if err != nil {
+			return domains.Character{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domains.Character{}, err
+	}
+	r.wrote(ctx)
+
+	return character, nil
+}
+
+// SyncCharactersInDatabase syncs a whole run in one transaction and answers
+// the characters it synced; the ones deleted since they were read are left
+// out. The rows are staged and the audit entries and versions are written
+// with COPY rather than one statement per character.
+func (r *CharacterRepository) SyncCharactersInDatabase(
+	ctx context.Context,
+	upstreams []domains.Character,
+	resetOverrides bool,
+) ([]domains.Character, error) {
+	if r.pool == nil {
+		return syncCharactersOneByOne(ctx, r, upstreams, resetOverrides)
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctxTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctxTimeout); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	ids := make([]uint, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		ids = append(ids, upstream.ID)
+	}
+	stored, err := selectCharactersForSync(ctxTimeout, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var synced []domains.Character
+	var pending []syncedCharacter
+	for _, upstream := range upstreams {
+		current, ok := stored[upstream.ID]
+		if !ok {
+			continue
+		}
+
+		overriddenFields := current.overriddenFields
+		if resetOverrides {
+			overriddenFields = []string{}
+		}
+		character := domains.MergeUpstreamCharacter(current.character, upstream, overriddenFields)
+		if character == current.character && !resetOverrides {
+			synced = append(synced, character)
+			continue
+		}
+
+		pending = append(pending, syncedCharacter{
+			before:           current.character,
+			after:            character,
+			overriddenFields: overriddenFields,
+			changed:          character != current.character,
+		})
+	}
+
+	if len(pending) > 0 {
+		err = updateSyncedCharacters(ctxTimeout, tx, pending)
+		if err != nil {
+			return nil, err
+		}
+		err = recordSyncedChanges(ctxTimeout, tx, pending)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctxTimeout); err != nil {
+		return nil, err
+	}
+	r.wrote(ctx)
+
+	for _, p := range pending {
+		synced = append(synced, p.after)
+	}
+	sort.Slice(synced, func(i, j int) bool { return synced[i].ID < synced[j].ID })
+
+	return synced, nil
+}
+
+// syncCharactersOneByOne is SyncCharactersInDatabase for the backends with no
+// COPY: every character is synced in a transaction of its own.
+func syncCharactersOneByOne(
+	ctx context.Context,
+	r domains.CharacterSyncRepository,
+	upstreams []domains.Character,
+	resetOverrides bool,
+) ([]domains.Character, error) {
+	var synced []domains.Character
+	for _, upstream := range upstreams {
+		character, err := r.SyncCharacterInDatabase(ctx, upstream, resetOverrides)
+		if err != nil {
+			if errors.Is(err, domains.ErrCharacterNotFoundInDatabase) || errors.Is(err, domains.ErrCharacterIsDeleted) {
+				continue
+			}
+			return synced, err
+		}
+		synced = append(synced, character)
+	}
+	sort.Slice(synced, func(i, j int) bool { return synced[i].ID < synced[j].ID })
+
+	return synced, nil
+}
+
+type storedCharacter struct {
+	character        domains.Character
+	overriddenFields []string
+}
+
+type syncedCharacter struct {
+	before           domains.Character
+	after            domains.Character
+	overriddenFields []string
+	changed          bool
+}
+
+func selectCharactersForSync(
+	ctx context.Context,
+	tx pgx.Tx,
+	ids []uint,
+) (map[uint]storedCharacter, error) {
+	rows, err := tx.Query(
+		ctx,
+		`SELECT "id", "name", "ki", "race", "image", "version", "overridden_fields" FROM "character_dragonball" WHERE "id" = ANY($1) AND "deleted_at" IS NULL ORDER BY "id" FOR UPDATE`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[uint]storedCharacter{}
+	for rows.Next() {
+		var character domains.Character
+		var overriddenFields []byte
+		if err := rows.Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+			&character.Version,
+			&overriddenFields,
+		); err != nil {
+			return nil, err
+		}
+
+		fields := []string{}
+		if len(overriddenFields) > 0 {
+			if err := json.Unmarshal(overriddenFields, &fields); err != nil {
+				return nil, err
+			}
+		}
+		stored[character.ID] = storedCharacter{character: character, overriddenFields: fields}
+	}
+
+	return stored, rows.Err()
+}
+
+// updateSyncedCharacters copies the new values into a table that lives as
+// long as the transaction and updates every character from it at once. As
+// in updateCharacter, only the rows whose values changed get a new version.
+func updateSyncedCharacters(
+	ctx context.Context,
+	tx pgx.Tx,
+	pending []syncedCharacter,
+) error {
+	_, err := tx.Exec(
+		ctx,
+		`CREATE TEMPORARY TABLE "character_sync" ("id" INT NOT NULL, "name" VARCHAR(64) NOT NULL, "ki" VARCHAR(256) NOT NULL, "race" VARCHAR(64) NOT NULL, "image" VARCHAR(256) NOT NULL, "overridden_fields" JSONB NOT NULL, "changed" BOOLEAN NOT NULL) ON COMMIT DROP`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"character_sync"},
+		[]string{"id", "name", "ki", "race", "image", "overridden_fields", "changed"},
+		pgx.CopyFromSlice(len(pending), func(i int) ([]interface{}, error) {
+			encoded, err := json.Marshal(pending[i].overriddenFields)
+			if err != nil {
+				return nil, err
+			}
+			after := pending[i].after
+			return []interface{}{after.ID, after.Name, after.Ki, after.Race, after.Image, string(encoded), pending[i].changed}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(
+		ctx,
+		`UPDATE "character_dragonball" AS c SET "name" = s."name", "ki" = s."ki", "race" = s."race", "image" = s."image", "overridden_fields" = s."overridden_fields", "version" = c."version" + CASE WHEN s."changed" THEN 1 ELSE 0 END FROM "character_sync" AS s WHERE c."id" = s."id" RETURNING c."id", c."version"`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	versions := map[uint]int{}
+	for rows.Next() {
+		var id uint
+		var version int
+		if err := rows.Scan(&id, &version); err != nil {
+			return err
+		}
+		versions[id] = version
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range pending {
+		pending[i].after.Version = versions[pending[i].after.ID]
+	}
+
+	return nil
+}
+
+// recordSyncedChanges is recordChange for a whole sync run: the characters
+// whose values changed get their audit entry and new version through COPY.
+func recordSyncedChanges(
+	ctx context.Context,
+	tx pgx.Tx,
+	pending []syncedCharacter,
+) error {
+	var changed []syncedCharacter
+	var ids []uint
+	for _, p := range pending {
+		if !p.changed {
+			continue
+		}
+		changed = append(changed, p)
+		ids = append(ids, p.after.ID)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	auditor := domains.AuditorFromContext(ctx)
+	_, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"character_audit"},
+		[]string{"character_id", "action", "actor", "source", "before", "after"},
+		pgx.CopyFromSlice(len(changed), func(i int) ([]interface{}, error) {
+			before, after := changed[i].before, changed[i].after
+			beforeJSON, err := auditJSON(&before)
+			if err != nil {
+				return nil, err
+			}
+			afterJSON, err := auditJSON(&after)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{after.ID, string(domains.AuditActionUpdate), auditor.Actor, string(auditor.Source), beforeJSON, afterJSON}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	// NOW() is when the transaction started, so every version written by
+	// the statements of this transaction opens at the same instant.
+	var now time.Time
+	if err := tx.QueryRow(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE "character_version" SET "valid_to" = NOW() WHERE "character_id" = ANY($1) AND "valid_to" IS NULL`,
+		ids,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"character_version"},
+		[]string{"character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from"},
+		pgx.CopyFromSlice(len(changed), func(i int) ([]interface{}, error) {
+			after := changed[i].after
+			return []interface{}{after.ID, after.Version, after.Name, after.Ki, after.Race, after.Image, after.DeletedAt, now}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, c := range changed {
+		before, after := c.before, c.after
+		payload, err := json.Marshal(domains.NewCharacterChange(after.ID, domains.AuditActionUpdate, &before, &after))
+		if err != nil {
+			return err
+		}
+		batch.Queue(`SELECT pg_notify($1, $2)`, domains.CharacterChangesChannel, string(payload))
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// checkNameIsFree answers ErrCharacterAlreadyExistInDatabase when another
+// live character already has the name, which lookups by name expect to be
+// unique.
+func checkNameIsFree(
+	ctx context.Context,
+	tx *sql.Tx,
+	character domains.Character,
+) error {
+	var count int
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM "character_dragonball" WHERE "name" = $1 AND "id" <> $2 AND "deleted_at" IS NULL`,
+		character.Name,
+		character.ID,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return domains.ErrCharacterAlreadyExistInDatabase
+	}
+
+	return nil
+}
+
+func selectCharacterForUpdate(
+	ctx context.Context,
+	tx *sql.Tx,
+	d dialect,
+	id uint,
+) (domains.Character, []string, error) {
+	var character domains.Character
+	var overriddenFields []byte
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "overridden_fields" FROM "character_dragonball" WHERE "id" = $1`+d.lockRows,
+		id,
+	).Scan(
+		&character.ID,
+		&character.Name,
+		&character.Ki,
+		&character.Race,
+		&character.Image,
+		&character.DeletedAt,
+		&character.Version,
+		&overriddenFields,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.Character{}, nil, domains.ErrCharacterNotFoundInDatabase
+		}
+		return domains.Character{}, nil, err
+	}
+
+	if character.DeletedAt != nil {
+		return domains.Character{}, nil, domains.ErrCharacterIsDeleted
+	}
+
+	fields := []string{}
+	if len(overriddenFields) > 0 {
+		if err := json.Unmarshal(overriddenFields, &fields); err != nil {
+			return domains.Character{}, nil, err
+		}
+	}
+
+	return character, fields, nil
+}
+
+// updateCharacter answers the version of the row, which only moves on when
+// its values changed; a write of the overridden fields alone keeps it.
+func updateCharacter(
+	ctx context.Context,
+	tx *sql.Tx,
+	character domains.Character,
+	overriddenFields []string,
+	changed bool,
+) (int, error) {
+	encoded, err := json.Marshal(overriddenFields)
+	if err != nil {
+		return 0, err
+	}
+
+	increment := 0
+	if changed {
+		increment = 1
+	}
+
+	var version int
+	err = tx.QueryRowContext(
+		ctx,
+		`UPDATE "character_dragonball" SET "name" = $2, "ki" = $3, "race" = $4, "image" = $5, "overridden_fields" = $6, "version" = "version" + $7 WHERE "id" = $1 RETURNING "version"`,
+		character.ID,
+		character.Name,
+		character.Ki,
+		character.Race,
+		character.Image,
+		string(encoded),
+		increment,
+	).Scan(&version)
+
+	return version, err
+}
+
+func mergeFields(current []string, added []string) []string {
+	seen := make(map[string]struct{}, len(current)+len(added))
+	merged := []string{}
+	for _, field := range append(append([]string{}, current...), added...) {
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		merged = append(merged, field)
+	}
+	sort.Strings(merged)
+
+	return merged
+}
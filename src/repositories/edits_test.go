@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func characterForUpdateRows(overriddenFields string, deletedAt interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version", "overridden_fields"}).
+		AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", deletedAt, 3, []byte(overriddenFields))
+}
+
+func Test_PatchCharacterInDatabase(t *testing.T) {
+	ki := "90.000.000"
+
+	t.Run("execute patch and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "overridden_fields" FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`),
+		).WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`["race"]`, nil))
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`UPDATE "character_dragonball" SET "name" = $2, "ki" = $3, "race" = $4, "image" = $5, "overridden_fields" = $6, "version" = "version" + $7 WHERE "id" = $1 RETURNING "version"`),
+		).WithArgs(uint(1), "goku", ki, "Saiyan", "goku.webp", `["ki","race"]`, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(1), "update", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WithArgs(uint(1), 4, "goku", ki, "Saiyan", "goku.webp", nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Ki: &ki}, 3)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, ki, character.Ki)
+		assert.Equal(t, 4, character.Version)
+	})
+
+	t.Run("execute patch without changes and keep the version", func(t *testing.T) {
+		sameKi := "60.000.000"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`[]`, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "character_dragonball" SET "name" = $2`)).
+			WithArgs(uint(1), "goku", sameKi, "Saiyan", "goku.webp", `["ki"]`, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Ki: &sameKi}, 3)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, character.Version)
+	})
+
+	t.Run("execute patch to the name of another character and return ErrCharacterAlreadyExistInDatabase", func(t *testing.T) {
+		name := "vegeta"
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`[]`, nil))
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT COUNT(*) FROM "character_dragonball" WHERE "name" = $1 AND "id" <> $2 AND "deleted_at" IS NULL`),
+		).WithArgs(name, uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Name: &name}, 3)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterAlreadyExistInDatabase)
+	})
+
+	t.Run("execute patch with a stale version and return version mismatch", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`[]`, nil))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Ki: &ki}, 2)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+	})
+
+	t.Run("execute patch on a deleted character and return character deleted", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`[]`, time.Now()))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterIsDeleted)
+	})
+
+	t.Run("execute patch on a missing character and return not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "overridden_fields"}))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.PatchCharacterInDatabase(context.Background(), 1, domains.CharacterPatch{Ki: &ki}, domains.AnyVersion)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+}
+
+func Test_SyncCharacterInDatabase(t *testing.T) {
+	upstream := domains.Character{
+		ID:    1,
+		Name:  "Goku",
+		Ki:    "90.000.000",
+		Race:  "Saiyan",
+		Image: "goku_ssj.webp",
+	}
+
+	t.Run("execute sync and keep the overridden fields", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`["ki"]`, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "character_dragonball" SET "name" = $2`)).
+			WithArgs(uint(1), "goku", "60.000.000", "Saiyan", "goku_ssj.webp", `["ki"]`, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(1), "update", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.SyncCharacterInDatabase(context.Background(), upstream, false)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, "60.000.000", character.Ki)
+		assert.Equal(t, "goku_ssj.webp", character.Image)
+	})
+
+	t.Run("execute sync with reset and take every field from upstream", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`["ki"]`, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "character_dragonball" SET "name" = $2`)).
+			WithArgs(uint(1), "goku", "90.000.000", "Saiyan", "goku_ssj.webp", `[]`, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.SyncCharacterInDatabase(context.Background(), upstream, true)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, "90.000.000", character.Ki)
+	})
+
+	t.Run("execute sync without changes and write nothing", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "character_dragonball" WHERE "id" = $1 FOR UPDATE`)).
+			WithArgs(uint(1)).
+			WillReturnRows(characterForUpdateRows(`["ki","image"]`, nil))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.SyncCharacterInDatabase(context.Background(), upstream, false)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+	})
+}
+
+func Test_FetchCharacterInExternalAPIByName(t *testing.T) {
+	t.Run("execute fetch and do not write to the database", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "goku", r.URL.Query().Get("name"))
+			_, _ = w.Write([]byte(`[{"id":1,"name":"Goku","ki":"60.000.000","race":"Saiyan","image":"goku.webp"}]`))
+		}))
+		defer server.Close()
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		repo.externalAPIURL = server.URL
+		character, err := repo.FetchCharacterInExternalAPIByName(context.Background(), "goku")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, "goku", character.Name)
+	})
+}
+
+func Test_FetchCharacterInExternalAPIByID(t *testing.T) {
+	t.Run("execute fetch by the upstream id", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/characters/1", r.URL.Path)
+			_, _ = w.Write([]byte(`{"id":1,"name":"Goku","ki":"60.000.000","race":"Saiyan","image":"goku.webp"}`))
+		}))
+		defer server.Close()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		repo.externalAPIURL = server.URL
+		character, err := repo.FetchCharacterInExternalAPIByID(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domains.Character{ID: 1, Name: "goku", Ki: "60.000.000", Race: "Saiyan", Image: "goku.webp"}, character)
+	})
+
+	t.Run("execute fetch of an id the external api does not know and fail", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		repo.externalAPIURL = server.URL
+		_, err = repo.FetchCharacterInExternalAPIByID(context.Background(), 999)
+
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInExternalAPI)
+	})
+}
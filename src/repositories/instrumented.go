@@ -0,0 +1,291 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type StoreObserver interface {
+	ObserveQuery(method string, outcome string, elapsed time.Duration)
+	ObserveUpstream(operation string, outcome string, elapsed time.Duration)
+}
+
+// InstrumentedCharacterStore times every call to a storage backend and
+// traces it as a span named after the method. The calls reaching the external
+// API are observed as upstream calls, the others as queries;
+// GetCharacterInExternalAPIByName includes storing what it fetched.
+type InstrumentedCharacterStore struct {
+	characterStore domains.CharacterStore
+	storeObserver  StoreObserver
+	tracer         trace.Tracer
+}
+
+func NewInstrumentedCharacterStore(
+	characterStore domains.CharacterStore,
+	storeObserver StoreObserver,
+	tracer trace.Tracer,
+) *InstrumentedCharacterStore {
+
+	return &InstrumentedCharacterStore{
+		characterStore: characterStore,
+		storeObserver:  storeObserver,
+		tracer:         tracer,
+	}
+}
+
+// rejections are the errors answered to a request the data does not allow,
+// which are not failures of the storage or the external API.
+var rejections = []error{
+	domains.ErrCharacterNotFoundInDatabase,
+	domains.ErrCharacterNotFoundInExternalAPI,
+	domains.ErrCharacterVersionNotFound,
+	domains.ErrCharacterAlreadyExistInDatabase,
+	domains.ErrCharacterIsDeleted,
+	domains.ErrCharacterVersionMismatch,
+}
+
+// outcome is ok, rejected, canceled when the caller gave up, or error.
+func outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return "rejected"
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+
+	return "error"
+}
+
+func (s *InstrumentedCharacterStore) startQuery(
+	ctx context.Context,
+	method string,
+	attributes ...attribute.KeyValue,
+) (context.Context, func(err *error)) {
+	return s.start(ctx, method, s.storeObserver.ObserveQuery, attributes)
+}
+
+func (s *InstrumentedCharacterStore) startUpstream(
+	ctx context.Context,
+	operation string,
+	attributes ...attribute.KeyValue,
+) (context.Context, func(err *error)) {
+	return s.start(ctx, operation, s.storeObserver.ObserveUpstream, attributes)
+}
+
+// start opens the span of a call, answering the function that closes it and
+// observes the call once its error is known. Only failures mark the span as
+// an error; rejections are recorded as an event.
+func (s *InstrumentedCharacterStore) start(
+	ctx context.Context,
+	name string,
+	observe func(name string, outcome string, elapsed time.Duration),
+	attributes []attribute.KeyValue,
+) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := s.tracer.Start(ctx, "CharacterRepository."+name, trace.WithAttributes(attributes...))
+
+	return ctx, func(err *error) {
+		result := outcome(*err)
+		observe(name, result, time.Since(start))
+
+		span.SetAttributes(attribute.String("outcome", result))
+		switch result {
+		case "ok":
+		case "error":
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		default:
+			span.RecordError(*err)
+		}
+		span.End()
+	}
+}
+
+func (s *InstrumentedCharacterStore) PingContext(ctx context.Context) error {
+	return s.characterStore.PingContext(ctx)
+}
+
+func (s *InstrumentedCharacterStore) GetCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (_ domains.Character, err error) {
+	ctx, end := s.startUpstream(ctx, "GetCharacterInExternalAPIByName", attribute.String("character.name", name))
+	defer end(&err)
+	return s.characterStore.GetCharacterInExternalAPIByName(ctx, name)
+}
+
+func (s *InstrumentedCharacterStore) FetchCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (_ domains.Character, err error) {
+	ctx, end := s.startUpstream(ctx, "FetchCharacterInExternalAPIByName", attribute.String("character.name", name))
+	defer end(&err)
+	return s.characterStore.FetchCharacterInExternalAPIByName(ctx, name)
+}
+
+func (s *InstrumentedCharacterStore) FetchCharacterInExternalAPIByID(
+	ctx context.Context,
+	id uint,
+) (_ domains.Character, err error) {
+	ctx, end := s.startUpstream(ctx, "FetchCharacterInExternalAPIByID", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.FetchCharacterInExternalAPIByID(ctx, id)
+}
+
+func (s *InstrumentedCharacterStore) GetCharacterInDatabaseByName(
+	ctx context.Context,
+	name string,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "GetCharacterInDatabaseByName", attribute.String("character.name", name))
+	defer end(&err)
+	return s.characterStore.GetCharacterInDatabaseByName(ctx, name)
+}
+
+func (s *InstrumentedCharacterStore) GetCharacterInDatabaseByID(
+	ctx context.Context,
+	id uint,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "GetCharacterInDatabaseByID", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.GetCharacterInDatabaseByID(ctx, id)
+}
+
+func (s *InstrumentedCharacterStore) SearchCharactersInDatabase(
+	ctx context.Context,
+	limit int,
+	includeDeleted bool,
+) (_ []domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "SearchCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.SearchCharactersInDatabase(ctx, limit, includeDeleted)
+}
+
+func (s *InstrumentedCharacterStore) DeleteCharacterInDatabase(
+	ctx context.Context,
+	name string,
+	expectedVersion int,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "DeleteCharacterInDatabase", attribute.String("character.name", name))
+	defer end(&err)
+	return s.characterStore.DeleteCharacterInDatabase(ctx, name, expectedVersion)
+}
+
+func (s *InstrumentedCharacterStore) ListDeletedCharactersInDatabase(
+	ctx context.Context,
+	limit int,
+) (_ []domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "ListDeletedCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.ListDeletedCharactersInDatabase(ctx, limit)
+}
+
+func (s *InstrumentedCharacterStore) RestoreCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	expectedVersion int,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "RestoreCharacterInDatabase", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.RestoreCharacterInDatabase(ctx, id, expectedVersion)
+}
+
+func (s *InstrumentedCharacterStore) PurgeDeletedCharactersInDatabase(
+	ctx context.Context,
+	deletedBefore time.Time,
+) (_ []domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "PurgeDeletedCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.PurgeDeletedCharactersInDatabase(ctx, deletedBefore)
+}
+
+func (s *InstrumentedCharacterStore) ListCharacterHistoryInDatabase(
+	ctx context.Context,
+	characterID uint,
+	limit int,
+	offset int,
+) (_ []domains.AuditEntry, err error) {
+	ctx, end := s.startQuery(ctx, "ListCharacterHistoryInDatabase", attribute.Int("character.id", int(characterID)))
+	defer end(&err)
+	return s.characterStore.ListCharacterHistoryInDatabase(ctx, characterID, limit, offset)
+}
+
+func (s *InstrumentedCharacterStore) GetCharacterVersionInDatabaseAsOf(
+	ctx context.Context,
+	id uint,
+	asOf time.Time,
+) (_ domains.CharacterVersion, err error) {
+	ctx, end := s.startQuery(ctx, "GetCharacterVersionInDatabaseAsOf", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.GetCharacterVersionInDatabaseAsOf(ctx, id, asOf)
+}
+
+func (s *InstrumentedCharacterStore) GetCharacterVersionInDatabase(
+	ctx context.Context,
+	id uint,
+	version int,
+) (_ domains.CharacterVersion, err error) {
+	ctx, end := s.startQuery(ctx, "GetCharacterVersionInDatabase", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.GetCharacterVersionInDatabase(ctx, id, version)
+}
+
+func (s *InstrumentedCharacterStore) ExportCharactersInDatabase(
+	ctx context.Context,
+) (_ []domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "ExportCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.ExportCharactersInDatabase(ctx)
+}
+
+func (s *InstrumentedCharacterStore) ImportCharactersInDatabase(
+	ctx context.Context,
+	characters []domains.Character,
+	mode domains.ImportMode,
+	dryRun bool,
+) (_ domains.ImportReport, err error) {
+	ctx, end := s.startQuery(ctx, "ImportCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.ImportCharactersInDatabase(ctx, characters, mode, dryRun)
+}
+
+func (s *InstrumentedCharacterStore) PatchCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	patch domains.CharacterPatch,
+	expectedVersion int,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "PatchCharacterInDatabase", attribute.Int("character.id", int(id)))
+	defer end(&err)
+	return s.characterStore.PatchCharacterInDatabase(ctx, id, patch, expectedVersion)
+}
+
+func (s *InstrumentedCharacterStore) SyncCharacterInDatabase(
+	ctx context.Context,
+	upstream domains.Character,
+	resetOverrides bool,
+) (_ domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "SyncCharacterInDatabase", attribute.Int("character.id", int(upstream.ID)))
+	defer end(&err)
+	return s.characterStore.SyncCharacterInDatabase(ctx, upstream, resetOverrides)
+}
+
+func (s *InstrumentedCharacterStore) SyncCharactersInDatabase(
+	ctx context.Context,
+	upstreams []domains.Character,
+	resetOverrides bool,
+) (_ []domains.Character, err error) {
+	ctx, end := s.startQuery(ctx, "SyncCharactersInDatabase")
+	defer end(&err)
+	return s.characterStore.SyncCharactersInDatabase(ctx, upstreams, resetOverrides)
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type observedCall struct {
+	kind    string
+	name    string
+	outcome string
+}
+
+type recordingStoreObserver struct {
+	mu    sync.Mutex
+	calls []observedCall
+}
+
+func (o *recordingStoreObserver) ObserveQuery(method string, outcome string, elapsed time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, observedCall{kind: "query", name: method, outcome: outcome})
+}
+
+func (o *recordingStoreObserver) ObserveUpstream(operation string, outcome string, elapsed time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, observedCall{kind: "upstream", name: operation, outcome: outcome})
+}
+
+func Test_InstrumentedCharacterStore(t *testing.T) {
+	t.Run("execute queries and observe each by method and outcome", func(t *testing.T) {
+		observer := &recordingStoreObserver{}
+		store := NewInstrumentedCharacterStore(NewMemoryCharacterRepository(time.Second), observer, noop.NewTracerProvider().Tracer(""))
+		ctx := context.Background()
+
+		_, err := store.GetCharacterInDatabaseByName(ctx, "goku")
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		_, err = store.SearchCharactersInDatabase(ctx, 10, false)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []observedCall{
+			{kind: "query", name: "GetCharacterInDatabaseByName", outcome: "rejected"},
+			{kind: "query", name: "SearchCharactersInDatabase", outcome: "ok"},
+		}, observer.calls)
+	})
+
+	t.Run("execute external API calls and observe them as upstream calls", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		repo := NewMemoryCharacterRepository(time.Second)
+		repo.externalAPIURL = server.URL
+		observer := &recordingStoreObserver{}
+		store := NewInstrumentedCharacterStore(repo, observer, noop.NewTracerProvider().Tracer(""))
+
+		_, err := store.FetchCharacterInExternalAPIByName(context.Background(), "goku")
+		require.Error(t, err)
+
+		assert.Equal(t, []observedCall{
+			{kind: "upstream", name: "FetchCharacterInExternalAPIByName", outcome: "error"},
+		}, observer.calls)
+	})
+
+	t.Run("execute calls and trace them as spans of the caller's trace", func(t *testing.T) {
+		var traceparent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceparent = r.Header.Get("traceparent")
+			w.Write([]byte(`[{"id":1,"name":"Goku","ki":"60.000.000","race":"Saiyan","image":"goku.webp"}]`))
+		}))
+		defer server.Close()
+
+		exporter := tracetest.NewInMemoryExporter()
+		tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		tracing.Install(tracerProvider)
+		defer tracing.Install(sdktrace.NewTracerProvider())
+
+		repo := NewMemoryCharacterRepository(time.Second)
+		repo.externalAPIURL = server.URL
+		store := NewInstrumentedCharacterStore(repo, &recordingStoreObserver{}, tracerProvider.Tracer(tracing.Name))
+
+		ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "request")
+		_, err := store.GetCharacterInExternalAPIByName(ctx, "goku")
+		require.NoError(t, err)
+		_, err = store.GetCharacterInDatabaseByName(ctx, "vegeta")
+		require.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+		parent.End()
+
+		spans := exporter.GetSpans()
+		names := make([]string, 0, len(spans))
+		for _, span := range spans {
+			names = append(names, span.Name)
+			assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID(), span.Name)
+		}
+		assert.Equal(t, []string{
+			"HTTP GET",
+			"CharacterRepository.GetCharacterInExternalAPIByName",
+			"CharacterRepository.GetCharacterInDatabaseByName",
+			"request",
+		}, names)
+
+		assert.Contains(t, traceparent, parent.SpanContext().TraceID().String())
+		assert.Contains(t, spans[1].Attributes, attribute.String("character.name", "goku"))
+		assert.Equal(t, codes.Unset, spans[1].Status.Code)
+		assert.Contains(t, spans[2].Attributes, attribute.String("outcome", "rejected"))
+		assert.Len(t, spans[2].Events, 1)
+	})
+
+	t.Run("execute outcome and classify the errors", func(t *testing.T) {
+		assert.Equal(t, "ok", outcome(nil))
+		assert.Equal(t, "rejected", outcome(domains.ErrCharacterVersionMismatch))
+		assert.Equal(t, "canceled", outcome(context.Canceled))
+		assert.Equal(t, "error", outcome(errors.New("connection refused")))
+	})
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type notificationConn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// CharacterChangeListener holds a connection of its own listening on
+// domains.CharacterChangesChannel and hands every change to handle. When the
+// connection drops it reconnects, waiting from minBackoff up to maxBackoff
+// between attempts. Changes committed while it is disconnected are missed,
+// so once it listens again it calls flush.
+type CharacterChangeListener struct {
+	dial       func(ctx context.Context) (notificationConn, error)
+	handle     func(ctx context.Context, change domains.CharacterChange)
+	flush      func(ctx context.Context)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	wait       func(ctx context.Context, d time.Duration) bool
+}
+
+func NewCharacterChangeListener(
+	connString string,
+	handle func(ctx context.Context, change domains.CharacterChange),
+	flush func(ctx context.Context),
+	minBackoff time.Duration,
+	maxBackoff time.Duration,
+) *CharacterChangeListener {
+
+	return &CharacterChangeListener{
+		dial: func(ctx context.Context) (notificationConn, error) {
+			return pgx.Connect(ctx, connString)
+		},
+		handle:     handle,
+		flush:      flush,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		wait:       wait,
+	}
+}
+
+func (l *CharacterChangeListener) Run(ctx context.Context) {
+	backoff := l.minBackoff
+	reconnecting := false
+	for {
+		err := l.listen(ctx, func() {
+			backoff = l.minBackoff
+			if reconnecting {
+				l.flush(ctx)
+			}
+			reconnecting = true
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		domains.LoggerFromContext(ctx).Error("error when execute CharacterChangeListener.listen", "retry_in", backoff, "err", err)
+		if !l.wait(ctx, backoff) {
+			return
+		}
+		backoff = min(2*backoff, l.maxBackoff)
+	}
+}
+
+// listen returns once the connection fails, calling listening as soon as
+// notifications are flowing again.
+func (l *CharacterChangeListener) listen(
+	ctx context.Context,
+	listening func(),
+) error {
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+domains.CharacterChangesChannel); err != nil {
+		return err
+	}
+	listening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		change, err := domains.ParseCharacterChange(notification.Payload)
+		if err != nil {
+			domains.LoggerFromContext(ctx).Warn("error when execute ParseCharacterChange", "payload", notification.Payload, "err", err)
+			continue
+		}
+
+		l.handle(ctx, change)
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
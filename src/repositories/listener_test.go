@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNotificationConn struct {
+	payloads []string
+	err      error
+}
+
+func (c *fakeNotificationConn) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeNotificationConn) WaitForNotification(context.Context) (*pgconn.Notification, error) {
+	if len(c.payloads) == 0 {
+		return nil, c.err
+	}
+	payload := c.payloads[0]
+	c.payloads = c.payloads[1:]
+
+	return &pgconn.Notification{Channel: domains.CharacterChangesChannel, Payload: payload}, nil
+}
+
+func (c *fakeNotificationConn) Close(context.Context) error {
+	return nil
+}
+
+func Test_CharacterChangeListener(t *testing.T) {
+	t.Run("execute listen and hand over every change of a known payload version", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		conn := &fakeNotificationConn{
+			payloads: []string{
+				`{"v":1,"id":1,"action":"update","names":["goku","kakarot"]}`,
+				`{"v":2,"id":2,"names":["vegeta"]}`,
+				`not json`,
+				`{"v":1,"id":3,"action":"delete","names":["krillin"]}`,
+			},
+			err: errors.New("conn closed"),
+		}
+
+		var changes []domains.CharacterChange
+		listener := NewCharacterChangeListener("", func(_ context.Context, change domains.CharacterChange) {
+			changes = append(changes, change)
+		}, func(context.Context) {}, time.Second, time.Minute)
+		listener.dial = func(context.Context) (notificationConn, error) { return conn, nil }
+		listener.wait = func(context.Context, time.Duration) bool {
+			cancel()
+			return false
+		}
+
+		listener.Run(ctx)
+
+		assert.Equal(t, []domains.CharacterChange{
+			{V: 1, ID: 1, Action: domains.AuditActionUpdate, Names: []string{"goku", "kakarot"}},
+			{V: 1, ID: 3, Action: domains.AuditActionDelete, Names: []string{"krillin"}},
+		}, changes)
+	})
+
+	t.Run("execute reconnects with a doubling backoff reset once listening", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dials := []error{errors.New("refused"), errors.New("refused"), errors.New("refused"), nil, errors.New("refused")}
+		listener := NewCharacterChangeListener("", func(context.Context, domains.CharacterChange) {}, func(context.Context) {}, time.Second, 3*time.Second)
+		listener.dial = func(context.Context) (notificationConn, error) {
+			err := dials[0]
+			dials = dials[1:]
+			if err != nil {
+				return nil, err
+			}
+			return &fakeNotificationConn{err: errors.New("conn closed")}, nil
+		}
+
+		var backoffs []time.Duration
+		listener.wait = func(_ context.Context, d time.Duration) bool {
+			backoffs = append(backoffs, d)
+			if len(dials) == 0 {
+				return false
+			}
+			return true
+		}
+
+		listener.Run(ctx)
+
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, time.Second, 2 * time.Second}, backoffs)
+	})
+
+	t.Run("execute reconnects and flush once listening again, not when first listening", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dials := []error{nil, errors.New("refused"), nil, nil}
+		flushes := 0
+		listener := NewCharacterChangeListener("", func(context.Context, domains.CharacterChange) {}, func(context.Context) { flushes++ }, time.Second, time.Minute)
+		listener.dial = func(context.Context) (notificationConn, error) {
+			err := dials[0]
+			dials = dials[1:]
+			if err != nil {
+				return nil, err
+			}
+			return &fakeNotificationConn{err: errors.New("conn closed")}, nil
+		}
+		listener.wait = func(context.Context, time.Duration) bool {
+			return len(dials) > 0
+		}
+
+		listener.Run(ctx)
+
+		assert.Equal(t, 2, flushes)
+	})
+}
+
+func Test_ParseCharacterChange(t *testing.T) {
+	t.Run("execute new change of a rename and name both sides once", func(t *testing.T) {
+		goku := domains.Character{ID: 1, Name: "goku"}
+		kakarot := domains.Character{ID: 1, Name: "kakarot"}
+
+		change := domains.NewCharacterChange(1, domains.AuditActionUpdate, &goku, &kakarot)
+		assert.Equal(t, []string{"goku", "kakarot"}, change.Names)
+
+		change = domains.NewCharacterChange(1, domains.AuditActionDelete, &goku, &goku)
+		assert.Equal(t, []string{"goku"}, change.Names)
+	})
+
+	t.Run("execute parse of an unknown version and return ErrCharacterChangePayloadVersion", func(t *testing.T) {
+		_, err := domains.ParseCharacterChange(`{"v":2,"id":1}`)
+		assert.ErrorIs(t, err, domains.ErrCharacterChangePayloadVersion)
+	})
+}
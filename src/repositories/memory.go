@@ -0,0 +1,675 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+type memoryCharacter struct {
+	character        domains.Character
+	overriddenFields []string
+}
+
+type memoryAPIKey struct {
+	key  domains.APIKey
+	hash string
+}
+
+// MemoryCharacterRepository keeps characters, their audit and their versions
+// in the process, for demos and tests. It answers exactly what
+// CharacterRepository answers, including which fields each method fills in,
+// and loses everything on restart.
+type MemoryCharacterRepository struct {
+	mu             sync.Mutex
+	characters     map[uint]*memoryCharacter
+	audit          []domains.AuditEntry
+	versions       map[uint][]domains.CharacterVersion
+	apiKeys        []memoryAPIKey
+	clientTimeout  time.Duration
+	externalAPIURL string
+	now            func() time.Time
+}
+
+func NewMemoryCharacterRepository(
+	clientTimeout time.Duration,
+) *MemoryCharacterRepository {
+
+	return &MemoryCharacterRepository{
+		characters:     map[uint]*memoryCharacter{},
+		versions:       map[uint][]domains.CharacterVersion{},
+		clientTimeout:  clientTimeout,
+		externalAPIURL: defaultExternalAPIURL,
+		now:            time.Now,
+	}
+}
+
+func (r *MemoryCharacterRepository) PingContext(context.Context) error {
+	return nil
+}
+
+func (r *MemoryCharacterRepository) PingExternalAPI(ctx context.Context) error {
+	return pingExternalAPI(ctx, r.externalAPIURL, r.clientTimeout)
+}
+
+func (r *MemoryCharacterRepository) GetCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	character, err := r.FetchCharacterInExternalAPIByName(ctx, name)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// As storedCharacterByID: the id is stored under another name.
+	if stored, ok := r.characters[character.ID]; ok {
+		if stored.character.DeletedAt != nil {
+			return domains.Character{}, domains.ErrCharacterIsDeleted
+		}
+		return stored.character, nil
+	}
+
+	stored := domains.Character{
+		ID:    character.ID,
+		Name:  strings.ToLower(character.Name),
+		Ki:    character.Ki,
+		Race:  character.Race,
+		Image: character.Image,
+	}
+	stored.Version = r.insert(stored)
+	r.recordChange(ctx, character.ID, domains.AuditActionInsert, nil, &stored)
+	character.Version = stored.Version
+
+	return character, nil
+}
+
+func (r *MemoryCharacterRepository) FetchCharacterInExternalAPIByName(
+	ctx context.Context,
+	name string,
+) (domains.Character, error) {
+	return fetchCharacterInExternalAPIByName(ctx, r.externalAPIURL, r.clientTimeout, name)
+}
+
+func (r *MemoryCharacterRepository) FetchCharacterInExternalAPIByID(
+	ctx context.Context,
+	id uint,
+) (domains.Character, error) {
+	return fetchCharacterInExternalAPIByID(ctx, r.externalAPIURL, r.clientTimeout, id)
+}
+
+func (r *MemoryCharacterRepository) GetCharacterInDatabaseByName(
+	_ context.Context,
+	name string,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.sorted() {
+		if stored.character.Name != strings.ToLower(name) {
+			continue
+		}
+		character := stored.character
+		character.UpdatedAt = time.Time{}
+		if character.DeletedAt != nil {
+			return character, domains.ErrCharacterIsDeleted
+		}
+
+		return character, nil
+	}
+
+	return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+}
+
+func (r *MemoryCharacterRepository) GetCharacterInDatabaseByID(
+	_ context.Context,
+	id uint,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.characters[id]
+	if !ok {
+		return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+	}
+
+	return stored.character, nil
+}
+
+func (r *MemoryCharacterRepository) SearchCharactersInDatabase(
+	_ context.Context,
+	limit int,
+	includeDeleted bool,
+) ([]domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := []domains.Character{}
+	for _, stored := range r.sorted() {
+		if len(results) >= limit {
+			break
+		}
+		if stored.character.DeletedAt != nil && !includeDeleted {
+			continue
+		}
+
+		character := stored.character
+		character.Version = 0
+		results = append(results, character)
+	}
+
+	return results, nil
+}
+
+func (r *MemoryCharacterRepository) DeleteCharacterInDatabase(
+	ctx context.Context,
+	name string,
+	expectedVersion int,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.sorted() {
+		if stored.character.Name != strings.ToLower(name) || stored.character.DeletedAt != nil {
+			continue
+		}
+		if expectedVersion != domains.AnyVersion && expectedVersion != stored.character.Version {
+			return domains.Character{}, domains.ErrCharacterVersionMismatch
+		}
+
+		before := stored.character
+		before.UpdatedAt = time.Time{}
+		character := r.markDeleted(stored)
+		r.recordChange(ctx, character.ID, domains.AuditActionDelete, &before, &character)
+
+		return character, nil
+	}
+
+	return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+}
+
+func (r *MemoryCharacterRepository) ListDeletedCharactersInDatabase(
+	_ context.Context,
+	limit int,
+) ([]domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := []domains.Character{}
+	for _, stored := range r.sorted() {
+		if stored.character.DeletedAt != nil {
+			deleted = append(deleted, columns(stored.character))
+		}
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(*deleted[j].DeletedAt)
+	})
+	if len(deleted) > limit {
+		deleted = deleted[:limit]
+	}
+
+	return deleted, nil
+}
+
+func (r *MemoryCharacterRepository) RestoreCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	expectedVersion int,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.characters[id]
+	if !ok || stored.character.DeletedAt == nil {
+		return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+	}
+	if expectedVersion != domains.AnyVersion && expectedVersion != stored.character.Version {
+		return domains.Character{}, domains.ErrCharacterVersionMismatch
+	}
+
+	before := stored.character
+	before.UpdatedAt = time.Time{}
+	stored.character.DeletedAt = nil
+	r.touch(stored)
+
+	character := stored.character
+	character.UpdatedAt = time.Time{}
+	r.recordChange(ctx, id, domains.AuditActionRestore, &before, &character)
+
+	return character, nil
+}
+
+func (r *MemoryCharacterRepository) PurgeDeletedCharactersInDatabase(
+	ctx context.Context,
+	deletedBefore time.Time,
+) ([]domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := []domains.Character{}
+	for _, stored := range r.sorted() {
+		if stored.character.DeletedAt == nil || !stored.character.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		character := columns(stored.character)
+		delete(r.characters, character.ID)
+		r.recordChange(ctx, character.ID, domains.AuditActionPurge, &character, nil)
+		purged = append(purged, character)
+	}
+
+	return purged, nil
+}
+
+func (r *MemoryCharacterRepository) ListCharacterHistoryInDatabase(
+	_ context.Context,
+	characterID uint,
+	limit int,
+	offset int,
+) ([]domains.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := []domains.AuditEntry{}
+	for i := len(r.audit) - 1; i >= 0 && len(results) < limit; i-- {
+		if r.audit[i].CharacterID != characterID {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		results = append(results, r.audit[i])
+	}
+
+	return results, nil
+}
+
+func (r *MemoryCharacterRepository) GetCharacterVersionInDatabaseAsOf(
+	_ context.Context,
+	id uint,
+	asOf time.Time,
+) (domains.CharacterVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, version := range r.versions[id] {
+		if !version.ValidFrom.After(asOf) && (version.ValidTo == nil || version.ValidTo.After(asOf)) {
+			return version, nil
+		}
+	}
+
+	return domains.CharacterVersion{}, domains.ErrCharacterVersionNotFound
+}
+
+func (r *MemoryCharacterRepository) GetCharacterVersionInDatabase(
+	_ context.Context,
+	id uint,
+	version int,
+) (domains.CharacterVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, characterVersion := range r.versions[id] {
+		if characterVersion.Version == version {
+			return characterVersion, nil
+		}
+	}
+
+	return domains.CharacterVersion{}, domains.ErrCharacterVersionNotFound
+}
+
+func (r *MemoryCharacterRepository) ExportCharactersInDatabase(
+	context.Context,
+) ([]domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.live(), nil
+}
+
+func (r *MemoryCharacterRepository) ImportCharactersInDatabase(
+	ctx context.Context,
+	characters []domains.Character,
+	mode domains.ImportMode,
+	dryRun bool,
+) (domains.ImportReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, changes := diffCharacters(r.live(), characters, mode)
+	report.DryRun = dryRun
+	if dryRun {
+		return report, nil
+	}
+
+	for _, change := range changes {
+		switch change.action {
+		case domains.AuditActionDelete:
+			after := *change.before
+			deleted := r.markDeleted(r.characters[change.before.ID])
+			after.DeletedAt = deleted.DeletedAt
+			after.Version = deleted.Version
+			change.after = &after
+		default:
+			if stored, ok := r.characters[change.after.ID]; ok {
+				stored.character.Name = change.after.Name
+				stored.character.Ki = change.after.Ki
+				stored.character.Race = change.after.Race
+				stored.character.Image = change.after.Image
+				stored.character.DeletedAt = nil
+				r.touch(stored)
+				change.after.Version = stored.character.Version
+			} else {
+				change.after.Version = r.insert(*change.after)
+			}
+		}
+
+		r.recordChange(ctx, change.characterID(), change.action, change.before, change.after)
+	}
+
+	return report, nil
+}
+
+func (r *MemoryCharacterRepository) PatchCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	patch domains.CharacterPatch,
+	expectedVersion int,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.selectCharacter(id)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	before := columns(stored.character)
+	before.Version = stored.character.Version
+	if expectedVersion != domains.AnyVersion && expectedVersion != before.Version {
+		return domains.Character{}, domains.ErrCharacterVersionMismatch
+	}
+
+	character := patch.Apply(before)
+	if character.Name != before.Name {
+		for _, other := range r.characters {
+			if other.character.ID != id && other.character.Name == character.Name && other.character.DeletedAt == nil {
+				return domains.Character{}, domains.ErrCharacterAlreadyExistInDatabase
+			}
+		}
+	}
+
+	stored.overriddenFields = mergeFields(stored.overriddenFields, patch.Fields())
+	if character != before {
+		character.Version = r.update(stored, character)
+		r.recordChange(ctx, id, domains.AuditActionUpdate, &before, &character)
+	}
+
+	return character, nil
+}
+
+func (r *MemoryCharacterRepository) SyncCharacterInDatabase(
+	ctx context.Context,
+	upstream domains.Character,
+	resetOverrides bool,
+) (domains.Character, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.selectCharacter(upstream.ID)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	before := columns(stored.character)
+	before.Version = stored.character.Version
+	if resetOverrides {
+		stored.overriddenFields = []string{}
+	}
+	character := domains.MergeUpstreamCharacter(before, upstream, stored.overriddenFields)
+	if character == before && !resetOverrides {
+		return character, nil
+	}
+
+	if character != before {
+		character.Version = r.update(stored, character)
+		r.recordChange(ctx, character.ID, domains.AuditActionUpdate, &before, &character)
+	}
+
+	return character, nil
+}
+
+func (r *MemoryCharacterRepository) SyncCharactersInDatabase(
+	ctx context.Context,
+	upstreams []domains.Character,
+	resetOverrides bool,
+) ([]domains.Character, error) {
+	return syncCharactersOneByOne(ctx, r, upstreams, resetOverrides)
+}
+
+// selectCharacter answers what selectCharacterForUpdate answers.
+func (r *MemoryCharacterRepository) selectCharacter(id uint) (*memoryCharacter, error) {
+	stored, ok := r.characters[id]
+	if !ok {
+		return nil, domains.ErrCharacterNotFoundInDatabase
+	}
+	if stored.character.DeletedAt != nil {
+		return nil, domains.ErrCharacterIsDeleted
+	}
+
+	return stored, nil
+}
+
+// insert answers the version of the new row, which starts after the
+// versions a purged character with the same id left behind.
+func (r *MemoryCharacterRepository) insert(character domains.Character) int {
+	character.Version = 1
+	if versions := r.versions[character.ID]; len(versions) > 0 {
+		character.Version = versions[len(versions)-1].Version + 1
+	}
+	character.UpdatedAt = r.now()
+	r.characters[character.ID] = &memoryCharacter{
+		character:        character,
+		overriddenFields: []string{},
+	}
+
+	return character.Version
+}
+
+func (r *MemoryCharacterRepository) update(
+	stored *memoryCharacter,
+	character domains.Character,
+) int {
+	stored.character.Name = character.Name
+	stored.character.Ki = character.Ki
+	stored.character.Race = character.Race
+	stored.character.Image = character.Image
+	r.touch(stored)
+
+	return stored.character.Version
+}
+
+// markDeleted answers the columns the soft delete by name returns.
+func (r *MemoryCharacterRepository) markDeleted(stored *memoryCharacter) domains.Character {
+	deletedAt := r.now()
+	stored.character.DeletedAt = &deletedAt
+	r.touch(stored)
+
+	character := stored.character
+	character.UpdatedAt = time.Time{}
+	return character
+}
+
+// touch is every UPDATE of a row: the version and updated_at move on.
+func (r *MemoryCharacterRepository) touch(stored *memoryCharacter) {
+	stored.character.Version++
+	stored.character.UpdatedAt = r.now()
+}
+
+func (r *MemoryCharacterRepository) sorted() []*memoryCharacter {
+	sorted := make([]*memoryCharacter, 0, len(r.characters))
+	for _, stored := range r.characters {
+		sorted = append(sorted, stored)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].character.ID < sorted[j].character.ID
+	})
+
+	return sorted
+}
+
+// live answers what selectAllCharacters answers.
+func (r *MemoryCharacterRepository) live() []domains.Character {
+	results := []domains.Character{}
+	for _, stored := range r.sorted() {
+		if stored.character.DeletedAt == nil {
+			character := columns(stored.character)
+			results = append(results, character)
+		}
+	}
+
+	return results
+}
+
+// recordChange is the counterpart of the package function for the SQL
+// repositories, without the NOTIFY: a process has no replicas to tell.
+func (r *MemoryCharacterRepository) recordChange(
+	ctx context.Context,
+	characterID uint,
+	action domains.AuditAction,
+	before *domains.Character,
+	after *domains.Character,
+) {
+	now := r.now()
+	auditor := domains.AuditorFromContext(ctx)
+	r.audit = append(r.audit, domains.AuditEntry{
+		ID:          uint64(len(r.audit) + 1),
+		CharacterID: characterID,
+		Action:      action,
+		Actor:       auditor.Actor,
+		Source:      auditor.Source,
+		Before:      memoryAuditJSON(before),
+		After:       memoryAuditJSON(after),
+		CreatedAt:   now,
+	})
+
+	versions := r.versions[characterID]
+	for i := range versions {
+		if versions[i].ValidTo == nil {
+			versions[i].ValidTo = &now
+		}
+	}
+	if after != nil {
+		versions = append(versions, domains.CharacterVersion{
+			Character: columns(*after),
+			Version:   after.Version,
+			ValidFrom: now,
+		})
+	}
+	r.versions[characterID] = versions
+}
+
+func (r *MemoryCharacterRepository) CreateAPIKeyInDatabase(
+	_ context.Context,
+	name string,
+	role domains.Role,
+	hash string,
+) (domains.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.apiKeys {
+		if stored.key.Name == name || stored.hash == hash {
+			return domains.APIKey{}, domains.ErrAPIKeyAlreadyExists
+		}
+	}
+
+	key := domains.APIKey{
+		ID:        uint(len(r.apiKeys) + 1),
+		Name:      name,
+		Role:      role,
+		CreatedAt: r.now(),
+	}
+	r.apiKeys = append(r.apiKeys, memoryAPIKey{key: key, hash: hash})
+
+	return key, nil
+}
+
+func (r *MemoryCharacterRepository) GetAPIKeyInDatabaseByHash(
+	_ context.Context,
+	hash string,
+) (domains.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.apiKeys {
+		if stored.hash == hash && stored.key.RevokedAt == nil {
+			return stored.key, nil
+		}
+	}
+
+	return domains.APIKey{}, domains.ErrAPIKeyIsInvalid
+}
+
+func (r *MemoryCharacterRepository) ListAPIKeysInDatabase(context.Context) ([]domains.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]domains.APIKey, 0, len(r.apiKeys))
+	for _, stored := range r.apiKeys {
+		results = append(results, stored.key)
+	}
+
+	return results, nil
+}
+
+func (r *MemoryCharacterRepository) RevokeAPIKeyInDatabase(
+	_ context.Context,
+	id uint,
+) (domains.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id == 0 || int(id) > len(r.apiKeys) {
+		return domains.APIKey{}, domains.ErrAPIKeyNotFound
+	}
+
+	stored := &r.apiKeys[id-1]
+	if stored.key.RevokedAt == nil {
+		revokedAt := r.now()
+		stored.key.RevokedAt = &revokedAt
+	}
+
+	return stored.key, nil
+}
+
+// columns keeps the fields every table stores, dropping version and
+// updated_at that only some statements read back.
+func columns(character domains.Character) domains.Character {
+	return domains.Character{
+		ID:        character.ID,
+		Name:      character.Name,
+		Ki:        character.Ki,
+		Race:      character.Race,
+		Image:     character.Image,
+		DeletedAt: character.DeletedAt,
+	}
+}
+
+func memoryAuditJSON(character *domains.Character) json.RawMessage {
+	if character == nil {
+		return nil
+	}
+
+	b, _ := json.Marshal(character)
+	return b
+}
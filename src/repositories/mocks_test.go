@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/conformance"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/encilab/dragon-ball/src/domains/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockCharacterStore is a domains.CharacterStore made of the generated mocks.
+type mockCharacterStore struct {
+	*mocks.CharacterRepository
+	*mocks.CharacterTrashRepository
+	*mocks.CharacterAuditRepository
+	*mocks.CharacterVersionRepository
+	*mocks.CharacterArchiveRepository
+	*mocks.CharacterEditRepository
+	*mocks.CharacterSyncRepository
+	domains.Pinger
+}
+
+// Test_MocksConformance wires every generated mock to answer through the
+// memory repository and runs the conformance suite through them, so a mock
+// that drops an argument or a return value, or has drifted from its
+// interface, fails here rather than in the handler tests relying on it.
+func Test_MocksConformance(t *testing.T) {
+	conformance.TestCharacterStore(t, func(t *testing.T, externalAPIURL string) domains.CharacterStore {
+		repo := NewMemoryCharacterRepository(time.Second)
+		repo.externalAPIURL = externalAPIURL
+
+		characterRepoMock := mocks.NewCharacterRepository(t)
+		characterRepoMock.On("GetCharacterInExternalAPIByName", mock.Anything, mock.Anything).Return(repo.GetCharacterInExternalAPIByName).Maybe()
+		characterRepoMock.On("GetCharacterInDatabaseByName", mock.Anything, mock.Anything).Return(repo.GetCharacterInDatabaseByName).Maybe()
+		characterRepoMock.On("GetCharacterInDatabaseByID", mock.Anything, mock.Anything).Return(repo.GetCharacterInDatabaseByID).Maybe()
+		characterRepoMock.On("SearchCharactersInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.SearchCharactersInDatabase).Maybe()
+		characterRepoMock.On("DeleteCharacterInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.DeleteCharacterInDatabase).Maybe()
+
+		characterTrashRepoMock := mocks.NewCharacterTrashRepository(t)
+		characterTrashRepoMock.On("ListDeletedCharactersInDatabase", mock.Anything, mock.Anything).Return(repo.ListDeletedCharactersInDatabase).Maybe()
+		characterTrashRepoMock.On("RestoreCharacterInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.RestoreCharacterInDatabase).Maybe()
+		characterTrashRepoMock.On("PurgeDeletedCharactersInDatabase", mock.Anything, mock.Anything).Return(repo.PurgeDeletedCharactersInDatabase).Maybe()
+
+		characterAuditRepoMock := mocks.NewCharacterAuditRepository(t)
+		characterAuditRepoMock.On("ListCharacterHistoryInDatabase", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(repo.ListCharacterHistoryInDatabase).Maybe()
+
+		characterVersionRepoMock := mocks.NewCharacterVersionRepository(t)
+		characterVersionRepoMock.On("GetCharacterVersionInDatabaseAsOf", mock.Anything, mock.Anything, mock.Anything).Return(repo.GetCharacterVersionInDatabaseAsOf).Maybe()
+		characterVersionRepoMock.On("GetCharacterVersionInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.GetCharacterVersionInDatabase).Maybe()
+
+		characterArchiveRepoMock := mocks.NewCharacterArchiveRepository(t)
+		characterArchiveRepoMock.On("ExportCharactersInDatabase", mock.Anything).Return(repo.ExportCharactersInDatabase).Maybe()
+		characterArchiveRepoMock.On("ImportCharactersInDatabase", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(repo.ImportCharactersInDatabase).Maybe()
+
+		characterEditRepoMock := mocks.NewCharacterEditRepository(t)
+		characterEditRepoMock.On("PatchCharacterInDatabase", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(repo.PatchCharacterInDatabase).Maybe()
+
+		characterSyncRepoMock := mocks.NewCharacterSyncRepository(t)
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByName", mock.Anything, mock.Anything).Return(repo.FetchCharacterInExternalAPIByName).Maybe()
+		characterSyncRepoMock.On("FetchCharacterInExternalAPIByID", mock.Anything, mock.Anything).Return(repo.FetchCharacterInExternalAPIByID).Maybe()
+		characterSyncRepoMock.On("SyncCharacterInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.SyncCharacterInDatabase).Maybe()
+		characterSyncRepoMock.On("SyncCharactersInDatabase", mock.Anything, mock.Anything, mock.Anything).Return(repo.SyncCharactersInDatabase).Maybe()
+
+		return mockCharacterStore{
+			CharacterRepository:        characterRepoMock,
+			CharacterTrashRepository:   characterTrashRepoMock,
+			CharacterAuditRepository:   characterAuditRepoMock,
+			CharacterVersionRepository: characterVersionRepoMock,
+			CharacterArchiveRepository: characterArchiveRepoMock,
+			CharacterEditRepository:    characterEditRepoMock,
+			CharacterSyncRepository:    characterSyncRepoMock,
+			Pinger:                     repo,
+		}
+	})
+}
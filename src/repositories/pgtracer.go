@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PostgresTracer traces every statement pgx sends, the ones run through the
+// database/sql view of a pool included, as a client span holding its SQL.
+// The arguments of the statements are never recorded.
+type PostgresTracer struct {
+	tracer trace.Tracer
+}
+
+func NewPostgresTracer(tracer trace.Tracer) *PostgresTracer {
+	return &PostgresTracer{
+		tracer: tracer,
+	}
+}
+
+var (
+	_ pgx.QueryTracer    = (*PostgresTracer)(nil)
+	_ pgx.BatchTracer    = (*PostgresTracer)(nil)
+	_ pgx.CopyFromTracer = (*PostgresTracer)(nil)
+)
+
+func (t *PostgresTracer) TraceQueryStart(
+	ctx context.Context,
+	conn *pgx.Conn,
+	data pgx.TraceQueryStartData,
+) context.Context {
+	ctx, _ = t.start(ctx, conn, sqlOperation(data.SQL), attribute.String("db.query.text", data.SQL))
+	return ctx
+}
+
+func (t *PostgresTracer) TraceQueryEnd(
+	ctx context.Context,
+	_ *pgx.Conn,
+	data pgx.TraceQueryEndData,
+) {
+	endSpan(ctx, data.Err, attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *PostgresTracer) TraceBatchStart(
+	ctx context.Context,
+	conn *pgx.Conn,
+	data pgx.TraceBatchStartData,
+) context.Context {
+	ctx, _ = t.start(ctx, conn, "BATCH", attribute.Int("db.operation.batch.size", data.Batch.Len()))
+	return ctx
+}
+
+func (t *PostgresTracer) TraceBatchQuery(
+	ctx context.Context,
+	_ *pgx.Conn,
+	data pgx.TraceBatchQueryData,
+) {
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(attribute.String("db.query.text", data.SQL)))
+	if data.Err != nil {
+		trace.SpanFromContext(ctx).RecordError(data.Err)
+	}
+}
+
+func (t *PostgresTracer) TraceBatchEnd(
+	ctx context.Context,
+	_ *pgx.Conn,
+	data pgx.TraceBatchEndData,
+) {
+	endSpan(ctx, data.Err)
+}
+
+func (t *PostgresTracer) TraceCopyFromStart(
+	ctx context.Context,
+	conn *pgx.Conn,
+	data pgx.TraceCopyFromStartData,
+) context.Context {
+	ctx, _ = t.start(ctx, conn, "COPY", attribute.String("db.collection.name", data.TableName.Sanitize()))
+	return ctx
+}
+
+func (t *PostgresTracer) TraceCopyFromEnd(
+	ctx context.Context,
+	_ *pgx.Conn,
+	data pgx.TraceCopyFromEndData,
+) {
+	endSpan(ctx, data.Err, attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+func (t *PostgresTracer) start(
+	ctx context.Context,
+	conn *pgx.Conn,
+	operation string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	attributes = append(attributes,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation.name", operation),
+	)
+	if conn != nil {
+		attributes = append(attributes,
+			attribute.String("db.namespace", conn.Config().Database),
+			attribute.String("server.address", conn.Config().Host),
+			attribute.Int("server.port", int(conn.Config().Port)),
+		)
+	}
+
+	return t.tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func endSpan(
+	ctx context.Context,
+	err error,
+	attributes ...attribute.KeyValue,
+) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attributes...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlOperation answers the first keyword of query, such as SELECT or BEGIN.
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "postgresql"
+	}
+
+	return strings.ToUpper(fields[0])
+}
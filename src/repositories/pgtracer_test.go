@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_PostgresTracer(t *testing.T) {
+	newTracer := func() (*PostgresTracer, *tracetest.InMemoryExporter) {
+		exporter := tracetest.NewInMemoryExporter()
+		tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		return NewPostgresTracer(tracerProvider.Tracer("test")), exporter
+	}
+
+	t.Run("execute a query and trace it as a client span holding its SQL", func(t *testing.T) {
+		tracer, exporter := newTracer()
+		query := `select "id" FROM "character_dragonball" WHERE "name" = $1`
+
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: query, Args: []any{"goku"}})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "SELECT", spans[0].Name)
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		assert.ElementsMatch(t, []attribute.KeyValue{
+			attribute.String("db.query.text", query),
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", "SELECT"),
+			attribute.Int64("db.response.rows_affected", 1),
+		}, spans[0].Attributes)
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+
+	t.Run("execute a failing copy and mark its span as an error", func(t *testing.T) {
+		tracer, exporter := newTracer()
+
+		ctx := tracer.TraceCopyFromStart(context.Background(), nil, pgx.TraceCopyFromStartData{TableName: pgx.Identifier{"character_dragonball"}})
+		tracer.TraceCopyFromEnd(ctx, nil, pgx.TraceCopyFromEndData{Err: errors.New("connection reset")})
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "COPY", spans[0].Name)
+		assert.Contains(t, spans[0].Attributes, attribute.String("db.collection.name", `"character_dragonball"`))
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Equal(t, "connection reset", spans[0].Status.Description)
+	})
+
+	t.Run("execute a batch and record each of its queries as an event", func(t *testing.T) {
+		tracer, exporter := newTracer()
+		batch := &pgx.Batch{}
+		batch.Queue(`SELECT pg_notify($1, $2)`, "a", "b")
+
+		ctx := tracer.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{Batch: batch})
+		tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: `SELECT pg_notify($1, $2)`})
+		tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "BATCH", spans[0].Name)
+		require.Len(t, spans[0].Events, 1)
+		assert.Contains(t, spans[0].Events[0].Attributes, attribute.String("db.query.text", `SELECT pg_notify($1, $2)`))
+	})
+}
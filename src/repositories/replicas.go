@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ReadReplicas spreads the reads of CharacterRepository over streaming
+// replicas of the primary. A replica lagging more than maxLag, or failing its
+// check, is skipped until a later check finds it in sync again. A principal
+// who wrote reads from the primary for stickiness afterwards, so they see
+// their own writes even while the replicas have not replayed them yet.
+type ReadReplicas struct {
+	replicas      []*readReplica
+	stickiness    time.Duration
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          atomic.Uint64
+	now           func() time.Time
+
+	mu         sync.Mutex
+	lastWrites map[string]time.Time
+}
+
+type readReplica struct {
+	sqlClient *sql.DB
+	usable    atomic.Bool
+}
+
+func NewReadReplicas(
+	pools []*pgxpool.Pool,
+	stickiness time.Duration,
+	maxLag time.Duration,
+	checkInterval time.Duration,
+) *ReadReplicas {
+	sqlClients := make([]*sql.DB, 0, len(pools))
+	for _, pool := range pools {
+		sqlClients = append(sqlClients, stdlib.OpenDBFromPool(pool))
+	}
+
+	return newReadReplicas(sqlClients, stickiness, maxLag, checkInterval)
+}
+
+func newReadReplicas(
+	sqlClients []*sql.DB,
+	stickiness time.Duration,
+	maxLag time.Duration,
+	checkInterval time.Duration,
+) *ReadReplicas {
+	replicas := make([]*readReplica, 0, len(sqlClients))
+	for _, sqlClient := range sqlClients {
+		replica := &readReplica{sqlClient: sqlClient}
+		replica.usable.Store(true)
+		replicas = append(replicas, replica)
+	}
+
+	return &ReadReplicas{
+		replicas:      replicas,
+		stickiness:    stickiness,
+		maxLag:        maxLag,
+		checkInterval: checkInterval,
+		now:           time.Now,
+		lastWrites:    map[string]time.Time{},
+	}
+}
+
+// Run checks the lag of every replica each checkInterval until ctx is done.
+func (rr *ReadReplicas) Run(ctx context.Context) {
+	ticker := time.NewTicker(rr.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		rr.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rr *ReadReplicas) check(ctx context.Context) {
+	for i, replica := range rr.replicas {
+		ctxTimeout, cancel := context.WithTimeout(ctx, rr.checkInterval)
+		lag, err := replicationLag(ctxTimeout, replica.sqlClient)
+		cancel()
+
+		usable := err == nil && lag <= rr.maxLag
+		if replica.usable.Swap(usable) == usable {
+			continue
+		}
+		logger := domains.LoggerFromContext(ctx).With("replica", i)
+		switch {
+		case err != nil:
+			logger.Error("error when execute ReadReplicas.check, reading from the primary", "err", err)
+		case !usable:
+			logger.Warn("replica lags behind the primary, reading from the primary", "lag", lag)
+		default:
+			logger.Info("replica is back in sync, reading from it")
+		}
+	}
+}
+
+// replicationLag is how far behind the primary the replica has replayed. A
+// replica with nothing left to replay is not behind, however long ago the
+// last write was.
+func replicationLag(ctx context.Context, sqlClient *sql.DB) (time.Duration, error) {
+	var seconds float64
+	err := sqlClient.QueryRowContext(
+		ctx,
+		`SELECT CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0) END`,
+	).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// wrote starts the stickiness of the principal of ctx.
+func (rr *ReadReplicas) wrote(ctx context.Context) {
+	client, ok := stickinessKey(ctx)
+	if rr.stickiness <= 0 || !ok {
+		return
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	now := rr.now()
+	for client, at := range rr.lastWrites {
+		if now.Sub(at) >= rr.stickiness {
+			delete(rr.lastWrites, client)
+		}
+	}
+	rr.lastWrites[client] = now
+}
+
+// stickinessKey answers who the reads and writes of ctx come from. Requests
+// without a principal share their actor with every other anonymous caller,
+// so they are not kept on the primary.
+func stickinessKey(ctx context.Context) (string, bool) {
+	principal, ok := domains.PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	return principal.Actor(), true
+}
+
+type primaryReadsKey struct{}
+
+// withPrimaryReads sends every read made with the returned context to the
+// primary.
+func withPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// reader answers the replica the next read of the principal of ctx goes to, or
+// nil when it has to go to the primary.
+func (rr *ReadReplicas) reader(ctx context.Context) *readReplica {
+	if primary, _ := ctx.Value(primaryReadsKey{}).(bool); primary || rr.sticky(ctx) {
+		return nil
+	}
+
+	start := rr.next.Add(1)
+	for i := range rr.replicas {
+		replica := rr.replicas[(start+uint64(i))%uint64(len(rr.replicas))]
+		if replica.usable.Load() {
+			return replica
+		}
+	}
+
+	return nil
+}
+
+func (rr *ReadReplicas) sticky(ctx context.Context) bool {
+	client, ok := stickinessKey(ctx)
+	if !ok {
+		return false
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	at, ok := rr.lastWrites[client]
+	return ok && rr.now().Sub(at) < rr.stickiness
+}
+
+// read runs query on a replica, and on the primary when there is none to
+// read from or the replica fails. A replica that fails is skipped until its
+// next check.
+func (r *CharacterRepository) read(
+	ctx context.Context,
+	query func(sqlClient *sql.DB) error,
+) error {
+	if r.readReplicas == nil {
+		return query(r.sqlClient)
+	}
+
+	replica := r.readReplicas.reader(ctx)
+	if replica == nil {
+		return query(r.sqlClient)
+	}
+
+	err := query(replica.sqlClient)
+	if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return err
+	}
+
+	domains.LoggerFromContext(ctx).Error("error when execute CharacterRepository.read on a replica, reading from the primary", "err", err)
+	replica.usable.Store(false)
+
+	return query(r.sqlClient)
+}
+
+// wrote keeps the actor of ctx on the primary for the stickiness of the
+// replicas.
+func (r *CharacterRepository) wrote(ctx context.Context) {
+	if r.readReplicas != nil {
+		r.readReplicas.wrote(ctx)
+	}
+}
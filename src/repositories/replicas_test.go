@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const replicationLagQuery = `SELECT CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0) END`
+
+func Test_ReadReplicas(t *testing.T) {
+	t.Run("execute reads and spread them over the usable replicas", func(t *testing.T) {
+		first, second := &sql.DB{}, &sql.DB{}
+		rr := newReadReplicas([]*sql.DB{first, second}, time.Second, time.Second, time.Second)
+
+		ctx := context.Background()
+		a, b := rr.reader(ctx), rr.reader(ctx)
+		require.NotNil(t, a)
+		require.NotNil(t, b)
+		assert.NotSame(t, a.sqlClient, b.sqlClient)
+
+		rr.replicas[0].usable.Store(false)
+		assert.Same(t, second, rr.reader(ctx).sqlClient)
+		assert.Same(t, second, rr.reader(ctx).sqlClient)
+
+		rr.replicas[1].usable.Store(false)
+		assert.Nil(t, rr.reader(ctx))
+	})
+
+	t.Run("execute reads after a write and keep the writer on the primary until the stickiness passes", func(t *testing.T) {
+		rr := newReadReplicas([]*sql.DB{{}}, 5*time.Second, time.Second, time.Second)
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		rr.now = func() time.Time { return now }
+
+		goku := domains.WithPrincipal(context.Background(), domains.Principal{Name: "goku", Method: domains.AuthMethodAPIKey})
+		vegeta := domains.WithPrincipal(context.Background(), domains.Principal{Name: "vegeta", Method: domains.AuthMethodJWT})
+
+		rr.wrote(goku)
+		assert.Nil(t, rr.reader(goku))
+		assert.NotNil(t, rr.reader(vegeta))
+
+		now = now.Add(5 * time.Second)
+		assert.NotNil(t, rr.reader(goku))
+		assert.Nil(t, rr.reader(withPrimaryReads(goku)))
+	})
+
+	t.Run("execute reads after an anonymous write and keep every anonymous reader on the replicas", func(t *testing.T) {
+		rr := newReadReplicas([]*sql.DB{{}}, 5*time.Second, time.Second, time.Second)
+
+		anonymous := domains.WithAuditor(context.Background(), "ip:10.0.0.1", domains.AuditSourceAPI)
+
+		rr.wrote(anonymous)
+		assert.NotNil(t, rr.reader(anonymous))
+		assert.Empty(t, rr.lastWrites)
+	})
+
+	t.Run("execute checks and skip a replica while it lags or fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(replicationLagQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(30.5))
+		mock.ExpectQuery(regexp.QuoteMeta(replicationLagQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.2))
+		mock.ExpectQuery(regexp.QuoteMeta(replicationLagQuery)).
+			WillReturnError(errors.New("connection refused"))
+
+		rr := newReadReplicas([]*sql.DB{db}, time.Second, 10*time.Second, time.Second)
+		ctx := context.Background()
+
+		rr.check(ctx)
+		assert.Nil(t, rr.reader(ctx))
+		rr.check(ctx)
+		assert.NotNil(t, rr.reader(ctx))
+		rr.check(ctx)
+		assert.Nil(t, rr.reader(ctx))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_ReadFromReplicas(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at" FROM "character_dragonball" WHERE "id" = $1`)
+	columns := []string{"id", "name", "ki", "race", "image", "deleted_at", "version", "updated_at"}
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("execute get by id on a replica and success", func(t *testing.T) {
+		primary, primaryMock, err := sqlmock.New()
+		require.NoError(t, err)
+		replica, replicaMock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		replicaMock.ExpectQuery(query).WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", nil, 3, updatedAt))
+
+		repo := newCharacterRepository(primary, 1000*time.Millisecond, postgresDialect)
+		repo.readReplicas = newReadReplicas([]*sql.DB{replica}, time.Second, time.Second, time.Second)
+		character, err := repo.GetCharacterInDatabaseByID(context.Background(), 1)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, character.Version)
+	})
+
+	t.Run("execute get by id of a missing character on a replica and return ErrCharacterNotFoundInDatabase", func(t *testing.T) {
+		primary, primaryMock, err := sqlmock.New()
+		require.NoError(t, err)
+		replica, replicaMock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		replicaMock.ExpectQuery(query).WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		repo := newCharacterRepository(primary, 1000*time.Millisecond, postgresDialect)
+		repo.readReplicas = newReadReplicas([]*sql.DB{replica}, time.Second, time.Second, time.Second)
+		_, err = repo.GetCharacterInDatabaseByID(context.Background(), 1)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+
+	t.Run("execute get by id on a failing replica and fall back to the primary", func(t *testing.T) {
+		primary, primaryMock, err := sqlmock.New()
+		require.NoError(t, err)
+		replica, replicaMock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		replicaMock.ExpectQuery(query).WithArgs(uint(1)).
+			WillReturnError(errors.New("connection refused"))
+		primaryMock.ExpectQuery(query).WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", nil, 3, updatedAt))
+
+		repo := newCharacterRepository(primary, 1000*time.Millisecond, postgresDialect)
+		repo.readReplicas = newReadReplicas([]*sql.DB{replica}, time.Second, time.Second, time.Second)
+		character, err := repo.GetCharacterInDatabaseByID(context.Background(), 1)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), character.ID)
+		assert.Nil(t, repo.readReplicas.reader(context.Background()))
+	})
+
+	t.Run("execute SQLClients and name the primary and every replica", func(t *testing.T) {
+		primary, first, second := &sql.DB{}, &sql.DB{}, &sql.DB{}
+
+		repo := newCharacterRepository(primary, 1000*time.Millisecond, postgresDialect)
+		repo.readReplicas = newReadReplicas([]*sql.DB{first, second}, time.Second, time.Second, time.Second)
+
+		assert.Equal(t, map[string]*sql.DB{
+			"primary":   primary,
+			"replica-0": first,
+			"replica-1": second,
+		}, repo.SQLClients())
+	})
+
+	t.Run("execute search after a write of the same principal on the primary", func(t *testing.T) {
+		primary, primaryMock, err := sqlmock.New()
+		require.NoError(t, err)
+		replica, replicaMock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		primaryMock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, ki, race, image, deleted_at, updated_at FROM "character_dragonball" WHERE deleted_at IS NULL ORDER BY id`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "updated_at"}).
+				AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", nil, updatedAt))
+
+		repo := newCharacterRepository(primary, 1000*time.Millisecond, postgresDialect)
+		repo.readReplicas = newReadReplicas([]*sql.DB{replica}, time.Minute, time.Second, time.Second)
+		ctx := domains.WithPrincipal(context.Background(), domains.Principal{Name: "goku", Method: domains.AuthMethodAPIKey})
+		repo.wrote(ctx)
+		characters, err := repo.SearchCharactersInDatabase(ctx, 10, false)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Len(t, characters, 1)
+	})
+}
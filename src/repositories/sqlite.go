@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	_ "embed"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dialect is what the SQL of CharacterRepository needs to vary between
+// Postgres and SQLite; every other statement runs unchanged on both.
+type dialect struct {
+	lockRows          string
+	isUniqueViolation func(err error) bool
+	utcTimes          bool
+}
+
+var postgresDialect = dialect{
+	lockRows:          " FOR UPDATE",
+	isUniqueViolation: isPostgresUniqueViolation,
+}
+
+// SQLite has no row locks, a transaction writing takes the whole database,
+// and it compares timestamps as text, which only orders them in one zone.
+var sqliteDialect = dialect{
+	lockRows:          "",
+	isUniqueViolation: isSQLiteUniqueViolation,
+	utcTimes:          true,
+}
+
+// pgUniqueViolation is the SQLSTATE of unique_violation.
+const pgUniqueViolation = "23505"
+
+func isPostgresUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func (d dialect) timeArg(t time.Time) time.Time {
+	if d.utcTimes {
+		return t.UTC()
+	}
+
+	return t
+}
+
+//go:embed sqlite.sql
+var sqliteSchema string
+
+// sqliteTimeFormat matches the _time_format=sqlite parameters are written with.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+var registerSQLiteFunctions sync.Once
+
+// NewSQLiteClient opens the embedded SQLite database at path, ":memory:" for
+// one that lives as long as the process, and creates the schema.
+//
+// NOW() and pg_notify() are provided so the Postgres statements run as they
+// are; there are no other replicas to notify, so pg_notify does nothing.
+func NewSQLiteClient(path string) (*sql.DB, error) {
+	registerSQLiteFunctions.Do(func() {
+		sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			return time.Now().UTC().Format(sqliteTimeFormat), nil
+		})
+		sqlite.MustRegisterScalarFunction("pg_notify", 2, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			return nil, nil
+		})
+	})
+
+	sqlClient, err := sql.Open("sqlite", "file:"+path+"?_time_format=sqlite&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// One connection serialises the writers and keeps a ":memory:" database
+	// alive, since each connection would otherwise open its own.
+	sqlClient.SetMaxOpenConns(1)
+	sqlClient.SetConnMaxLifetime(0)
+	sqlClient.SetConnMaxIdleTime(0)
+
+	if _, err := sqlClient.Exec(sqliteSchema); err != nil {
+		sqlClient.Close()
+		return nil, err
+	}
+
+	return sqlClient, nil
+}
+
+func NewSQLiteCharacterRepository(
+	sqlClient *sql.DB,
+	clientTimeout time.Duration,
+) *CharacterRepository {
+
+	return newCharacterRepository(sqlClient, clientTimeout, sqliteDialect)
+}
@@ -0,0 +1,84 @@
+-- The SQLite counterpart of conf/init.sql, kept in step with it.
+CREATE TABLE IF NOT EXISTS character_dragonball (
+	id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	ki TEXT NOT NULL,
+	race TEXT NOT NULL,
+	image TEXT NOT NULL,
+	deleted_at DATETIME NULL,
+	version INTEGER NOT NULL DEFAULT 1,
+	overridden_fields TEXT NOT NULL DEFAULT '[]',
+	updated_at DATETIME NOT NULL DEFAULT (NOW()),
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_character_name ON character_dragonball (name);
+
+CREATE INDEX IF NOT EXISTS idx_character_deleted_at ON character_dragonball (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TRIGGER IF NOT EXISTS trg_character_dragonball_touch
+	AFTER UPDATE ON character_dragonball
+	FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE character_dragonball SET updated_at = NOW() WHERE id = NEW.id;
+END;
+
+CREATE TABLE IF NOT EXISTS character_audit (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	character_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	source TEXT NOT NULL,
+	"before" TEXT NULL,
+	"after" TEXT NULL,
+	created_at DATETIME NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX IF NOT EXISTS idx_character_audit_character_id ON character_audit (character_id, id);
+
+CREATE TRIGGER IF NOT EXISTS trg_character_audit_append_only_update
+	BEFORE UPDATE ON character_audit
+BEGIN
+	SELECT RAISE(ABORT, 'character_audit is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_character_audit_append_only_delete
+	BEFORE DELETE ON character_audit
+BEGIN
+	SELECT RAISE(ABORT, 'character_audit is append-only');
+END;
+
+CREATE TABLE IF NOT EXISTS character_version (
+	character_id INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	ki TEXT NOT NULL,
+	race TEXT NOT NULL,
+	image TEXT NOT NULL,
+	deleted_at DATETIME NULL,
+	valid_from DATETIME NOT NULL,
+	valid_to DATETIME NULL,
+	PRIMARY KEY (character_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_character_version_open ON character_version (character_id) WHERE valid_to IS NULL;
+
+CREATE TABLE IF NOT EXISTS api_key (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	key_hash TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT (NOW()),
+	revoked_at DATETIME NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_name ON api_key (name);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_key_hash ON api_key (key_hash);
+
+CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER NOT NULL
+);
+
+DELETE FROM schema_version;
+INSERT INTO schema_version (version) VALUES (2);
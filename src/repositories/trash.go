@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func (r *CharacterRepository) ListDeletedCharactersInDatabase(
+	ctx context.Context,
+	limit int,
+) ([]domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	rows, err := r.sqlClient.QueryContext(
+		ctxTimeout,
+		`SELECT "id", "name", "ki", "race", "image", "deleted_at" FROM "character_dragonball" WHERE "deleted_at" IS NOT NULL ORDER BY "deleted_at" DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []domains.Character{}
+	for rows.Next() {
+		var character domains.Character
+		if err := rows.Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+			&character.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		results = append(results, character)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *CharacterRepository) RestoreCharacterInDatabase(
+	ctx context.Context,
+	id uint,
+	expectedVersion int,
+) (domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return domains.Character{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	var before domains.Character
+	err = tx.QueryRowContext(
+		ctxTimeout,
+		`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "id" = $1 AND "deleted_at" IS NOT NULL`+r.dialect.lockRows,
+		id,
+	).Scan(
+		&before.ID,
+		&before.Name,
+		&before.Ki,
+		&before.Race,
+		&before.Image,
+		&before.DeletedAt,
+		&before.Version,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domains.Character{}, domains.ErrCharacterNotFoundInDatabase
+		}
+		return domains.Character{}, err
+	}
+
+	if expectedVersion != domains.AnyVersion && expectedVersion != before.Version {
+		err = domains.ErrCharacterVersionMismatch
+		return domains.Character{}, err
+	}
+
+	_, err = tx.ExecContext(
+		ctxTimeout,
+		`UPDATE "character_dragonball" SET "deleted_at" = NULL, "version" = "version" + 1 WHERE "id" = $1`,
+		id,
+	)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	character := before
+	character.DeletedAt = nil
+	character.Version++
+	err = recordChange(ctxTimeout, tx, id, domains.AuditActionRestore, &before, &character)
+	if err != nil {
+		return domains.Character{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domains.Character{}, err
+	}
+	r.wrote(ctx)
+
+	return character, nil
+}
+
+func (r *CharacterRepository) PurgeDeletedCharactersInDatabase(
+	ctx context.Context,
+	deletedBefore time.Time,
+) ([]domains.Character, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	tx, err := r.sqlClient.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				domains.LoggerFromContext(ctx).Error("error when execute tx.Rollback", "err", rbErr)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(
+		ctxTimeout,
+		`DELETE FROM "character_dragonball" WHERE "deleted_at" IS NOT NULL AND "deleted_at" < $1 RETURNING "id", "name", "ki", "race", "image", "deleted_at"`,
+		r.dialect.timeArg(deletedBefore),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := []domains.Character{}
+	for rows.Next() {
+		var character domains.Character
+		if err = rows.Scan(
+			&character.ID,
+			&character.Name,
+			&character.Ki,
+			&character.Race,
+			&character.Image,
+			&character.DeletedAt,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		purged = append(purged, character)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range purged {
+		err = recordChange(ctxTimeout, tx, purged[i].ID, domains.AuditActionPurge, &purged[i], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.wrote(ctx)
+
+	return purged, nil
+}
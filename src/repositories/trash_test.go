@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListDeletedCharactersInDatabase(t *testing.T) {
+	t.Run("execute list and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", time.Now())
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at" FROM "character_dragonball" WHERE "deleted_at" IS NOT NULL ORDER BY "deleted_at" DESC LIMIT $1`),
+		).WithArgs(10).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		results, err := repo.ListDeletedCharactersInDatabase(context.Background(), 10)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.NotNil(t, results[0].DeletedAt)
+	})
+}
+
+func Test_RestoreCharacterInDatabase(t *testing.T) {
+	t.Run("execute restore and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", time.Now(), 2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "id" = $1 AND "deleted_at" IS NOT NULL FOR UPDATE`),
+		).WithArgs(uint(1)).
+			WillReturnRows(rows)
+		mock.ExpectExec(
+			regexp.QuoteMeta(`UPDATE "character_dragonball" SET "deleted_at" = NULL, "version" = "version" + 1 WHERE "id" = $1`),
+		).WithArgs(uint(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(1), "restore", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_version"`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		character, err := repo.RestoreCharacterInDatabase(context.Background(), 1, 2)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Nil(t, character.DeletedAt)
+	})
+
+	t.Run("execute restore of a character not in the trash and return not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "id" = $1`),
+		).WithArgs(uint(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}))
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.RestoreCharacterInDatabase(context.Background(), 1, domains.AnyVersion)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterNotFoundInDatabase)
+	})
+
+	t.Run("execute restore with a stale version and return version mismatch", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at", "version"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", time.Now(), 2)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "id", "name", "ki", "race", "image", "deleted_at", "version" FROM "character_dragonball" WHERE "id" = $1 AND "deleted_at" IS NOT NULL FOR UPDATE`),
+		).WithArgs(uint(1)).
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.RestoreCharacterInDatabase(context.Background(), 1, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionMismatch)
+	})
+}
+
+func Test_PurgeDeletedCharactersInDatabase(t *testing.T) {
+	t.Run("execute purge and success", func(t *testing.T) {
+		deletedBefore := time.Now().Add(-time.Hour)
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "ki", "race", "image", "deleted_at"}).
+			AddRow(1, "goku", "60.000.000", "Saiyan", "goku.webp", deletedBefore.Add(-time.Hour)).
+			AddRow(2, "vegeta", "54.000.000", "Saiyan", "vegeta.webp", deletedBefore.Add(-time.Hour))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`DELETE FROM "character_dragonball" WHERE "deleted_at" IS NOT NULL AND "deleted_at" < $1 RETURNING "id", "name", "ki", "race", "image", "deleted_at"`),
+		).WithArgs(deletedBefore).
+			WillReturnRows(rows)
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(1), "purge", "system", "purge", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "character_audit"`)).
+			WithArgs(uint(2), "purge", "system", "purge", sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "character_version" SET "valid_to" = NOW()`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+			WithArgs(domains.CharacterChangesChannel, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		ctx := domains.WithAuditor(context.Background(), "system", domains.AuditSourcePurge)
+		purged, err := repo.PurgeDeletedCharactersInDatabase(ctx, deletedBefore)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		require.Len(t, purged, 2)
+		assert.Equal(t, "goku", purged[0].Name)
+		assert.Equal(t, "vegeta", purged[1].Name)
+	})
+}
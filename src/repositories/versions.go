@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+func (r *CharacterRepository) GetCharacterVersionInDatabaseAsOf(
+	ctx context.Context,
+	id uint,
+	asOf time.Time,
+) (domains.CharacterVersion, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	var characterVersion domains.CharacterVersion
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		return scanCharacterVersion(sqlClient.QueryRowContext(
+			ctxTimeout,
+			`SELECT "character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to" FROM "character_version" WHERE "character_id" = $1 AND "valid_from" <= $2 AND ("valid_to" IS NULL OR "valid_to" > $2)`,
+			id,
+			r.dialect.timeArg(asOf),
+		), &characterVersion)
+	})
+
+	if err != nil {
+		return domains.CharacterVersion{}, characterVersionError(err)
+	}
+
+	return characterVersion, nil
+}
+
+func (r *CharacterRepository) GetCharacterVersionInDatabase(
+	ctx context.Context,
+	id uint,
+	version int,
+) (domains.CharacterVersion, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, r.clientTimeout)
+	defer cancel()
+
+	var characterVersion domains.CharacterVersion
+	err := r.read(ctxTimeout, func(sqlClient *sql.DB) error {
+		return scanCharacterVersion(sqlClient.QueryRowContext(
+			ctxTimeout,
+			`SELECT "character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to" FROM "character_version" WHERE "character_id" = $1 AND "version" = $2`,
+			id,
+			version,
+		), &characterVersion)
+	})
+
+	if err != nil {
+		return domains.CharacterVersion{}, characterVersionError(err)
+	}
+
+	return characterVersion, nil
+}
+
+func scanCharacterVersion(row *sql.Row, characterVersion *domains.CharacterVersion) error {
+	return row.Scan(
+		&characterVersion.ID,
+		&characterVersion.Version,
+		&characterVersion.Name,
+		&characterVersion.Ki,
+		&characterVersion.Race,
+		&characterVersion.Image,
+		&characterVersion.DeletedAt,
+		&characterVersion.ValidFrom,
+		&characterVersion.ValidTo,
+	)
+}
+
+func characterVersionError(err error) error {
+	if err == sql.ErrNoRows {
+		return domains.ErrCharacterVersionNotFound
+	}
+
+	return err
+}
+
+// nextVersion is the version of a new row of character $1: the one after the
+// versions the character had before a purge, or 1.
+const nextVersion = `(SELECT COALESCE(MAX("version"), 0) + 1 FROM "character_version" WHERE "character_id" = $1)`
+
+// recordChange appends the audit entry and the new version of a character in
+// the transaction of the write, and announces it to the other replicas. The
+// version is numbered after.Version, the version of the row once written. A
+// nil after closes the open version without opening another one, which is
+// what a purge does.
+func recordChange(
+	ctx context.Context,
+	e execer,
+	characterID uint,
+	action domains.AuditAction,
+	before *domains.Character,
+	after *domains.Character,
+) error {
+	if err := insertAudit(ctx, e, characterID, action, before, after); err != nil {
+		return err
+	}
+
+	_, err := e.ExecContext(
+		ctx,
+		`UPDATE "character_version" SET "valid_to" = NOW() WHERE "character_id" = $1 AND "valid_to" IS NULL`,
+		characterID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if after != nil {
+		_, err = e.ExecContext(
+			ctx,
+			`INSERT INTO "character_version" ("character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from") VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())`,
+			characterID,
+			after.Version,
+			after.Name,
+			after.Ki,
+			after.Race,
+			after.Image,
+			after.DeletedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return notifyCharacterChange(ctx, e, domains.NewCharacterChange(characterID, action, before, after))
+}
+
+// notifyCharacterChange announces the write on CharacterChangesChannel.
+// Postgres holds the notification until the transaction commits and drops it
+// on rollback, so listeners only hear about writes that happened.
+func notifyCharacterChange(
+	ctx context.Context,
+	e execer,
+	change domains.CharacterChange,
+) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(
+		ctx,
+		`SELECT pg_notify($1, $2)`,
+		domains.CharacterChangesChannel,
+		string(payload),
+	)
+
+	return err
+}
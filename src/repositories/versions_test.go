@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetCharacterVersionInDatabaseAsOf(t *testing.T) {
+	asOf := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("execute get as of and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to"}).
+			AddRow(1, 2, "goku", "60.000.000", "Saiyan", "goku.webp", nil, asOf.Add(-time.Hour), asOf.Add(time.Hour))
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to" FROM "character_version" WHERE "character_id" = $1 AND "valid_from" <= $2 AND ("valid_to" IS NULL OR "valid_to" > $2)`),
+		).WithArgs(uint(1), asOf).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		characterVersion, err := repo.GetCharacterVersionInDatabaseAsOf(context.Background(), 1, asOf)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, characterVersion.Version)
+		assert.Equal(t, uint(1), characterVersion.ID)
+		assert.NotNil(t, characterVersion.ValidTo)
+	})
+
+	t.Run("execute get before the first version and return not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`FROM "character_version" WHERE "character_id" = $1 AND "valid_from" <= $2`),
+		).WithArgs(uint(1), asOf).
+			WillReturnRows(sqlmock.NewRows([]string{"character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to"}))
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		_, err = repo.GetCharacterVersionInDatabaseAsOf(context.Background(), 1, asOf)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.ErrorIs(t, err, domains.ErrCharacterVersionNotFound)
+	})
+}
+
+func Test_GetCharacterVersionInDatabase(t *testing.T) {
+	t.Run("execute get by version and success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to"}).
+			AddRow(1, 1, "goku", "60.000.000", "Saiyan", "goku.webp", nil, time.Now(), nil)
+
+		mock.ExpectQuery(
+			regexp.QuoteMeta(`SELECT "character_id", "version", "name", "ki", "race", "image", "deleted_at", "valid_from", "valid_to" FROM "character_version" WHERE "character_id" = $1 AND "version" = $2`),
+		).WithArgs(uint(1), 1).
+			WillReturnRows(rows)
+
+		repo := newCharacterRepository(db, 1000*time.Millisecond, postgresDialect)
+		characterVersion, err := repo.GetCharacterVersionInDatabase(context.Background(), 1, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, err)
+		assert.Nil(t, characterVersion.ValidTo)
+	})
+}
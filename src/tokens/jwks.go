@@ -0,0 +1,302 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+)
+
+var ErrKeyNotFound = errors.New("no key of the JWKS has the kid of the token")
+var ErrJWKSIsEmpty = errors.New("JWKS has no RSA or P-256 signing key")
+
+// KeySet answers the public key a token was signed with, by the kid of its
+// header. A token without kid is verified with the only key of the set, if
+// it has a single one.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS reads the RSA and P-256 signing keys of a JWKS, as RFC 7517
+// describes it, by kid. Keys of other types and encryption keys are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("JWKS is not valid json: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch {
+		case k.Kty == "RSA":
+			key, err = rsaPublicKey(k)
+		case k.Kty == "EC" && k.Crv == "P-256":
+			key, err = ecdsaPublicKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %d of the JWKS: %w", i, err)
+		}
+
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrJWKSIsEmpty
+	}
+
+	return keys, nil
+}
+
+func rsaPublicKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("n is not a base64url integer")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("e is not a base64url integer")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func ecdsaPublicKey(k jwk) (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, errors.New("x is not a base64url integer")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, errors.New("y is not a base64url integer")
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	// Checks the point is on the curve.
+	if _, err := key.ECDH(); err != nil {
+		return nil, errors.New("x and y are not a point of P-256")
+	}
+
+	return key, nil
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// StaticKeySet is a JWKS read once, from a file.
+type StaticKeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+func LoadJWKSFile(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeySet{keys: keys}, nil
+}
+
+func (s *StaticKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := lookup(s.keys, kid)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// minRefetchInterval bounds how often tokens with an unknown kid, or an
+// issuer that cannot be reached, make the JWKS be fetched again, so they
+// cannot be used to flood the issuer.
+const minRefetchInterval = 30 * time.Second
+
+// fetchTimeout bounds a fetch of the JWKS, which is shared by every request
+// waiting for it and outlives the one that started it.
+const fetchTimeout = 10 * time.Second
+
+// RemoteKeySet is a JWKS fetched from a URL. It is fetched again once older
+// than refresh, or when a token has a kid it does not know, for keys the
+// issuer rotated in. When the URL cannot be reached, the keys fetched last
+// keep being used.
+type RemoteKeySet struct {
+	client  *http.Client
+	url     string
+	refresh time.Duration
+	now     func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	err         error
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetching    chan struct{}
+}
+
+func NewRemoteKeySet(
+	client *http.Client,
+	url string,
+	refresh time.Duration,
+) *RemoteKeySet {
+
+	return &RemoteKeySet{
+		client:  client,
+		url:     url,
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	_, ok := lookup(s.keys, kid)
+	now := s.now()
+	if s.keys != nil && ok && now.Sub(s.fetchedAt) < s.refresh {
+		defer s.mu.Unlock()
+		return s.answer(kid)
+	}
+
+	fetching := s.fetching
+	if fetching == nil {
+		if now.Sub(s.attemptedAt) < minRefetchInterval {
+			defer s.mu.Unlock()
+			return s.answer(kid)
+		}
+
+		fetching = make(chan struct{})
+		s.fetching = fetching
+		s.attemptedAt = now
+		go s.fetchKeys(context.WithoutCancel(ctx), fetching)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.answer(kid)
+}
+
+// answer looks kid up in the keys fetched last, with s.mu held.
+func (s *RemoteKeySet) answer(kid string) (crypto.PublicKey, error) {
+	if s.keys == nil {
+		return nil, s.err
+	}
+
+	key, ok := lookup(s.keys, kid)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// fetchKeys fetches the JWKS once for every request waiting on done, which
+// it closes when the keys are updated.
+func (s *RemoteKeySet) fetchKeys(ctx context.Context, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	keys, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(done)
+
+	s.fetching = nil
+	if err != nil {
+		if s.keys != nil {
+			domains.LoggerFromContext(ctx).Warn("error when execute RemoteKeySet.fetch, using the keys fetched last", "url", s.url, "err", err)
+		}
+		s.err = err
+		return
+	}
+
+	s.keys = keys
+	s.err = nil
+	s.fetchedAt = s.now()
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS, status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
@@ -0,0 +1,292 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// jwks answers the JWKS of the public halves of keys, by kid.
+func jwks(t *testing.T, keys map[string]crypto.Signer) []byte {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch public := key.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{"kid": kid, "kty": "RSA", "use": "sig", "n": encode(public.N), "e": encode(big.NewInt(int64(public.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": encode(public.X), "y": encode(public.Y)})
+		}
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+func Test_ParseJWKS(t *testing.T) {
+	t.Run("given RSA and P-256 keys, it answers them by kid", func(t *testing.T) {
+		rsaKey := newRSAKey(t)
+		ecdsaKey := newECDSAKey(t)
+
+		keys, err := ParseJWKS(jwks(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecdsaKey}))
+
+		require.NoError(t, err)
+		assert.True(t, rsaKey.PublicKey.Equal(keys["rsa"]))
+		assert.True(t, ecdsaKey.PublicKey.Equal(keys["ec"]))
+	})
+
+	t.Run("given encryption and symmetric keys only, it returns an error", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kid":"a","kty":"oct","k":"c2VjcmV0"},{"kid":"b","kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`))
+
+		assert.ErrorIs(t, err, ErrJWKSIsEmpty)
+	})
+
+	t.Run("given a point that is not on P-256, it returns an error", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kid":"a","kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`))
+
+		assert.ErrorContains(t, err, "not a point of P-256")
+	})
+
+	t.Run("given a body that is not json, it returns an error", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`<html>`))
+
+		assert.ErrorContains(t, err, "JWKS is not valid json")
+	})
+}
+
+func Test_StaticKeySet(t *testing.T) {
+	key := newECDSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(t, map[string]crypto.Signer{"k1": key}), 0o600))
+
+	keySet, err := LoadJWKSFile(path)
+	require.NoError(t, err)
+
+	t.Run("given a known kid, it answers its key", func(t *testing.T) {
+		public, err := keySet.Key(context.Background(), "k1")
+
+		require.NoError(t, err)
+		assert.True(t, key.PublicKey.Equal(public))
+	})
+
+	t.Run("given no kid and a single key, it answers that key", func(t *testing.T) {
+		public, err := keySet.Key(context.Background(), "")
+
+		require.NoError(t, err)
+		assert.True(t, key.PublicKey.Equal(public))
+	})
+
+	t.Run("given an unknown kid, it returns ErrKeyNotFound", func(t *testing.T) {
+		_, err := keySet.Key(context.Background(), "k2")
+
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+}
+
+func Test_RemoteKeySet(t *testing.T) {
+	first := newRSAKey(t)
+	second := newRSAKey(t)
+
+	newServer := func(t *testing.T, fetches *atomic.Int32, body *atomic.Value) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			data, _ := body.Load().([]byte)
+			if data == nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write(data)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("given keys fetched within refresh, it fetches them once", func(t *testing.T) {
+		var fetches atomic.Int32
+		var body atomic.Value
+		body.Store(jwks(t, map[string]crypto.Signer{"k1": first}))
+		server := newServer(t, &fetches, &body)
+
+		keySet := NewRemoteKeySet(server.Client(), server.URL, 10*time.Minute)
+		_, err := keySet.Key(context.Background(), "k1")
+		require.NoError(t, err)
+		public, err := keySet.Key(context.Background(), "k1")
+
+		require.NoError(t, err)
+		assert.True(t, first.PublicKey.Equal(public))
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("given a rotated key, it fetches the JWKS again, at most every minRefetchInterval", func(t *testing.T) {
+		var fetches atomic.Int32
+		var body atomic.Value
+		body.Store(jwks(t, map[string]crypto.Signer{"k1": first}))
+		server := newServer(t, &fetches, &body)
+
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		keySet := NewRemoteKeySet(server.Client(), server.URL, 10*time.Minute)
+		keySet.now = func() time.Time { return now }
+		_, err := keySet.Key(context.Background(), "k1")
+		require.NoError(t, err)
+
+		body.Store(jwks(t, map[string]crypto.Signer{"k1": first, "k2": second}))
+		_, err = keySet.Key(context.Background(), "k2")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		assert.Equal(t, int32(1), fetches.Load())
+
+		now = now.Add(minRefetchInterval)
+		public, err := keySet.Key(context.Background(), "k2")
+		require.NoError(t, err)
+		assert.True(t, second.PublicKey.Equal(public))
+		assert.Equal(t, int32(2), fetches.Load())
+	})
+
+	t.Run("given a JWKS that cannot be fetched again, it keeps the keys fetched last", func(t *testing.T) {
+		var fetches atomic.Int32
+		var body atomic.Value
+		body.Store(jwks(t, map[string]crypto.Signer{"k1": first}))
+		server := newServer(t, &fetches, &body)
+
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		keySet := NewRemoteKeySet(server.Client(), server.URL, time.Minute)
+		keySet.now = func() time.Time { return now }
+		_, err := keySet.Key(context.Background(), "k1")
+		require.NoError(t, err)
+
+		body.Store([]byte(nil))
+		now = now.Add(time.Minute)
+		public, err := keySet.Key(context.Background(), "k1")
+
+		require.NoError(t, err)
+		assert.True(t, first.PublicKey.Equal(public))
+		assert.Equal(t, int32(2), fetches.Load())
+	})
+
+	t.Run("given a JWKS that was never fetched, it returns the error", func(t *testing.T) {
+		var fetches atomic.Int32
+		var body atomic.Value
+		server := newServer(t, &fetches, &body)
+
+		keySet := NewRemoteKeySet(server.Client(), server.URL, time.Minute)
+		_, err := keySet.Key(context.Background(), "k1")
+
+		assert.ErrorContains(t, err, "status code 503")
+		assert.NotErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("given a JWKS that was never fetched, it tries again at most every minRefetchInterval", func(t *testing.T) {
+		var fetches atomic.Int32
+		var body atomic.Value
+		server := newServer(t, &fetches, &body)
+
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		keySet := NewRemoteKeySet(server.Client(), server.URL, time.Minute)
+		keySet.now = func() time.Time { return now }
+		_, err := keySet.Key(context.Background(), "k1")
+		require.ErrorContains(t, err, "status code 503")
+
+		body.Store(jwks(t, map[string]crypto.Signer{"k1": first}))
+		_, err = keySet.Key(context.Background(), "k1")
+		assert.ErrorContains(t, err, "status code 503")
+		assert.Equal(t, int32(1), fetches.Load())
+
+		now = now.Add(minRefetchInterval)
+		public, err := keySet.Key(context.Background(), "k1")
+		require.NoError(t, err)
+		assert.True(t, first.PublicKey.Equal(public))
+		assert.Equal(t, int32(2), fetches.Load())
+	})
+
+	t.Run("given concurrent requests for an unknown kid, it fetches the JWKS once", func(t *testing.T) {
+		var fetches atomic.Int32
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			<-release
+			_, _ = w.Write(jwks(t, map[string]crypto.Signer{"k1": first}))
+		}))
+		t.Cleanup(server.Close)
+
+		keySet := NewRemoteKeySet(server.Client(), server.URL, time.Minute)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := keySet.Key(context.Background(), "k1")
+				errs <- err
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("given a request cancelled while the JWKS is fetched, it keeps fetching for the next one", func(t *testing.T) {
+		var fetches atomic.Int32
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			<-release
+			_, _ = w.Write(jwks(t, map[string]crypto.Signer{"k1": first}))
+		}))
+		t.Cleanup(server.Close)
+
+		keySet := NewRemoteKeySet(server.Client(), server.URL, time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+		_, err := keySet.Key(ctx, "k1")
+		require.ErrorIs(t, err, context.Canceled)
+
+		close(release)
+		public, err := keySet.Key(context.Background(), "k1")
+		require.NoError(t, err)
+		assert.True(t, first.PublicKey.Equal(public))
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+}
@@ -0,0 +1,156 @@
+// Package tokens verifies the JWTs the platform issues, signed with RS256 or
+// ES256 by a key of a JWKS, and maps their scopes onto the roles of the API
+// keys.
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Options struct {
+	// Issuer and Audience are not checked when empty.
+	Issuer        string
+	Audience      string
+	RequireExpiry bool
+	// Leeway is the clock skew allowed when checking exp, nbf and iat.
+	Leeway time.Duration
+	// Scopes is the scope granting each role; a role without one is not
+	// granted to any token.
+	Scopes map[domains.Role]string
+}
+
+type Verifier struct {
+	keys   KeySet
+	parser *jwt.Parser
+	scopes map[domains.Role]string
+}
+
+func NewVerifier(
+	keys KeySet,
+	options Options,
+) *Verifier {
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithLeeway(options.Leeway),
+		jwt.WithIssuedAt(),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	if options.RequireExpiry {
+		parserOptions = append(parserOptions, jwt.WithExpirationRequired())
+	}
+
+	return &Verifier{
+		keys:   keys,
+		parser: jwt.NewParser(parserOptions...),
+		scopes: options.Scopes,
+	}
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space separated list of RFC 8693; Scp is the list some
+	// issuers send instead, as an array or a space separated string.
+	Scope string `json:"scope"`
+	Scp   any    `json:"scp"`
+}
+
+func (c claims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	switch scp := c.Scp.(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(scp)...)
+	case []any:
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	return scopes
+}
+
+// VerifyToken answers the principal named by the sub of token, with the
+// highest role its scopes grant. Tokens that are badly signed, expired, of
+// another issuer or audience, or without sub are ErrTokenIsInvalid; a JWKS
+// that cannot be fetched is an error of its own.
+func (v *Verifier) VerifyToken(
+	ctx context.Context,
+	token string,
+) (domains.Principal, error) {
+	var c claims
+	_, err := v.parser.ParseWithClaims(token, &c, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenUnverifiable) && !errors.Is(err, ErrKeyNotFound) {
+			return domains.Principal{}, err
+		}
+		return domains.Principal{}, fmt.Errorf("%w: %s", domains.ErrTokenIsInvalid, reason(err))
+	}
+
+	if c.Subject == "" {
+		return domains.Principal{}, fmt.Errorf("%w: sub is missing", domains.ErrTokenIsInvalid)
+	}
+
+	return domains.Principal{
+		Name:   c.Subject,
+		Role:   v.role(c.scopes()),
+		Method: domains.AuthMethodJWT,
+	}, nil
+}
+
+func (v *Verifier) role(scopes []string) domains.Role {
+	var granted domains.Role
+	for _, role := range []domains.Role{domains.RoleReader, domains.RoleEditor, domains.RoleAdmin} {
+		scope := v.scopes[role]
+		if scope == "" {
+			continue
+		}
+		for _, s := range scopes {
+			if s == scope {
+				granted = role
+				break
+			}
+		}
+	}
+
+	return granted
+}
+
+// reason answers why jwt refused a token in words fit for its sender.
+func reason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "exp is in the past"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "exp is missing"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "nbf or iat is in the future"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "iss is not the expected one"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "aud does not include the expected one"
+	case errors.Is(err, ErrKeyNotFound):
+		return "kid is not in the JWKS"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "signature is invalid"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "token is malformed"
+	default:
+		return "token cannot be verified"
+	}
+}
@@ -0,0 +1,162 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/encilab/dragon-ball/src/domains"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Verifier(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	ecdsaKey := newECDSAKey(t)
+	otherKey := newRSAKey(t)
+
+	keys, err := ParseJWKS(jwks(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecdsaKey}))
+	require.NoError(t, err)
+	verifier := NewVerifier(&StaticKeySet{keys: keys}, Options{
+		Issuer:        "https://auth.example.com",
+		Audience:      "dragon-ball",
+		RequireExpiry: true,
+		Leeway:        30 * time.Second,
+		Scopes: map[domains.Role]string{
+			domains.RoleReader: "characters:read",
+			domains.RoleEditor: "characters:write",
+			domains.RoleAdmin:  "characters:admin",
+		},
+	})
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "ci-bot",
+			"iss":   "https://auth.example.com",
+			"aud":   []string{"dragon-ball", "other"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"scope": "openid characters:read characters:write",
+		}
+	}
+	sign := func(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	t.Run("given an RS256 token, it returns the highest role of its scopes", func(t *testing.T) {
+		principal, err := verifier.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()))
+
+		require.NoError(t, err)
+		assert.Equal(t, domains.Principal{Name: "ci-bot", Role: domains.RoleEditor, Method: domains.AuthMethodJWT}, principal)
+	})
+
+	t.Run("given an ES256 token with scp, it returns its role", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "scope")
+		claims["scp"] = []string{"characters:admin"}
+
+		principal, err := verifier.VerifyToken(context.Background(), sign(t, jwt.SigningMethodES256, "ec", ecdsaKey, claims))
+
+		require.NoError(t, err)
+		assert.Equal(t, domains.RoleAdmin, principal.Role)
+	})
+
+	t.Run("given a token without any mapped scope, it returns no role", func(t *testing.T) {
+		claims := validClaims()
+		claims["scope"] = "openid profile"
+
+		principal, err := verifier.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+
+		require.NoError(t, err)
+		assert.Empty(t, principal.Role)
+	})
+
+	t.Run("given a token expired within the leeway, it accepts it", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+
+		_, err := verifier.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+
+		assert.NoError(t, err)
+	})
+
+	invalid := []struct {
+		name   string
+		token  func(t *testing.T) string
+		reason string
+	}{
+		{"an expired token", func(t *testing.T) string {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, "exp is in the past"},
+		{"a token without exp", func(t *testing.T) string {
+			claims := validClaims()
+			delete(claims, "exp")
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, "exp is missing"},
+		{"a token of another issuer", func(t *testing.T) string {
+			claims := validClaims()
+			claims["iss"] = "https://evil.example.com"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, "iss is not the expected one"},
+		{"a token for another audience", func(t *testing.T) string {
+			claims := validClaims()
+			claims["aud"] = "other"
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, "aud does not include the expected one"},
+		{"a token signed by a key out of the JWKS", func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, "rsa", otherKey, validClaims())
+		}, "signature is invalid"},
+		{"a token with an unknown kid", func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, "rotated", otherKey, validClaims())
+		}, "kid is not in the JWKS"},
+		{"a token signed with HS256", func(t *testing.T) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+			token.Header["kid"] = "rsa"
+			signed, err := token.SignedString([]byte("secret"))
+			require.NoError(t, err)
+			return signed
+		}, "signature is invalid"},
+		{"a token without sub", func(t *testing.T) string {
+			claims := validClaims()
+			delete(claims, "sub")
+			return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)
+		}, "sub is missing"},
+		{"a string that is not a token", func(t *testing.T) string {
+			return "not-a-token"
+		}, "token is malformed"},
+	}
+	for _, tc := range invalid {
+		t.Run("given "+tc.name+", it returns ErrTokenIsInvalid", func(t *testing.T) {
+			_, err := verifier.VerifyToken(context.Background(), tc.token(t))
+
+			assert.ErrorIs(t, err, domains.ErrTokenIsInvalid)
+			assert.ErrorContains(t, err, tc.reason)
+		})
+	}
+
+	t.Run("given a JWKS that cannot be fetched, it returns an error that is not ErrTokenIsInvalid", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+		remote := NewVerifier(NewRemoteKeySet(server.Client(), server.URL, time.Minute), Options{})
+
+		_, err := remote.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()))
+
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, domains.ErrTokenIsInvalid))
+	})
+}
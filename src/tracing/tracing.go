@@ -0,0 +1,76 @@
+// Package tracing sets up the OpenTelemetry tracer provider of the web app
+// and the W3C trace context propagation of its incoming and outgoing
+// requests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Name is the instrumentation scope of every span the web app starts.
+const Name = "github.com/encilab/dragon-ball"
+
+// NewExporter answers the exporter named by OTEL_TRACES_EXPORTER: otlp, sent
+// over HTTP to endpoint (the OTLP default, localhost:4318, when empty), stdout
+// or none, which answers nil.
+func NewExporter(
+	ctx context.Context,
+	name string,
+	endpoint string,
+) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "otlp":
+		var options []otlptracehttp.Option
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, options...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", name)
+	}
+}
+
+// NewTracerProvider samples sampleRatio of the new traces and follows the
+// decision of the caller for the others. Without an exporter nothing is
+// recorded, but the trace context still reaches the external API.
+func NewTracerProvider(
+	exporter sdktrace.SpanExporter,
+	serviceName string,
+	sampleRatio float64,
+) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	if exporter == nil {
+		options = append(options, sdktrace.WithSampler(sdktrace.NeverSample()))
+	} else {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Install makes tracerProvider and the W3C trace context and baggage
+// propagators the global ones, which the outgoing HTTP client uses.
+func Install(tracerProvider *sdktrace.TracerProvider) {
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
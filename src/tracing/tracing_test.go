@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_NewExporter(t *testing.T) {
+	t.Run("given none, it answers no exporter", func(t *testing.T) {
+		exporter, err := NewExporter(context.Background(), "none", "")
+		require.NoError(t, err)
+		assert.Nil(t, exporter)
+	})
+
+	t.Run("given otlp and an endpoint, it answers an exporter", func(t *testing.T) {
+		exporter, err := NewExporter(context.Background(), "otlp", "http://collector:4318")
+		require.NoError(t, err)
+		assert.NotNil(t, exporter)
+	})
+
+	t.Run("given an unknown exporter, it answers an error", func(t *testing.T) {
+		_, err := NewExporter(context.Background(), "zipkin", "")
+		assert.EqualError(t, err, `unknown OTEL_TRACES_EXPORTER "zipkin"`)
+	})
+}
+
+func Test_NewTracerProvider(t *testing.T) {
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x00, 0xf0},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	t.Run("given an exporter, it records the spans of the caller's trace", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		tracerProvider := NewTracerProvider(exporter, "dragon-ball", 0)
+
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+		_, span := tracerProvider.Tracer(Name).Start(ctx, "GET /api/v1/characters/:id")
+		span.End()
+		require.NoError(t, tracerProvider.ForceFlush(context.Background()))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, remote.TraceID(), spans[0].SpanContext.TraceID())
+		assert.Equal(t, "dragon-ball", spans[0].Resource.Attributes()[0].Value.AsString())
+	})
+
+	t.Run("given a sample ratio of 0, it records no new trace", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		tracerProvider := NewTracerProvider(exporter, "dragon-ball", 0)
+
+		_, span := tracerProvider.Tracer(Name).Start(context.Background(), "GET /api/v1/characters/:id")
+		span.End()
+		require.NoError(t, tracerProvider.ForceFlush(context.Background()))
+
+		assert.Empty(t, exporter.GetSpans())
+	})
+
+	t.Run("given no exporter, it records nothing but keeps the caller's trace", func(t *testing.T) {
+		tracerProvider := NewTracerProvider(nil, "dragon-ball", 1)
+
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+		_, span := tracerProvider.Tracer(Name).Start(ctx, "GET /api/v1/characters/:id")
+		span.End()
+
+		assert.False(t, span.IsRecording())
+		assert.Equal(t, remote.TraceID(), span.SpanContext().TraceID())
+	})
+}